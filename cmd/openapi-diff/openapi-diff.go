@@ -0,0 +1,165 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// openapi-diff compares two OpenAPI v2 or v3 documents, in JSON or YAML, and
+// writes the changes from the old one to the new one to stdout. It exits
+// with status 1 if any change is breaking, so that it can be used in CI, and
+// with status 2 on errors.
+//
+//	openapi-diff [-o text|json] old.json new.json
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/specdiff"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func main() {
+	os.Exit(run(os.Args, os.Stdout, os.Stderr))
+}
+
+// errUsage is returned when the arguments are invalid, once the usage is
+// printed.
+var errUsage = errors.New("invalid arguments")
+
+// run runs the command with the given arguments, including the program
+// name, and returns its exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	changes, err := diff(args, stdout, stderr)
+	switch {
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		return 2
+	case err != nil:
+		fmt.Fprintln(stderr, err)
+		return 2
+	case specdiff.HasBreaking(changes):
+		return 1
+	}
+	return 0
+}
+
+func diff(args []string, stdout, stderr io.Writer) ([]specdiff.Change, error) {
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	output := flags.String("o", "text", "output format, text or json")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s [-o text|json] OLD NEW\n", args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return nil, err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return nil, errUsage
+	}
+	if *output != "text" && *output != "json" {
+		return nil, fmt.Errorf("unknown output format %q", *output)
+	}
+
+	oldVersion, oldData, err := readDocument(flags.Arg(0))
+	if err != nil {
+		return nil, err
+	}
+	newVersion, newData, err := readDocument(flags.Arg(1))
+	if err != nil {
+		return nil, err
+	}
+	if oldVersion != newVersion {
+		return nil, fmt.Errorf("can't compare an OpenAPI %s document with an OpenAPI %s document", oldVersion, newVersion)
+	}
+
+	var changes []specdiff.Change
+	if oldVersion == "v3" {
+		oldSpec, newSpec, err := unmarshalBoth[spec3.OpenAPI](flags.Arg(0), oldData, flags.Arg(1), newData)
+		if err != nil {
+			return nil, err
+		}
+		changes = specdiff.DiffV3(oldSpec, newSpec)
+	} else {
+		oldSpec, newSpec, err := unmarshalBoth[spec.Swagger](flags.Arg(0), oldData, flags.Arg(1), newData)
+		if err != nil {
+			return nil, err
+		}
+		changes = specdiff.Diff(oldSpec, newSpec)
+	}
+
+	if *output == "json" {
+		err = specdiff.WriteJSON(stdout, changes)
+	} else {
+		err = specdiff.WriteText(stdout, changes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error writing changes: %w", err)
+	}
+	return changes, nil
+}
+
+// readDocument returns the OpenAPI version of a document, v2 or v3, and the
+// document as JSON.
+func readDocument(path string) (string, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	data, err = yaml.YAMLToJSON(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("error interpreting %s: %w", path, err)
+	}
+	var header struct {
+		OpenAPI string `json:"openapi"`
+		Swagger string `json:"swagger"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return "", nil, fmt.Errorf("error interpreting %s: %w", path, err)
+	}
+	switch {
+	case header.OpenAPI != "":
+		return "v3", data, nil
+	case header.Swagger != "":
+		return "v2", data, nil
+	}
+	return "", nil, fmt.Errorf("%s is neither an OpenAPI v2 nor an OpenAPI v3 document", path)
+}
+
+func unmarshalBoth[T any](oldPath string, oldData []byte, newPath string, newData []byte) (*T, *T, error) {
+	oldSpec, err := unmarshal[T](oldPath, oldData)
+	if err != nil {
+		return nil, nil, err
+	}
+	newSpec, err := unmarshal[T](newPath, newData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oldSpec, newSpec, nil
+}
+
+func unmarshal[T any](path string, data []byte) (*T, error) {
+	var ret T
+	if err := yaml.Unmarshal(data, &ret); err != nil {
+		return nil, fmt.Errorf("error interpreting %s: %w", path, err)
+	}
+	return &ret, nil
+}
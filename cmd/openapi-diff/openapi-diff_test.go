@@ -0,0 +1,163 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	oldSpec = `
+swagger: "2.0"
+paths:
+  /foos:
+    get:
+      parameters:
+      - name: limit
+        in: query
+        type: integer
+      responses:
+        200:
+          description: OK
+`
+	compatibleSpec = `
+swagger: "2.0"
+paths:
+  /foos:
+    get:
+      parameters:
+      - name: limit
+        in: query
+        type: integer
+      - name: watch
+        in: query
+        type: boolean
+      responses:
+        200:
+          description: OK
+`
+	breakingSpec = `
+swagger: "2.0"
+paths:
+  /foos:
+    get:
+      parameters:
+      - name: limit
+        in: query
+        type: string
+      responses:
+        200:
+          description: OK
+`
+	v3Spec = `
+openapi: 3.0.0
+info:
+  title: test
+  version: v1
+paths: {}
+`
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	file := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+	oldFile := file("old.yaml", oldSpec)
+	compatibleFile := file("compatible.yaml", compatibleSpec)
+	breakingFile := file("breaking.yaml", breakingSpec)
+	v3File := file("v3.yaml", v3Spec)
+
+	for _, tc := range []struct {
+		name           string
+		args           []string
+		expectedStatus int
+		expectedStdout string
+		expectedStderr string
+	}{
+		{
+			name:           "no changes",
+			args:           []string{oldFile, oldFile},
+			expectedStatus: 0,
+		},
+		{
+			name:           "compatible",
+			args:           []string{oldFile, compatibleFile},
+			expectedStatus: 0,
+			expectedStdout: "compatible: /paths/~1foos/get/parameters/1: parameter added\n",
+		},
+		{
+			name:           "breaking",
+			args:           []string{oldFile, breakingFile},
+			expectedStatus: 1,
+			expectedStdout: "breaking: /paths/~1foos/get/parameters/0/type: type changed from [\"integer\"] to [\"string\"]\n",
+		},
+		{
+			name:           "json",
+			args:           []string{"-o", "json", oldFile, compatibleFile},
+			expectedStatus: 0,
+			expectedStdout: `[
+  {
+    "kind": "ParameterAdded",
+    "breaking": false,
+    "pointer": "/paths/~1foos/get/parameters/1"
+  }
+]
+`,
+		},
+		{
+			name:           "unknown output format",
+			args:           []string{"-o", "yaml", oldFile, compatibleFile},
+			expectedStatus: 2,
+			expectedStderr: "unknown output format \"yaml\"\n",
+		},
+		{
+			name:           "different versions",
+			args:           []string{oldFile, v3File},
+			expectedStatus: 2,
+			expectedStderr: "can't compare an OpenAPI v2 document with an OpenAPI v3 document\n",
+		},
+		{
+			name:           "missing file",
+			args:           []string{oldFile, filepath.Join(dir, "missing.yaml")},
+			expectedStatus: 2,
+			expectedStderr: "error reading " + filepath.Join(dir, "missing.yaml") + ": open " + filepath.Join(dir, "missing.yaml") + ": no such file or directory\n",
+		},
+		{
+			name:           "missing argument",
+			args:           []string{oldFile},
+			expectedStatus: 2,
+			expectedStderr: "usage: openapi-diff [-o text|json] OLD NEW\n  -o string\n    \toutput format, text or json (default \"text\")\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			status := run(append([]string{"openapi-diff"}, tc.args...), &stdout, &stderr)
+			assert.Equal(t, tc.expectedStatus, status)
+			assert.Equal(t, tc.expectedStdout, stdout.String())
+			assert.Equal(t, tc.expectedStderr, stderr.String())
+		})
+	}
+}
@@ -18,6 +18,7 @@ package aggregator
 
 import (
 	"fmt"
+	"maps"
 	"reflect"
 	"sort"
 	"strings"
@@ -200,122 +201,108 @@ func MergeSpecs(dest, source *spec.Swagger) error {
 
 // mergeSpecs merges source into dest while resolving conflicts.
 // The source is not mutated.
+//
+// Unlike MergeSpecsWithOptions, dest is partly mutated if a path
+// conflicts, see mergePlan.legacy.
 func mergeSpecs(dest, source *spec.Swagger, renameModelConflicts, renameParameterConflicts, ignorePathConflicts bool) (err error) {
-	// Paths may be empty, due to [ACL constraints](http://goo.gl/8us55a#securityFiltering).
-	if source.Paths == nil {
-		// When a source spec does not have any path, that means none of the definitions
-		// are used thus we should not do anything
-		return nil
+	plan, err := planMerge(dest, source, legacyMergeOptions(renameModelConflicts, renameParameterConflicts, ignorePathConflicts))
+	if err != nil || plan == nil {
+		return err
 	}
 	if dest.Paths == nil {
 		dest.Paths = &spec.Paths{}
 	}
-	if ignorePathConflicts {
-		keepPaths := []string{}
-		hasConflictingPath := false
-		for k := range source.Paths.Paths {
-			if _, found := dest.Paths.Paths[k]; !found {
-				keepPaths = append(keepPaths, k)
-			} else {
-				hasConflictingPath = true
-			}
-		}
-		if len(keepPaths) == 0 {
-			// There is nothing to merge. All paths are conflicting.
-			return nil
-		}
-		if hasConflictingPath {
-			source = FilterSpecByPathsWithoutSideEffects(source, keepPaths)
-		}
+	plan.legacy = true
+	return plan.apply(dest)
+}
+
+// MergeSpecsWithOptions copies paths, definitions and parameters from source
+// to dest, resolving conflicts as configured by opts.
+//
+// The destination is mutated, the source is not.
+func MergeSpecsWithOptions(dest, source *spec.Swagger, opts MergeOptions) error {
+	plan, err := planMerge(dest, source, opts)
+	if err != nil {
+		return err
 	}
+	return plan.apply(dest)
+}
 
-	// Check for model conflicts and rename to make definitions conflict-free (modulo different GVKs)
-	usedNames := map[string]bool{}
-	for k := range dest.Definitions {
-		usedNames[k] = true
+// apply merges the planned source into dest, or fails without mutating dest
+// if a conflict is resolved with ConflictFail, or if the references of the
+// merged spec are checked and invalid.
+func (plan *mergePlan) apply(dest *spec.Swagger) error {
+	if plan == nil || plan.source == nil {
+		return nil
 	}
-	renames := map[string]string{}
-DEFINITIONLOOP:
-	for k, v := range source.Definitions {
-		existing, found := dest.Definitions[k]
-		if !found || deepEqualDefinitionsModuloGVKs(&existing, &v) {
-			// skip for now, we copy them after the rename loop
-			continue
+	for _, c := range plan.definitionConflicts {
+		if c.resolution == ConflictFail {
+			return fmt.Errorf("model name conflict in merging OpenAPI spec: %s", c.from)
 		}
-
-		if !renameModelConflicts {
-			return fmt.Errorf("model name conflict in merging OpenAPI spec: %s", k)
+	}
+	for _, c := range plan.parameterConflicts {
+		if c.resolution == ConflictFail {
+			return fmt.Errorf("parameter name conflict in merging OpenAPI spec: %s", c.from)
 		}
-
-		// Reuse previously renamed model if one exists
-		var newName string
-		i := 1
-		for found {
-			i++
-			newName = fmt.Sprintf("%s_v%d", k, i)
-			existing, found = dest.Definitions[newName]
-			if found && deepEqualDefinitionsModuloGVKs(&existing, &v) {
-				renames[k] = newName
-				continue DEFINITIONLOOP
+	}
+	preferSourcePaths := map[string]bool{}
+	for _, c := range plan.pathConflicts {
+		switch c.resolution {
+		case ConflictFail:
+			if plan.legacy {
+				// copyTo fails once it gets to the paths.
+				continue
 			}
+			return fmt.Errorf("unable to merge: duplicated path %s", c.from)
+		case ConflictPreferSource:
+			preferSourcePaths[c.from] = true
 		}
-
-		_, foundInSource := source.Definitions[newName]
-		for usedNames[newName] || foundInSource {
-			i++
-			newName = fmt.Sprintf("%s_v%d", k, i)
-			_, foundInSource = source.Definitions[newName]
-		}
-		renames[k] = newName
-		usedNames[newName] = true
 	}
-	source = renameDefinitions(source, renames)
-
-	// Check for parameter conflicts and rename to make parameters conflict-free
-	usedNames = map[string]bool{}
-	for k := range dest.Parameters {
-		usedNames[k] = true
+	if !plan.checkReferences {
+		return plan.copyTo(dest, preferSourcePaths)
 	}
-	renames = map[string]string{}
-PARAMETERLOOP:
-	for k, p := range source.Parameters {
-		existing, found := dest.Parameters[k]
-		if !found || reflect.DeepEqual(&existing, &p) {
-			// skip for now, we copy them after the rename loop
-			continue
-		}
 
-		if !renameParameterConflicts {
-			return fmt.Errorf("parameter name conflict in merging OpenAPI spec: %s", k)
+	merged := *dest
+	merged.Paths = &spec.Paths{}
+	if dest.Paths != nil {
+		merged.Paths.VendorExtensible = dest.Paths.VendorExtensible
+		merged.Paths.Paths = maps.Clone(dest.Paths.Paths)
+	}
+	merged.Definitions = maps.Clone(dest.Definitions)
+	merged.Parameters = maps.Clone(dest.Parameters)
+	if err := plan.copyTo(&merged, preferSourcePaths); err != nil {
+		return err
+	}
+	var invalid []ReferenceFinding
+	for _, f := range CheckReferences(&merged) {
+		if f.Problem.Invalid() {
+			invalid = append(invalid, f)
 		}
+	}
+	if len(invalid) > 0 {
+		return &ReferenceError{Findings: invalid}
+	}
+	*dest = merged
+	return nil
+}
 
-		// Reuse previously renamed parameter if one exists
-		var newName string
-		i := 1
-		for found {
-			i++
-			newName = fmt.Sprintf("%s_v%d", k, i)
-			existing, found = dest.Parameters[newName]
-			if found && reflect.DeepEqual(&existing, &p) {
-				renames[k] = newName
-				continue PARAMETERLOOP
-			}
+// copyTo copies the planned source to dest, which is mutated.
+func (plan *mergePlan) copyTo(dest *spec.Swagger, preferSourcePaths map[string]bool) error {
+	source := plan.merged
+	preferSource := map[string]bool{}
+	for _, c := range plan.definitionConflicts {
+		if c.resolution == ConflictPreferSource {
+			preferSource[c.from] = true
 		}
+	}
 
-		_, foundInSource := source.Parameters[newName]
-		for usedNames[newName] || foundInSource {
-			i++
-			newName = fmt.Sprintf("%s_v%d", k, i)
-			_, foundInSource = source.Parameters[newName]
-		}
-		renames[k] = newName
-		usedNames[newName] = true
+	if dest.Paths == nil {
+		dest.Paths = &spec.Paths{}
 	}
-	source = renameParameters(source, renames)
 
 	// Now without conflict (modulo different GVKs), copy definitions to dest
 	for k, v := range source.Definitions {
-		if existing, found := dest.Definitions[k]; !found {
+		if existing, found := dest.Definitions[k]; !found || preferSource[k] {
 			if dest.Definitions == nil {
 				dest.Definitions = make(spec.Definitions, len(source.Definitions))
 			}
@@ -323,13 +310,28 @@ PARAMETERLOOP:
 		} else if merged, changed, err := mergedGVKs(&existing, &v); err != nil {
 			return err
 		} else if changed {
-			existing.Extensions[gvkKey] = merged
+			// Copy the extensions rather than updating them in place: the
+			// existing definition may be shared with a previously merged
+			// source.
+			extensions := make(spec.Extensions, len(existing.Extensions)+1)
+			for k, v := range existing.Extensions {
+				extensions[k] = v
+			}
+			extensions[gvkKey] = merged
+			existing.Extensions = extensions
+			dest.Definitions[k] = existing
 		}
 	}
 
 	// Now without conflict, copy parameters to dest
+	preferSource = map[string]bool{}
+	for _, c := range plan.parameterConflicts {
+		if c.resolution == ConflictPreferSource {
+			preferSource[c.from] = true
+		}
+	}
 	for k, v := range source.Parameters {
-		if _, found := dest.Parameters[k]; !found {
+		if _, found := dest.Parameters[k]; !found || preferSource[k] {
 			if dest.Parameters == nil {
 				dest.Parameters = make(map[string]spec.Parameter, len(source.Parameters))
 			}
@@ -337,10 +339,14 @@ PARAMETERLOOP:
 		}
 	}
 
-	// Check for path conflicts
 	for k, v := range source.Paths.Paths {
-		if _, found := dest.Paths.Paths[k]; found {
-			return fmt.Errorf("unable to merge: duplicated path %s", k)
+		// Filtering by paths keeps the sub-paths of the kept paths, which
+		// may conflict too.
+		if _, found := dest.Paths.Paths[k]; found && !preferSourcePaths[k] {
+			if plan.legacy {
+				return fmt.Errorf("unable to merge: duplicated path %s", k)
+			}
+			continue
 		}
 		// PathItem may be empty, due to [ACL constraints](http://goo.gl/8us55a#securityFiltering).
 		if dest.Paths.Paths == nil {
@@ -352,6 +358,199 @@ PARAMETERLOOP:
 	return nil
 }
 
+// mergePlan is what merging a source spec into a destination spec takes,
+// computed without mutating either.
+type mergePlan struct {
+	// original is the source spec, without the conflicting paths where the
+	// destination is preferred.
+	original *spec.Swagger
+	// source is the source spec, without the conflicting paths where the
+	// destination is preferred, and with its definitions renamed. It is
+	// nil if there is nothing to merge.
+	source *spec.Swagger
+	// merged is source with its parameters renamed too, as merged into
+	// the destination.
+	merged *spec.Swagger
+	// pathConflicts are the paths of the source that are also in the
+	// destination, sorted.
+	pathConflicts []rename
+	// definitionConflicts and parameterConflicts are the definitions and
+	// parameters of the source that conflict with the ones of the
+	// destination, sorted by source name.
+	definitionConflicts []rename
+	parameterConflicts  []rename
+	// checkReferences is MergeOptions.CheckReferences.
+	checkReferences bool
+	// legacy is true for the MergeSpecs variants, which fail on the
+	// conflicting paths of the source only after copying its definitions
+	// and parameters to the destination, including the conflicting
+	// sub-paths of the paths kept by ConflictPreferDest.
+	legacy bool
+}
+
+// rename is the resolution of a conflicting path, definition or parameter
+// of the source spec.
+type rename struct {
+	from, to string
+	// reused is true if the destination already has an identical
+	// definition or parameter under the new name.
+	reused     bool
+	resolution ConflictResolution
+}
+
+// planMerge computes the merge of source into dest. It returns nil if there
+// is nothing to merge.
+func planMerge(dest, source *spec.Swagger, opts MergeOptions) (*mergePlan, error) {
+	// Paths may be empty, due to [ACL constraints](http://goo.gl/8us55a#securityFiltering).
+	if source.Paths == nil {
+		// When a source spec does not have any path, that means none of the definitions
+		// are used thus we should not do anything
+		return nil, nil
+	}
+	plan := &mergePlan{source: source, checkReferences: opts.CheckReferences}
+	keepPaths := []string{}
+	paths := make([]string, 0, len(source.Paths.Paths))
+	for k := range source.Paths.Paths {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+	for _, k := range paths {
+		var existing spec.PathItem
+		found := false
+		if dest.Paths != nil {
+			existing, found = dest.Paths.Paths[k]
+		}
+		if !found {
+			keepPaths = append(keepPaths, k)
+			continue
+		}
+		resolution := ConflictFail
+		if opts.PathConflict != nil {
+			v := source.Paths.Paths[k]
+			resolution = opts.PathConflict(k, &existing, &v)
+		}
+		switch resolution {
+		case ConflictRename:
+			return nil, fmt.Errorf("unable to merge: path %s can't be renamed", k)
+		case ConflictPreferSource, ConflictFail:
+			keepPaths = append(keepPaths, k)
+		case ConflictPreferDest:
+		default:
+			return nil, fmt.Errorf("invalid conflict resolution %v of path %s", resolution, k)
+		}
+		plan.pathConflicts = append(plan.pathConflicts, rename{from: k, to: k, resolution: resolution})
+	}
+	if len(keepPaths) < len(paths) {
+		if len(keepPaths) == 0 {
+			// There is nothing to merge. All paths are conflicting.
+			plan.source = nil
+			return plan, nil
+		}
+		plan.source = FilterSpecByPathsWithoutSideEffects(source, keepPaths)
+	}
+	plan.original = plan.source
+
+	definitionsEqual := opts.DefinitionsEqual
+	if definitionsEqual == nil {
+		definitionsEqual = deepEqualDefinitionsModuloGVKs
+	}
+
+	// Check for model conflicts and rename to make definitions conflict-free (modulo different GVKs)
+	var err error
+	plan.definitionConflicts, err = planRenames(dest.Definitions, plan.source.Definitions, definitionsEqual, opts.DefinitionConflict, opts.Rename)
+	if err != nil {
+		return nil, fmt.Errorf("definition: %w", err)
+	}
+	plan.source = renameDefinitions(plan.source, renameMap(plan.definitionConflicts))
+
+	// Check for parameter conflicts, which may come from renamed definitions, and rename to make parameters conflict-free
+	plan.parameterConflicts, err = planRenames(dest.Parameters, plan.source.Parameters, func(p1, p2 *spec.Parameter) bool {
+		return reflect.DeepEqual(p1, p2)
+	}, opts.ParameterConflict, opts.Rename)
+	if err != nil {
+		return nil, fmt.Errorf("parameter: %w", err)
+	}
+	plan.merged = renameParameters(plan.source, renameMap(plan.parameterConflicts))
+	return plan, nil
+}
+
+// planRenames returns the resolutions of the entries of source that
+// conflict with the ones of dest, sorted by name, as returned by resolve,
+// or ConflictRename if nil. An entry is renamed to the first identical entry
+// of dest named by renameFn, "<name>_v<n>" if nil, or else to the first
+// such name that is used neither in dest nor in source.
+func planRenames[M ~map[string]V, V any](dest, source M, equal func(*V, *V) bool, resolve func(name string, dest, source *V) ConflictResolution, renameFn func(name string, n int) string) ([]rename, error) {
+	if renameFn == nil {
+		renameFn = defaultRename
+	}
+	names := make([]string, 0, len(source))
+	for k := range source {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	usedNames := map[string]bool{}
+	for k := range dest {
+		usedNames[k] = true
+	}
+	var renames []rename
+NAMELOOP:
+	for _, k := range names {
+		v := source[k]
+		existing, found := dest[k]
+		if !found || equal(&existing, &v) {
+			continue
+		}
+
+		resolution := ConflictRename
+		if resolve != nil {
+			resolution = resolve(k, &existing, &v)
+		}
+		switch resolution {
+		case ConflictRename:
+		case ConflictPreferDest, ConflictPreferSource, ConflictFail:
+			renames = append(renames, rename{from: k, to: k, resolution: resolution})
+			continue
+		default:
+			return nil, fmt.Errorf("invalid conflict resolution %v of %s", resolution, k)
+		}
+
+		// Reuse previously renamed entry if one exists
+		var newName string
+		i := 1
+		for found {
+			i++
+			newName = renameFn(k, i)
+			existing, found = dest[newName]
+			if found && equal(&existing, &v) {
+				renames = append(renames, rename{from: k, to: newName, reused: true})
+				continue NAMELOOP
+			}
+		}
+
+		_, foundInSource := source[newName]
+		for usedNames[newName] || foundInSource {
+			i++
+			newName = renameFn(k, i)
+			_, foundInSource = source[newName]
+		}
+		renames = append(renames, rename{from: k, to: newName})
+		usedNames[newName] = true
+	}
+	return renames, nil
+}
+
+// renameMap returns the new names of the renamed entries.
+func renameMap(renames []rename) map[string]string {
+	ret := make(map[string]string, len(renames))
+	for _, r := range renames {
+		if r.resolution == ConflictRename {
+			ret[r.from] = r.to
+		}
+	}
+	return ret
+}
+
 // deepEqualDefinitionsModuloGVKs compares s1 and s2, but ignores the x-kubernetes-group-version-kind extension.
 func deepEqualDefinitionsModuloGVKs(s1, s2 *spec.Schema) bool {
 	if s1 == nil {
@@ -0,0 +1,452 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"sort"
+
+	"k8s.io/kube-openapi/pkg/schemamutation"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/util"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// SelectSpecV3PathsAndSchemas returns a self-contained spec with only the
+// given paths and component schemas, along with all the components they
+// reference, transitively. Paths and schemas that don't exist are ignored.
+// Security schemes and links are kept as they are.
+//
+// The input is not modified. Only the path and component maps are new:
+// the path items and components in them, and the security scheme and link
+// maps, are the ones of the input.
+func SelectSpecV3PathsAndSchemas(sp *spec3.OpenAPI, keepPaths []string, keepSchemas []string) *spec3.OpenAPI {
+	ret := *sp
+	ret.Paths = &spec3.Paths{Paths: map[string]*spec3.Path{}}
+	if sp.Paths != nil {
+		ret.Paths.VendorExtensible = sp.Paths.VendorExtensible
+	}
+
+	usedRefs := map[string]bool{}
+	walker := newReadonlyReferenceWalkerV3(func(ref *spec.Ref) {
+		if refStr := ref.String(); refStr != "" {
+			usedRefs[refStr] = true
+		}
+	}, sp)
+	for _, path := range keepPaths {
+		if sp.Paths == nil {
+			break
+		}
+		if pathItem, ok := sp.Paths.Paths[path]; ok {
+			ret.Paths.Paths[path] = pathItem
+			walker.walkPath(pathItem)
+		}
+	}
+	for _, name := range keepSchemas {
+		// The names may come from untrusted input, so they are looked up
+		// directly rather than parsed as references.
+		if sp.Components == nil {
+			break
+		}
+		refStr := schemaPrefixV3 + name
+		if schema, ok := sp.Components.Schemas[name]; ok && !walker.alreadyVisited[refStr] {
+			usedRefs[refStr] = true
+			walker.alreadyVisited[refStr] = true
+			walker.walkSchema(schema)
+		}
+	}
+
+	if sp.Components == nil {
+		return &ret
+	}
+	ret.Components = &spec3.Components{
+		SecuritySchemes: sp.Components.SecuritySchemes,
+		Links:           sp.Components.Links,
+	}
+	ret.Components.Schemas = keepUsedComponents(sp.Components.Schemas, schemaPrefixV3, usedRefs)
+	ret.Components.Parameters = keepUsedComponents(sp.Components.Parameters, parameterPrefixV3, usedRefs)
+	ret.Components.Responses = keepUsedComponents(sp.Components.Responses, responsePrefixV3, usedRefs)
+	ret.Components.RequestBodies = keepUsedComponents(sp.Components.RequestBodies, requestBodyPrefixV3, usedRefs)
+	ret.Components.Headers = keepUsedComponents(sp.Components.Headers, headerPrefixV3, usedRefs)
+	ret.Components.Examples = keepUsedComponents(sp.Components.Examples, examplePrefixV3, usedRefs)
+	return &ret
+}
+
+// keepUsedComponents returns the components whose reference, made of
+// the prefix and the component name, is used.
+func keepUsedComponents[T any](components map[string]T, prefix string, usedRefs map[string]bool) map[string]T {
+	var ret map[string]T
+	for name, component := range components {
+		if !usedRefs[prefix+name] {
+			continue
+		}
+		if ret == nil {
+			ret = map[string]T{}
+		}
+		ret[name] = component
+	}
+	return ret
+}
+
+// usedComponentsV3 returns the references to components used, directly or
+// transitively, by the paths of the spec.
+func usedComponentsV3(sp *spec3.OpenAPI) map[string]bool {
+	usedRefs := map[string]bool{}
+	walker := newReadonlyReferenceWalkerV3(func(ref *spec.Ref) {
+		if refStr := ref.String(); refStr != "" {
+			usedRefs[refStr] = true
+		}
+	}, sp)
+	if sp.Paths != nil {
+		for _, pathItem := range sp.Paths.Paths {
+			walker.walkPath(pathItem)
+		}
+	}
+	return usedRefs
+}
+
+// FilterSpecV3ByPathsWithoutSideEffects removes unnecessary paths and the
+// components used by those paths, like FilterSpecByPathsWithoutSideEffects
+// does for OpenAPI v2 specs. A component is removed only if it was used
+// by a removed path and it isn't used by a kept path anymore. Security
+// schemes and links are kept as they are.
+//
+// The input is not modified, and is returned as is if it has no paths.
+// Otherwise the kept path items and components are shared with the input,
+// in new maps.
+func FilterSpecV3ByPathsWithoutSideEffects(sp *spec3.OpenAPI, keepPathPrefixes []string) *spec3.OpenAPI {
+	if sp.Paths == nil {
+		return sp
+	}
+	initialUsedRefs := usedComponentsV3(sp)
+
+	prefixes := util.NewTrie(keepPathPrefixes)
+	ret := *sp
+	ret.Paths = &spec3.Paths{
+		VendorExtensible: sp.Paths.VendorExtensible,
+		Paths:            map[string]*spec3.Path{},
+	}
+	for path, pathItem := range sp.Paths.Paths {
+		if prefixes.HasPrefix(path) {
+			ret.Paths.Paths[path] = pathItem
+		}
+	}
+	if sp.Components == nil {
+		return &ret
+	}
+
+	usedRefs := usedComponentsV3(&ret)
+	keep := map[string]bool{}
+	components := *sp.Components
+	for _, kind := range componentKindsV3 {
+		for _, name := range kind.names(&components) {
+			ref := kind.prefix + name
+			keep[ref] = usedRefs[ref] || !initialUsedRefs[ref]
+		}
+	}
+	components.Schemas = keepUsedComponents(sp.Components.Schemas, schemaPrefixV3, keep)
+	components.Parameters = keepUsedComponents(sp.Components.Parameters, parameterPrefixV3, keep)
+	components.Responses = keepUsedComponents(sp.Components.Responses, responsePrefixV3, keep)
+	components.RequestBodies = keepUsedComponents(sp.Components.RequestBodies, requestBodyPrefixV3, keep)
+	components.Headers = keepUsedComponents(sp.Components.Headers, headerPrefixV3, keep)
+	components.Examples = keepUsedComponents(sp.Components.Examples, examplePrefixV3, keep)
+	ret.Components = &components
+	return &ret
+}
+
+// FilterSpecV3ByPaths is the same as FilterSpecV3ByPathsWithoutSideEffects,
+// but replaces the given spec.
+func FilterSpecV3ByPaths(sp *spec3.OpenAPI, keepPathPrefixes []string) {
+	*sp = *FilterSpecV3ByPathsWithoutSideEffects(sp, keepPathPrefixes)
+}
+
+// componentKindV3 is a kind of components of OpenAPI v3 specs, e.g.
+// schemas, and how to merge them.
+type componentKindV3 struct {
+	// name is the name of a component of this kind, in error messages.
+	name string
+	// prefix is the prefix of the references to components of this kind.
+	prefix string
+	// names returns the names of the components of this kind.
+	names func(c *spec3.Components) []string
+	// planRenames returns the renames of the components of source that
+	// conflict with the ones of dest.
+	planRenames func(dest, source *spec3.Components) ([]rename, error)
+	// rename renames the components, without mutating their map.
+	rename func(c *spec3.Components, renames map[string]string)
+	// copyMissing copies the components of source that are not in dest
+	// to dest, which is mutated.
+	copyMissing func(dest, source *spec3.Components)
+}
+
+func newComponentKindV3[M ~map[string]V, V any](name, prefix string, field func(*spec3.Components) *M, equal func(V, V) bool) componentKindV3 {
+	return componentKindV3{
+		name:   name,
+		prefix: prefix,
+		names: func(c *spec3.Components) []string {
+			names := make([]string, 0, len(*field(c)))
+			for k := range *field(c) {
+				names = append(names, k)
+			}
+			return names
+		},
+		planRenames: func(dest, source *spec3.Components) ([]rename, error) {
+			return planRenames(*field(dest), *field(source), func(v1, v2 *V) bool { return equal(*v1, *v2) }, nil, nil)
+		},
+		rename: func(c *spec3.Components, renames map[string]string) {
+			renamed := make(M, len(*field(c)))
+			for k, v := range *field(c) {
+				if newName, found := renames[k]; found {
+					k = newName
+				}
+				renamed[k] = v
+			}
+			*field(c) = renamed
+		},
+		copyMissing: func(dest, source *spec3.Components) {
+			d := field(dest)
+			for k, v := range *field(source) {
+				if _, found := (*d)[k]; found {
+					continue
+				}
+				if *d == nil {
+					*d = make(M, len(*field(source)))
+				}
+				(*d)[k] = v
+			}
+		},
+	}
+}
+
+func deepEqual[V any](v1, v2 V) bool {
+	return reflect.DeepEqual(v1, v2)
+}
+
+// componentKindsV3 are the kinds of components that are merged, in an
+// order where a kind only references the kinds before it, so that the
+// conflicts of a kind are found once the kinds it references are renamed.
+var componentKindsV3 = []componentKindV3{
+	newComponentKindV3("schema", schemaPrefixV3, func(c *spec3.Components) *map[string]*spec.Schema { return &c.Schemas }, deepEqualDefinitionsModuloGVKs),
+	newComponentKindV3("example", examplePrefixV3, func(c *spec3.Components) *map[string]*spec3.Example { return &c.Examples }, deepEqual[*spec3.Example]),
+	newComponentKindV3("header", headerPrefixV3, func(c *spec3.Components) *map[string]*spec3.Header { return &c.Headers }, deepEqual[*spec3.Header]),
+	newComponentKindV3("link", linkPrefixV3, func(c *spec3.Components) *map[string]*spec3.Link { return &c.Links }, deepEqual[*spec3.Link]),
+	newComponentKindV3("parameter", parameterPrefixV3, func(c *spec3.Components) *map[string]*spec3.Parameter { return &c.Parameters }, deepEqual[*spec3.Parameter]),
+	newComponentKindV3("request body", requestBodyPrefixV3, func(c *spec3.Components) *map[string]*spec3.RequestBody { return &c.RequestBodies }, deepEqual[*spec3.RequestBody]),
+	newComponentKindV3("response", responsePrefixV3, func(c *spec3.Components) *map[string]*spec3.Response { return &c.Responses }, deepEqual[*spec3.Response]),
+	newComponentKindV3("security scheme", securitySchemePrefixV3, func(c *spec3.Components) *spec3.SecuritySchemes { return &c.SecuritySchemes }, deepEqual[*spec3.SecurityScheme]),
+}
+
+// MergeSpecsV3 copies paths and components from source to dest, renaming
+// conflicting components, along with the references to them, with a
+// "_v<n>" suffix. Identical components are only copied once. It fails on
+// path conflicts. This is the OpenAPI v3 counterpart of MergeSpecs.
+//
+// The destination is mutated, the source is not.
+func MergeSpecsV3(dest, source *spec3.OpenAPI) error {
+	return mergeSpecsV3(dest, source, true, false)
+}
+
+// MergeSpecsV3IgnorePathConflictRenamingComponents is the same as
+// MergeSpecsV3 except it will ignore any path conflicts by keeping the paths
+// of destination.
+func MergeSpecsV3IgnorePathConflictRenamingComponents(dest, source *spec3.OpenAPI) error {
+	return mergeSpecsV3(dest, source, true, true)
+}
+
+// MergeSpecsV3FailOnComponentConflict is different from MergeSpecsV3 as it
+// fails if there is a component conflict.
+func MergeSpecsV3FailOnComponentConflict(dest, source *spec3.OpenAPI) error {
+	return mergeSpecsV3(dest, source, false, false)
+}
+
+// mergeSpecsV3 merges source into dest while resolving conflicts.
+// The source is not mutated.
+func mergeSpecsV3(dest, source *spec3.OpenAPI, renameComponentConflicts, ignorePathConflicts bool) error {
+	// Paths may be empty, due to [ACL constraints](http://goo.gl/8us55a#securityFiltering).
+	if source.Paths == nil {
+		return nil
+	}
+	keepPaths := []string{}
+	conflictingPaths := []string{}
+	for k := range source.Paths.Paths {
+		if _, found := pathsV3(dest)[k]; found {
+			conflictingPaths = append(conflictingPaths, k)
+		} else {
+			keepPaths = append(keepPaths, k)
+		}
+	}
+	if len(conflictingPaths) > 0 {
+		if !ignorePathConflicts {
+			sort.Strings(conflictingPaths)
+			return fmt.Errorf("unable to merge: duplicated path %s", conflictingPaths[0])
+		}
+		if len(keepPaths) == 0 {
+			// There is nothing to merge. All paths are conflicting.
+			return nil
+		}
+		source = FilterSpecV3ByPathsWithoutSideEffects(source, keepPaths)
+	}
+
+	destComponents := dest.Components
+	if destComponents == nil {
+		destComponents = &spec3.Components{}
+	}
+	if source.Components != nil {
+		for _, kind := range componentKindsV3 {
+			renames, err := kind.planRenames(destComponents, source.Components)
+			if err != nil {
+				return err
+			}
+			if len(renames) == 0 {
+				continue
+			}
+			if !renameComponentConflicts {
+				return fmt.Errorf("%s name conflict in merging OpenAPI spec: %s", kind.name, renames[0].from)
+			}
+			source = renameComponentsV3(source, kind, renameMap(renames))
+		}
+
+		// Now without conflict (modulo different GVKs), copy components to dest
+		for k, v := range source.Components.Schemas {
+			existing, found := destComponents.Schemas[k]
+			if !found {
+				continue
+			}
+			if merged, changed, err := mergedGVKs(existing, v); err != nil {
+				return err
+			} else if changed {
+				// Copy the schema rather than updating its extensions in
+				// place, it may be shared with a previously merged source.
+				schema := *existing
+				schema.Extensions = make(spec.Extensions, len(existing.Extensions)+1)
+				maps.Copy(schema.Extensions, existing.Extensions)
+				schema.Extensions[gvkKey] = merged
+				destComponents.Schemas[k] = &schema
+			}
+		}
+		for _, kind := range componentKindsV3 {
+			kind.copyMissing(destComponents, source.Components)
+		}
+		dest.Components = destComponents
+	}
+
+	if dest.Paths == nil {
+		dest.Paths = &spec3.Paths{}
+	}
+	for k, v := range source.Paths.Paths {
+		if dest.Paths.Paths == nil {
+			dest.Paths.Paths = map[string]*spec3.Path{}
+		}
+		dest.Paths.Paths[k] = v
+	}
+	return nil
+}
+
+func pathsV3(sp *spec3.OpenAPI) map[string]*spec3.Path {
+	if sp.Paths == nil {
+		return nil
+	}
+	return sp.Paths.Paths
+}
+
+// renameComponentsV3 renames components of the given kind, along with the
+// references to them, without mutating the input. The output might share
+// data structures with the input.
+func renameComponentsV3(s *spec3.OpenAPI, kind componentKindV3, renames map[string]string) *spec3.OpenAPI {
+	refRenames := make(map[string]string, len(renames))
+	for k, v := range renames {
+		refRenames[kind.prefix+k] = kind.prefix + v
+	}
+	ret := schemamutation.ReplaceReferencesV3(func(ref *spec.Ref) *spec.Ref {
+		if newRef, found := refRenames[ref.String()]; found {
+			ret := spec.MustCreateRef(newRef)
+			return &ret
+		}
+		return ref
+	}, s)
+	if ret == s {
+		shallowCopy := *s
+		ret = &shallowCopy
+	}
+	components := *ret.Components
+	kind.rename(&components, renames)
+	ret.Components = &components
+	if kind.prefix == securitySchemePrefixV3 {
+		ret = renameSecurityRequirements(ret, renames)
+	}
+	return ret
+}
+
+// renameSecurityRequirements renames the security schemes in the security
+// requirements of the operations, without mutating the input, which must
+// be a copy of the spec.
+func renameSecurityRequirements(s *spec3.OpenAPI, renames map[string]string) *spec3.OpenAPI {
+	rename := func(requirements []map[string][]string) ([]map[string][]string, bool) {
+		changed := false
+		ret := make([]map[string][]string, len(requirements))
+		for i, requirement := range requirements {
+			ret[i] = make(map[string][]string, len(requirement))
+			for k, v := range requirement {
+				if newName, found := renames[k]; found {
+					k = newName
+					changed = true
+				}
+				ret[i][k] = v
+			}
+		}
+		return ret, changed
+	}
+
+	if requirements, changed := rename(s.SecurityRequirement); changed {
+		s.SecurityRequirement = requirements
+	}
+	if s.Paths == nil {
+		return s
+	}
+	var paths map[string]*spec3.Path
+	for name, path := range s.Paths.Paths {
+		if path == nil {
+			continue
+		}
+		renamed := *path
+		changed := false
+		for _, op := range []**spec3.Operation{&renamed.Get, &renamed.Put, &renamed.Post, &renamed.Delete, &renamed.Options, &renamed.Head, &renamed.Patch, &renamed.Trace} {
+			if *op == nil {
+				continue
+			}
+			if requirements, ok := rename((*op).SecurityRequirement); ok {
+				copied := **op
+				copied.SecurityRequirement = requirements
+				*op = &copied
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if paths == nil {
+			paths = maps.Clone(s.Paths.Paths)
+		}
+		paths[name] = &renamed
+	}
+	if paths != nil {
+		s.Paths = &spec3.Paths{Paths: paths, VendorExtensible: s.Paths.VendorExtensible}
+	}
+	return s
+}
@@ -0,0 +1,565 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
+)
+
+type DebugSpecV3 struct {
+	*spec3.OpenAPI
+}
+
+func (d DebugSpecV3) String() string {
+	bytes, err := json.Marshal(d.OpenAPI)
+	if err != nil {
+		return fmt.Sprintf("DebugSpecV3.String failed: %s", err)
+	}
+	return string(bytes)
+}
+
+const selectSpecV3Input = `
+openapi: "3.0.0"
+info:
+  title: test
+  version: v1
+paths:
+  /test:
+    post:
+      operationId: addTest
+      requestBody:
+        $ref: "#/components/requestBodies/Test"
+      responses:
+        "200":
+          $ref: "#/components/responses/Ok"
+  /othertest:
+    delete:
+      operationId: deleteTest2
+      parameters:
+      - $ref: "#/components/parameters/body-deleteoptions"
+components:
+  schemas:
+    Test:
+      type: object
+      properties:
+        other:
+          $ref: "#/components/schemas/Other"
+    Other:
+      type: object
+      properties:
+        self:
+          $ref: "#/components/schemas/Other"
+    Status:
+      type: string
+    DeleteOptions:
+      type: object
+      properties:
+        preconditions:
+          $ref: "#/components/schemas/Preconditions"
+    Preconditions:
+      type: string
+  parameters:
+    body-deleteoptions:
+      name: body
+      in: query
+      schema:
+        $ref: "#/components/schemas/DeleteOptions"
+  requestBodies:
+    Test:
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Test"
+  responses:
+    Ok:
+      description: ok
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Status"
+  securitySchemes:
+    BearerToken:
+      type: apiKey
+      name: authorization
+      in: header
+`
+
+func TestSelectSpecV3PathsAndSchemas(t *testing.T) {
+	tcs := []struct {
+		name     string
+		paths    []string
+		schemas  []string
+		expected string
+	}{
+		{
+			name:  "paths",
+			paths: []string{"/test", "/notfound"},
+			expected: `
+openapi: "3.0.0"
+info:
+  title: test
+  version: v1
+paths:
+  /test:
+    post:
+      operationId: addTest
+      requestBody:
+        $ref: "#/components/requestBodies/Test"
+      responses:
+        "200":
+          $ref: "#/components/responses/Ok"
+components:
+  schemas:
+    Test:
+      type: object
+      properties:
+        other:
+          $ref: "#/components/schemas/Other"
+    Other:
+      type: object
+      properties:
+        self:
+          $ref: "#/components/schemas/Other"
+    Status:
+      type: string
+  requestBodies:
+    Test:
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Test"
+  responses:
+    Ok:
+      description: ok
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Status"
+  securitySchemes:
+    BearerToken:
+      type: apiKey
+      name: authorization
+      in: header
+`,
+		},
+		{
+			name:    "schemas",
+			schemas: []string{"DeleteOptions", "NotFound", "%zz", "a b/c~"},
+			expected: `
+openapi: "3.0.0"
+info:
+  title: test
+  version: v1
+paths: {}
+components:
+  schemas:
+    DeleteOptions:
+      type: object
+      properties:
+        preconditions:
+          $ref: "#/components/schemas/Preconditions"
+    Preconditions:
+      type: string
+  securitySchemes:
+    BearerToken:
+      type: apiKey
+      name: authorization
+      in: header
+`,
+		},
+		{
+			name:    "paths and schemas",
+			paths:   []string{"/othertest"},
+			schemas: []string{"Other"},
+			expected: `
+openapi: "3.0.0"
+info:
+  title: test
+  version: v1
+paths:
+  /othertest:
+    delete:
+      operationId: deleteTest2
+      parameters:
+      - $ref: "#/components/parameters/body-deleteoptions"
+components:
+  schemas:
+    Other:
+      type: object
+      properties:
+        self:
+          $ref: "#/components/schemas/Other"
+    DeleteOptions:
+      type: object
+      properties:
+        preconditions:
+          $ref: "#/components/schemas/Preconditions"
+    Preconditions:
+      type: string
+  parameters:
+    body-deleteoptions:
+      name: body
+      in: query
+      schema:
+        $ref: "#/components/schemas/DeleteOptions"
+  securitySchemes:
+    BearerToken:
+      type: apiKey
+      name: authorization
+      in: header
+`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ast := assert.New(t)
+			var input, orig, expected *spec3.OpenAPI
+			ast.NoError(yaml.Unmarshal([]byte(selectSpecV3Input), &input))
+			ast.NoError(yaml.Unmarshal([]byte(selectSpecV3Input), &orig))
+			ast.NoError(yaml.Unmarshal([]byte(tc.expected), &expected))
+
+			selected := SelectSpecV3PathsAndSchemas(input, tc.paths, tc.schemas)
+			ast.Equal(DebugSpecV3{expected}.String(), DebugSpecV3{selected}.String())
+			ast.Equal(DebugSpecV3{orig}.String(), DebugSpecV3{input}.String(), "unexpected mutation of input")
+		})
+	}
+}
+
+func TestFilterSpecV3ByPaths(t *testing.T) {
+	ast := assert.New(t)
+	var input, orig, expected *spec3.OpenAPI
+	ast.NoError(yaml.Unmarshal([]byte(selectSpecV3Input+`
+    Unused:
+      type: string
+`), &input))
+	ast.NoError(yaml.Unmarshal([]byte(selectSpecV3Input+`
+    Unused:
+      type: string
+`), &orig))
+	ast.NoError(yaml.Unmarshal([]byte(`
+openapi: "3.0.0"
+info:
+  title: test
+  version: v1
+paths:
+  /othertest:
+    delete:
+      operationId: deleteTest2
+      parameters:
+      - $ref: "#/components/parameters/body-deleteoptions"
+components:
+  schemas:
+    DeleteOptions:
+      type: object
+      properties:
+        preconditions:
+          $ref: "#/components/schemas/Preconditions"
+    Preconditions:
+      type: string
+  parameters:
+    body-deleteoptions:
+      name: body
+      in: query
+      schema:
+        $ref: "#/components/schemas/DeleteOptions"
+  securitySchemes:
+    BearerToken:
+      type: apiKey
+      name: authorization
+      in: header
+    Unused:
+      type: string
+`), &expected))
+
+	filtered := FilterSpecV3ByPathsWithoutSideEffects(input, []string{"/other"})
+	ast.Equal(DebugSpecV3{expected}.String(), DebugSpecV3{filtered}.String())
+	ast.Equal(DebugSpecV3{orig}.String(), DebugSpecV3{input}.String(), "unexpected mutation of input")
+}
+
+const mergeSpecsV3Dest = `
+openapi: "3.0.0"
+info:
+  title: dest
+  version: v1
+paths:
+  /test:
+    post:
+      parameters:
+      - $ref: "#/components/parameters/a"
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Test"
+      responses:
+        "200":
+          $ref: "#/components/responses/Ok"
+      security:
+      - BearerToken: []
+components:
+  schemas:
+    Test:
+      type: object
+      x-kubernetes-group-version-kind:
+      - group: ""
+        kind: Test
+        version: v1
+    Status:
+      type: string
+  parameters:
+    a:
+      name: a
+      in: query
+      schema:
+        $ref: "#/components/schemas/Test"
+  responses:
+    Ok:
+      description: ok
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Status"
+  securitySchemes:
+    BearerToken:
+      type: apiKey
+      name: authorization
+      in: header
+`
+
+const mergeSpecsV3Source = `
+openapi: "3.0.0"
+info:
+  title: source
+  version: v1
+paths:
+  /othertest:
+    post:
+      parameters:
+      - $ref: "#/components/parameters/a"
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Test"
+      responses:
+        "200":
+          $ref: "#/components/responses/Ok"
+      security:
+      - BearerToken: []
+  /gvk:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Shared"
+components:
+  schemas:
+    Test:
+      type: object
+      description: "This Test has a description"
+    Status:
+      type: string
+    Shared:
+      type: object
+      x-kubernetes-group-version-kind:
+      - group: ""
+        kind: Shared
+        version: v2
+  parameters:
+    a:
+      name: a
+      in: query
+      schema:
+        $ref: "#/components/schemas/Test"
+  responses:
+    Ok:
+      description: ok
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Status"
+  securitySchemes:
+    BearerToken:
+      type: http
+      scheme: bearer
+`
+
+func TestMergeSpecsV3(t *testing.T) {
+	ast := assert.New(t)
+	var dest, source, orig, expected *spec3.OpenAPI
+	ast.NoError(yaml.Unmarshal([]byte(mergeSpecsV3Dest), &dest))
+	ast.NoError(yaml.Unmarshal([]byte(mergeSpecsV3Source), &source))
+	ast.NoError(yaml.Unmarshal([]byte(mergeSpecsV3Source), &orig))
+	// Shared only differs by its GVK in the destination.
+	dest.Components.Schemas["Shared"] = &spec.Schema{
+		SchemaProps:      spec.SchemaProps{Type: []string{"object"}},
+		VendorExtensible: spec.VendorExtensible{Extensions: spec.Extensions{gvkKey: []interface{}{map[string]interface{}{"group": "", "kind": "Shared", "version": "v1"}}}},
+	}
+	ast.NoError(yaml.Unmarshal([]byte(`
+openapi: "3.0.0"
+info:
+  title: dest
+  version: v1
+paths:
+  /test:
+    post:
+      parameters:
+      - $ref: "#/components/parameters/a"
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Test"
+      responses:
+        "200":
+          $ref: "#/components/responses/Ok"
+      security:
+      - BearerToken: []
+  /othertest:
+    post:
+      parameters:
+      - $ref: "#/components/parameters/a_v2"
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Test_v2"
+      responses:
+        "200":
+          $ref: "#/components/responses/Ok"
+      security:
+      - BearerToken_v2: []
+  /gvk:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Shared"
+components:
+  schemas:
+    Test:
+      type: object
+      x-kubernetes-group-version-kind:
+      - group: ""
+        kind: Test
+        version: v1
+    Test_v2:
+      type: object
+      description: "This Test has a description"
+    Status:
+      type: string
+    Shared:
+      type: object
+      x-kubernetes-group-version-kind:
+      - group: ""
+        kind: Shared
+        version: v1
+      - group: ""
+        kind: Shared
+        version: v2
+  parameters:
+    a:
+      name: a
+      in: query
+      schema:
+        $ref: "#/components/schemas/Test"
+    a_v2:
+      name: a
+      in: query
+      schema:
+        $ref: "#/components/schemas/Test_v2"
+  responses:
+    Ok:
+      description: ok
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Status"
+  securitySchemes:
+    BearerToken:
+      type: apiKey
+      name: authorization
+      in: header
+    BearerToken_v2:
+      type: http
+      scheme: bearer
+`), &expected))
+
+	ast.NoError(MergeSpecsV3(dest, source))
+	ast.Equal(DebugSpecV3{expected}.String(), DebugSpecV3{dest}.String())
+	ast.Equal(DebugSpecV3{orig}.String(), DebugSpecV3{source}.String(), "unexpected mutation of source")
+
+	// Merging again reuses the renamed components.
+	source = nil
+	ast.NoError(yaml.Unmarshal([]byte(mergeSpecsV3Source), &source))
+	paths := map[string]*spec3.Path{}
+	for path, item := range source.Paths.Paths {
+		paths[path+"/again"] = item
+	}
+	source.Paths.Paths = paths
+	ast.NoError(MergeSpecsV3(dest, source))
+	ast.Len(dest.Components.Schemas, 4)
+	ast.Len(dest.Components.Parameters, 2)
+	ast.Equal("#/components/schemas/Test_v2", dest.Paths.Paths["/othertest/again"].Post.RequestBody.Content["application/json"].Schema.Ref.String())
+}
+
+func TestMergeSpecsV3PathConflicts(t *testing.T) {
+	ast := assert.New(t)
+	var dest, source *spec3.OpenAPI
+	ast.NoError(yaml.Unmarshal([]byte(mergeSpecsV3Dest), &dest))
+	ast.NoError(yaml.Unmarshal([]byte(mergeSpecsV3Source), &source))
+	source.Paths.Paths["/test"] = source.Paths.Paths["/othertest"]
+	delete(source.Paths.Paths, "/othertest")
+
+	ast.EqualError(MergeSpecsV3(dest, source), "unable to merge: duplicated path /test")
+
+	// The conflicting path of the source is dropped along with the
+	// components only it uses.
+	ast.NoError(MergeSpecsV3IgnorePathConflictRenamingComponents(dest, source))
+	ast.Equal("#/components/schemas/Test", dest.Paths.Paths["/test"].Post.RequestBody.Content["application/json"].Schema.Ref.String())
+	ast.Contains(dest.Paths.Paths, "/gvk")
+	ast.NotContains(dest.Components.Schemas, "Test_v2")
+	ast.NotContains(dest.Components.Parameters, "a_v2")
+}
+
+func TestMergeSpecsV3FailOnComponentConflict(t *testing.T) {
+	ast := assert.New(t)
+	var dest, source *spec3.OpenAPI
+	ast.NoError(yaml.Unmarshal([]byte(mergeSpecsV3Dest), &dest))
+	ast.NoError(yaml.Unmarshal([]byte(mergeSpecsV3Source), &source))
+	ast.EqualError(MergeSpecsV3FailOnComponentConflict(dest, source), "schema name conflict in merging OpenAPI spec: Test")
+
+	var identical *spec3.OpenAPI
+	ast.NoError(yaml.Unmarshal([]byte(mergeSpecsV3Dest), &identical))
+	source = identical
+	source.Paths.Paths["/copy"] = source.Paths.Paths["/test"]
+	delete(source.Paths.Paths, "/test")
+	ast.NoError(MergeSpecsV3FailOnComponentConflict(dest, source))
+	ast.Contains(dest.Paths.Paths, "/copy")
+}
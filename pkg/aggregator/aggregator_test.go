@@ -24,10 +24,11 @@ import (
 	"reflect"
 	"testing"
 
+	openapi_v2 "github.com/google/gnostic-models/openapiv2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 
-	"k8s.io/kube-openapi/pkg/handler"
 	"k8s.io/kube-openapi/pkg/validation/spec"
 	"sigs.k8s.io/yaml"
 )
@@ -2117,7 +2118,8 @@ func BenchmarkMergeSpecsIgnorePathConflictsWithKubeSpec(b *testing.B) {
 		}
 
 		specBytes, _ := sp.MarshalJSON()
-		handler.ToProtoBinary(specBytes)
+		document, _ := openapi_v2.ParseDocument(specBytes)
+		proto.Marshal(document)
 
 		b.StopTimer()
 	}
@@ -2507,3 +2509,30 @@ func TestDeepEqualDefinitionsModuloGVKs(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeSpecsDoesNotMutatePreviousSources(t *testing.T) {
+	gvk := func(group string) spec.Extensions {
+		return spec.Extensions{gvkKey: []interface{}{map[string]interface{}{"group": group, "version": "v1", "kind": "Foo"}}}
+	}
+	source := func(path, group string) *spec.Swagger {
+		return &spec.Swagger{SwaggerProps: spec.SwaggerProps{
+			Paths: &spec.Paths{Paths: map[string]spec.PathItem{path: {}}},
+			Definitions: spec.Definitions{
+				"Foo": {VendorExtensible: spec.VendorExtensible{Extensions: gvk(group)}, SchemaProps: spec.SchemaProps{Type: []string{"object"}}},
+				"Bar": {SchemaProps: spec.SchemaProps{Type: []string{"object"}}},
+			},
+		}}
+	}
+	first, second := source("/first", "group1"), source("/second", "group2")
+	// Bar gets a GVK in the second source only.
+	second.Definitions["Bar"] = spec.Schema{VendorExtensible: spec.VendorExtensible{Extensions: gvk("group2")}, SchemaProps: spec.SchemaProps{Type: []string{"object"}}}
+
+	dest := &spec.Swagger{}
+	require.NoError(t, MergeSpecs(dest, first))
+	require.NoError(t, MergeSpecs(dest, second))
+
+	assert.Equal(t, gvk("group1"), first.Definitions["Foo"].Extensions)
+	assert.Nil(t, first.Definitions["Bar"].Extensions)
+	assert.Len(t, dest.Definitions["Foo"].Extensions[gvkKey], 2)
+	assert.Equal(t, gvk("group2"), dest.Definitions["Bar"].Extensions)
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/schemamutation"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// CanonicalizeDefinitions collapses structurally equivalent definitions,
+// e.g. vendored copies of ObjectMeta, into a single canonical definition,
+// and rewrites the references to the other ones. This is typically done
+// once all specs are merged, to shrink the merged spec.
+//
+// Definitions are equivalent if they are the same, ignoring descriptions
+// and the groups and versions of the x-kubernetes-group-version-kind
+// extension, once the definitions they reference are known to be
+// equivalent, which also holds for recursive definitions. Definitions of
+// different types, i.e. whose names end with different type names, e.g.
+// Time and MicroTime, or of different kinds, e.g. RoleBinding and
+// ClusterRoleBinding, are not equivalent. The canonical definition of
+// equivalent ones is the one preferred by DefaultCanonicalNameLess. It
+// keeps its descriptions, and the x-kubernetes-group-version-kind
+// extensions of the collapsed definitions are merged into it.
+//
+// The input is not modified, and is returned as is if no definition is
+// collapsed. Otherwise the returned spec has a new definition map, and the
+// objects holding a rewritten reference are copies, but everything else is
+// shared with the input.
+func CanonicalizeDefinitions(sp *spec.Swagger) (*spec.Swagger, error) {
+	return CanonicalizeDefinitionsWithOptions(sp, CanonicalizeOptions{})
+}
+
+// CanonicalizeOptions configures CanonicalizeDefinitionsWithOptions and
+// CanonicalizeDefinitionsV3WithOptions.
+type CanonicalizeOptions struct {
+	// NameLess returns true if name1 is preferred over name2 as the name
+	// of the canonical definition of equivalent definitions. The default
+	// is DefaultCanonicalNameLess.
+	NameLess func(name1, name2 string) bool
+}
+
+// DefaultCanonicalNameLess prefers the names of the definitions native to
+// Kubernetes, i.e. starting with "io.k8s.", over the ones of vendored
+// copies, then names without the "_v<n>" suffix of a definition renamed
+// when merging, then the shortest names, and then the first in
+// alphabetical order.
+func DefaultCanonicalNameLess(name1, name2 string) bool {
+	if native1, native2 := strings.HasPrefix(name1, "io.k8s."), strings.HasPrefix(name2, "io.k8s."); native1 != native2 {
+		return native1
+	}
+	if renamed1, renamed2 := renameSuffix.MatchString(name1), renameSuffix.MatchString(name2); renamed1 != renamed2 {
+		return renamed2
+	}
+	if len(name1) != len(name2) {
+		return len(name1) < len(name2)
+	}
+	return name1 < name2
+}
+
+// renameSuffix matches the suffix added to the names of the definitions
+// renamed when merging with the default MergeOptions.Rename.
+var renameSuffix = regexp.MustCompile(`_v[0-9]+$`)
+
+// typeName returns the name of the type of a definition, e.g. "ObjectMeta"
+// for "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta_v2".
+func typeName(name string) string {
+	name = renameSuffix.ReplaceAllString(name, "")
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// CanonicalizeDefinitionsWithOptions is the same as CanonicalizeDefinitions,
+// picking canonical names as configured by opts.
+func CanonicalizeDefinitionsWithOptions(sp *spec.Swagger, opts CanonicalizeOptions) (*spec.Swagger, error) {
+	definitions := make(map[string]*spec.Schema, len(sp.Definitions))
+	for k := range sp.Definitions {
+		def := sp.Definitions[k]
+		definitions[k] = &def
+	}
+	renames, err := canonicalNames(definitionPrefix, definitions, opts)
+	if err != nil || len(renames) == 0 {
+		return sp, err
+	}
+
+	ret := schemamutation.ReplaceReferences(canonicalRefs(definitionPrefix, renames), sp)
+	if ret == sp {
+		shallowCopy := *sp
+		ret = &shallowCopy
+	}
+	renamed := make(map[string]*spec.Schema, len(ret.Definitions))
+	for k := range ret.Definitions {
+		def := ret.Definitions[k]
+		renamed[k] = &def
+	}
+	canonical, err := collapseDefinitions(renamed, renames)
+	if err != nil {
+		return nil, err
+	}
+	ret.Definitions = make(spec.Definitions, len(canonical))
+	for k, v := range canonical {
+		ret.Definitions[k] = *v
+	}
+	return ret, nil
+}
+
+// CanonicalizeDefinitionsV3 is the same as CanonicalizeDefinitions for the
+// schemas of the components of an OpenAPI v3 spec. If a schema is
+// collapsed, the components and their schema map are new, the other
+// component maps being shared with the input.
+func CanonicalizeDefinitionsV3(sp *spec3.OpenAPI) (*spec3.OpenAPI, error) {
+	return CanonicalizeDefinitionsV3WithOptions(sp, CanonicalizeOptions{})
+}
+
+// CanonicalizeDefinitionsV3WithOptions is the same as
+// CanonicalizeDefinitionsV3, picking canonical names as configured by opts.
+func CanonicalizeDefinitionsV3WithOptions(sp *spec3.OpenAPI, opts CanonicalizeOptions) (*spec3.OpenAPI, error) {
+	if sp.Components == nil {
+		return sp, nil
+	}
+	renames, err := canonicalNames(schemaPrefixV3, sp.Components.Schemas, opts)
+	if err != nil || len(renames) == 0 {
+		return sp, err
+	}
+
+	ret := schemamutation.ReplaceReferencesV3(canonicalRefs(schemaPrefixV3, renames), sp)
+	if ret == sp {
+		shallowCopy := *sp
+		ret = &shallowCopy
+	}
+	components := *ret.Components
+	components.Schemas, err = collapseDefinitions(components.Schemas, renames)
+	if err != nil {
+		return nil, err
+	}
+	ret.Components = &components
+	return ret, nil
+}
+
+// refPattern matches the references of a marshaled schema.
+var refPattern = regexp.MustCompile(`"\$ref":("(?:[^"\\]|\\.)*")`)
+
+// canonicalNames returns the canonical names of the definitions that are
+// equivalent to another one with a canonical name.
+//
+// Equivalent definitions are found by partition refinement: definitions
+// start in one class per type name, and are split at each round by their normalized
+// JSON, where the references to definitions are replaced by the classes of
+// the referenced definitions, until no class is split anymore.
+func canonicalNames(prefix string, definitions map[string]*spec.Schema, opts CanonicalizeOptions) (map[string]string, error) {
+	less := opts.NameLess
+	if less == nil {
+		less = DefaultCanonicalNameLess
+	}
+	names := slices.Sorted(maps.Keys(definitions))
+	normalized := make(map[string]string, len(names))
+	for _, name := range names {
+		data, err := json.Marshal(normalizeDefinition(definitions[name]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal definition %s: %w", name, err)
+		}
+		normalized[name] = string(data)
+	}
+
+	classes := make(map[string]int, len(names))
+	types := map[string]int{}
+	for _, name := range names {
+		class, found := types[typeName(name)]
+		if !found {
+			class = len(types)
+			types[typeName(name)] = class
+		}
+		classes[name] = class
+	}
+	count := len(types)
+	for {
+		ids := map[string]int{}
+		next := make(map[string]int, len(names))
+		for _, name := range names {
+			key := strconv.Itoa(classes[name]) + refPattern.ReplaceAllStringFunc(normalized[name], func(match string) string {
+				ref, err := strconv.Unquote(refPattern.FindStringSubmatch(match)[1])
+				if err != nil || !strings.HasPrefix(ref, prefix) {
+					return match
+				}
+				if class, found := classes[ref[len(prefix):]]; found {
+					return `"$ref":` + strconv.Itoa(class)
+				}
+				return match
+			})
+			id, found := ids[key]
+			if !found {
+				id = len(ids)
+				ids[key] = id
+			}
+			next[name] = id
+		}
+		classes = next
+		if len(ids) == count {
+			break
+		}
+		count = len(ids)
+	}
+
+	canonical := make(map[int]string, count)
+	for _, name := range names {
+		if c, found := canonical[classes[name]]; !found || less(name, c) {
+			canonical[classes[name]] = name
+		}
+	}
+	renames := map[string]string{}
+	for _, name := range names {
+		if c := canonical[classes[name]]; c != name {
+			renames[name] = c
+		}
+	}
+	return renames, nil
+}
+
+// normalizeDefinition returns the definition without descriptions, and with
+// the kinds of its x-kubernetes-group-version-kind extension only.
+func normalizeDefinition(def *spec.Schema) *spec.Schema {
+	ret := *StripSchema(def, StripOptions{Descriptions: true})
+	if gvks, found := ret.Extensions[gvkKey]; found {
+		kinds := map[string]bool{}
+		if gvks, ok := gvks.([]interface{}); ok {
+			for _, gvk := range gvks {
+				if gvk, ok := gvk.(map[string]interface{}); ok {
+					kinds[fmt.Sprint(gvk["kind"])] = true
+				}
+			}
+		}
+		ret.Extensions = maps.Clone(ret.Extensions)
+		ret.Extensions[gvkKey] = kinds
+	}
+	return &ret
+}
+
+func canonicalRefs(prefix string, renames map[string]string) func(ref *spec.Ref) *spec.Ref {
+	return func(ref *spec.Ref) *spec.Ref {
+		refStr := ref.String()
+		if !strings.HasPrefix(refStr, prefix) {
+			return ref
+		}
+		if newName, found := renames[refStr[len(prefix):]]; found {
+			ret := spec.MustCreateRef(prefix + newName)
+			return &ret
+		}
+		return ref
+	}
+}
+
+// collapseDefinitions returns the definitions without the renamed ones,
+// with their x-kubernetes-group-version-kind extensions merged into the
+// canonical ones.
+func collapseDefinitions(definitions map[string]*spec.Schema, renames map[string]string) (map[string]*spec.Schema, error) {
+	ret := make(map[string]*spec.Schema, len(definitions)-len(renames))
+	for k, v := range definitions {
+		if _, found := renames[k]; !found {
+			ret[k] = v
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(renames)) {
+		canonical := ret[renames[k]]
+		gvks, changed, err := mergedGVKs(canonical, definitions[k])
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge definition %s into %s: %w", k, renames[k], err)
+		}
+		if changed {
+			merged := *canonical
+			merged.Extensions = maps.Clone(canonical.Extensions)
+			if merged.Extensions == nil {
+				merged.Extensions = spec.Extensions{}
+			}
+			merged.Extensions[gvkKey] = gvks
+			ret[renames[k]] = &merged
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,330 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
+)
+
+func TestCanonicalizeDefinitions(t *testing.T) {
+	var sp *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /apis/a/v1/foos:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/a.v1.Foo"
+  /apis/b/v1/foos:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/b.v1.Foo"
+  /apis/b/v1/bazs:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/b.v1.Baz"
+definitions:
+  a.v1.Foo:
+    type: object
+    description: A foo
+    x-kubernetes-group-version-kind:
+    - group: a
+      version: v1
+      kind: Foo
+    properties:
+      metadata:
+        $ref: "#/definitions/ObjectMeta"
+      spec:
+        $ref: "#/definitions/a.v1.Tree"
+  b.v1.Foo:
+    type: object
+    description: A foo of b
+    x-kubernetes-group-version-kind:
+    - group: b
+      version: v1
+      kind: Foo
+    properties:
+      metadata:
+        $ref: "#/definitions/vendor.ObjectMeta"
+      spec:
+        $ref: "#/definitions/b.v1.Tree"
+  b.v1.Baz:
+    type: object
+    description: A baz, which is not a foo
+    x-kubernetes-group-version-kind:
+    - group: b
+      version: v1
+      kind: Baz
+    properties:
+      metadata:
+        $ref: "#/definitions/vendor.ObjectMeta"
+      spec:
+        $ref: "#/definitions/b.v1.Tree"
+  ObjectMeta:
+    type: object
+    description: Standard object metadata
+    properties:
+      name:
+        type: string
+        description: The name
+  vendor.ObjectMeta:
+    type: object
+    description: Vendored object metadata
+    properties:
+      name:
+        type: string
+        description: The vendored name
+  a.v1.Tree:
+    type: object
+    properties:
+      children:
+        type: array
+        items:
+          $ref: "#/definitions/a.v1.Tree"
+  b.v1.Tree:
+    type: object
+    properties:
+      children:
+        type: array
+        items:
+          $ref: "#/definitions/b.v1.Tree"
+  b.v1.Other:
+    type: object
+    properties:
+      name:
+        type: integer
+`), &sp))
+	orig, err := cloneSpec(sp)
+	require.NoError(t, err)
+
+	canonical, err := CanonicalizeDefinitions(sp)
+	require.NoError(t, err)
+	assert.Equal(t, DebugSpec{orig}, DebugSpec{sp}, "input mutated")
+
+	var expected *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /apis/a/v1/foos:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/a.v1.Foo"
+  /apis/b/v1/foos:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/a.v1.Foo"
+  /apis/b/v1/bazs:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/b.v1.Baz"
+definitions:
+  a.v1.Foo:
+    type: object
+    description: A foo
+    x-kubernetes-group-version-kind:
+    - group: a
+      version: v1
+      kind: Foo
+    - group: b
+      version: v1
+      kind: Foo
+    properties:
+      metadata:
+        $ref: "#/definitions/ObjectMeta"
+      spec:
+        $ref: "#/definitions/a.v1.Tree"
+  b.v1.Baz:
+    type: object
+    description: A baz, which is not a foo
+    x-kubernetes-group-version-kind:
+    - group: b
+      version: v1
+      kind: Baz
+    properties:
+      metadata:
+        $ref: "#/definitions/ObjectMeta"
+      spec:
+        $ref: "#/definitions/a.v1.Tree"
+  ObjectMeta:
+    type: object
+    description: Standard object metadata
+    properties:
+      name:
+        type: string
+        description: The name
+  a.v1.Tree:
+    type: object
+    properties:
+      children:
+        type: array
+        items:
+          $ref: "#/definitions/a.v1.Tree"
+  b.v1.Other:
+    type: object
+    properties:
+      name:
+        type: integer
+`), &expected))
+	assert.Equal(t, DebugSpec{expected}, DebugSpec{canonical})
+
+	again, err := CanonicalizeDefinitions(canonical)
+	require.NoError(t, err)
+	assert.Same(t, canonical, again)
+}
+
+func TestCanonicalizeDefinitionsWithKubeSpec(t *testing.T) {
+	specs, _ := loadTestData()
+	merged, err := cloneSpec(specs[0])
+	require.NoError(t, err)
+	for _, s := range specs[1:] {
+		require.NoError(t, MergeSpecsIgnorePathConflictRenamingDefinitionsAndParameters(merged, s))
+	}
+
+	canonical, err := CanonicalizeDefinitions(merged)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(canonical.Definitions), len(merged.Definitions))
+	for _, f := range CheckReferences(canonical) {
+		assert.False(t, f.Problem.Invalid(), "%v", f)
+	}
+	assert.Contains(t, canonical.Definitions, "io.k8s.api.rbac.v1.RoleBinding")
+	assert.Contains(t, canonical.Definitions, "io.k8s.api.rbac.v1.ClusterRoleBinding", "different kinds are not equivalent")
+	assert.Contains(t, canonical.Definitions, "io.k8s.apimachinery.pkg.apis.meta.v1.MicroTime", "different types are not equivalent")
+}
+
+func TestCanonicalizeDefinitionsVendoredCopies(t *testing.T) {
+	var sp *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /apis/a/v1/foos:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/com.a.v1.Foo"
+definitions:
+  com.a.v1.Foo:
+    type: object
+    properties:
+      metadata:
+        $ref: "#/definitions/com.a.ObjectMeta"
+      time:
+        $ref: "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.Time"
+      microTime:
+        $ref: "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.MicroTime"
+  com.a.ObjectMeta:
+    type: object
+    description: Vendored object metadata
+    properties:
+      name:
+        type: string
+  io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta:
+    type: object
+    description: Standard object metadata
+    properties:
+      name:
+        type: string
+  io.k8s.apimachinery.pkg.apis.meta.v1.Time:
+    type: string
+    format: date-time
+  io.k8s.apimachinery.pkg.apis.meta.v1.MicroTime:
+    type: string
+    format: date-time
+`), &sp))
+
+	canonical, err := CanonicalizeDefinitions(sp)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"com.a.v1.Foo",
+		"io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta",
+		"io.k8s.apimachinery.pkg.apis.meta.v1.Time",
+		"io.k8s.apimachinery.pkg.apis.meta.v1.MicroTime",
+	}, keys(canonical.Definitions))
+	metadata, microTime := canonical.Definitions["com.a.v1.Foo"].Properties["metadata"], canonical.Definitions["com.a.v1.Foo"].Properties["microTime"]
+	assert.Equal(t, "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta", metadata.Ref.String())
+	assert.Equal(t, "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.MicroTime", microTime.Ref.String())
+
+	// The caller can prefer other names.
+	canonical, err = CanonicalizeDefinitionsWithOptions(sp, CanonicalizeOptions{
+		NameLess: func(name1, name2 string) bool {
+			return strings.HasPrefix(name1, "com.a.") && !strings.HasPrefix(name2, "com.a.")
+		},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, canonical.Definitions, "com.a.ObjectMeta")
+	assert.NotContains(t, canonical.Definitions, "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta")
+}
+
+func TestCanonicalizeDefinitionsV3(t *testing.T) {
+	var sp *spec3.OpenAPI
+	require.NoError(t, yaml.Unmarshal([]byte(`
+openapi: 3.0.0
+info:
+  title: test
+  version: v1
+paths:
+  /b:
+    get:
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/vendor.A"
+components:
+  schemas:
+    A:
+      type: string
+      description: An A
+    vendor.A:
+      type: string
+      description: A vendored A
+`), &sp))
+	origJSON, err := json.Marshal(sp)
+	require.NoError(t, err)
+
+	canonical, err := CanonicalizeDefinitionsV3(sp)
+	require.NoError(t, err)
+	afterJSON, err := json.Marshal(sp)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(origJSON), string(afterJSON), "input mutated")
+
+	assert.Equal(t, []string{"A"}, keys(canonical.Components.Schemas))
+	assert.Equal(t, "#/components/schemas/A", canonical.Paths.Paths["/b"].Get.Responses.StatusCodeResponses[200].Content["application/json"].Schema.Ref.String())
+}
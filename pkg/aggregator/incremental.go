@@ -0,0 +1,350 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// Aggregator merges the specs of several sources, and updates the merged
+// spec incrementally when the spec of a source is replaced or removed.
+//
+// The merged spec is the same as merging the specs of the sources with
+// MergeSpecsWithOptions, in the order they were last replaced in. To that
+// end, the aggregator remembers which sources contributed each path,
+// definition and parameter. A definition or parameter is removed when no
+// source contributes it anymore, and the x-kubernetes-group-version-kind
+// extension of a definition shared by several sources is recomputed from
+// the remaining ones. Removing a source that took part in a conflict
+// rebuilds the merged spec from scratch, since the other sources would
+// have been merged differently without it.
+//
+// An Aggregator is safe for concurrent use.
+type Aggregator struct {
+	template *spec.Swagger
+	opts     MergeOptions
+
+	// lock serializes the updates of current.
+	lock sync.Mutex
+	// current is never mutated, updates replace it.
+	current atomic.Pointer[aggregation]
+}
+
+// NewAggregator returns an aggregator without sources. The merged spec has
+// the fields of template, except for the paths, definitions and parameters
+// which are the ones of the sources. Conflicts are resolved as configured
+// by opts.
+func NewAggregator(template *spec.Swagger, opts MergeOptions) *Aggregator {
+	a := &Aggregator{template: template, opts: opts}
+	a.current.Store(a.newAggregation())
+	return a
+}
+
+// Spec returns the merged spec. It must not be mutated, and it is not
+// mutated by later updates of the aggregator.
+func (a *Aggregator) Spec() *spec.Swagger {
+	return a.current.Load().merged
+}
+
+// Sources returns the IDs of the sources, in merge order.
+func (a *Aggregator) Sources() []string {
+	sources := a.current.Load().sources
+	ids := make([]string, 0, len(sources))
+	for _, src := range sources {
+		ids = append(ids, src.id)
+	}
+	return ids
+}
+
+// Replace sets the spec of a source, which is merged last. The previous
+// spec of the source, if any, is removed first. The spec is not mutated,
+// and must not be mutated afterwards. If the merge fails, the aggregator
+// is left unchanged.
+func (a *Aggregator) Replace(sourceID string, s *spec.Swagger) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	ag, err := a.current.Load().without(a, sourceID)
+	if err != nil {
+		return err
+	}
+	if err := ag.add(a.opts, &aggregatedSource{id: sourceID, spec: s}); err != nil {
+		return fmt.Errorf("failed to merge the spec of %s: %w", sourceID, err)
+	}
+	a.current.Store(ag)
+	return nil
+}
+
+// Remove removes the spec of a source. It's a no-op if there is no such
+// source.
+func (a *Aggregator) Remove(sourceID string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	ag, err := a.current.Load().without(a, sourceID)
+	if err != nil {
+		return err
+	}
+	a.current.Store(ag)
+	return nil
+}
+
+func (a *Aggregator) newAggregation() *aggregation {
+	merged := &spec.Swagger{}
+	if a.template != nil {
+		*merged = *a.template
+	}
+	merged.Paths = &spec.Paths{Paths: map[string]spec.PathItem{}}
+	if a.template != nil && a.template.Paths != nil {
+		merged.Paths.VendorExtensible = a.template.Paths.VendorExtensible
+	}
+	merged.Definitions = spec.Definitions{}
+	merged.Parameters = map[string]spec.Parameter{}
+	return &aggregation{
+		merged:      merged,
+		pathOwners:  map[string]*aggregatedSource{},
+		definitions: map[string][]*aggregatedSource{},
+		parameters:  map[string][]*aggregatedSource{},
+	}
+}
+
+// aggregatedSource is a source of an aggregator.
+type aggregatedSource struct {
+	id   string
+	spec *spec.Swagger
+	// merged is the spec as merged, with its definitions and parameters
+	// renamed, nil if nothing was merged.
+	merged *spec.Swagger
+	// conflicting is true if the source took part in a conflict, either
+	// with a source merged after it, or by replacing the path, definition
+	// or parameter of a source merged before it.
+	conflicting bool
+}
+
+// aggregation is the state of an aggregator.
+type aggregation struct {
+	merged *spec.Swagger
+	// sources are in merge order.
+	sources []*aggregatedSource
+	// pathOwners are the sources of the merged paths.
+	pathOwners map[string]*aggregatedSource
+	// definitions and parameters are the sources contributing the merged
+	// definitions and parameters, in merge order. The merged one is the
+	// one of the first source, unless it is the result of a conflict.
+	definitions map[string][]*aggregatedSource
+	parameters  map[string][]*aggregatedSource
+}
+
+// clone returns a copy of ag that can be updated without mutating ag. The
+// sources are copied too, since updating the copy may mark them as
+// conflicting, but their specs are shared.
+func (ag *aggregation) clone() *aggregation {
+	merged := *ag.merged
+	merged.Paths = &spec.Paths{VendorExtensible: ag.merged.Paths.VendorExtensible, Paths: maps.Clone(ag.merged.Paths.Paths)}
+	merged.Definitions = maps.Clone(ag.merged.Definitions)
+	merged.Parameters = maps.Clone(ag.merged.Parameters)
+
+	sources := make(map[*aggregatedSource]*aggregatedSource, len(ag.sources))
+	ret := &aggregation{
+		merged:     &merged,
+		sources:    make([]*aggregatedSource, 0, len(ag.sources)),
+		pathOwners: make(map[string]*aggregatedSource, len(ag.pathOwners)),
+	}
+	for _, src := range ag.sources {
+		c := *src
+		sources[src] = &c
+		ret.sources = append(ret.sources, &c)
+	}
+	for k, src := range ag.pathOwners {
+		ret.pathOwners[k] = sources[src]
+	}
+	ret.definitions = cloneContributors(ag.definitions, sources)
+	ret.parameters = cloneContributors(ag.parameters, sources)
+	return ret
+}
+
+func cloneContributors(m map[string][]*aggregatedSource, sources map[*aggregatedSource]*aggregatedSource) map[string][]*aggregatedSource {
+	ret := make(map[string][]*aggregatedSource, len(m))
+	for k, v := range m {
+		contributors := make([]*aggregatedSource, len(v))
+		for i, src := range v {
+			contributors[i] = sources[src]
+		}
+		ret[k] = contributors
+	}
+	return ret
+}
+
+// without returns a copy of ag without the source with the given ID.
+func (ag *aggregation) without(a *Aggregator, id string) (*aggregation, error) {
+	i := slices.IndexFunc(ag.sources, func(src *aggregatedSource) bool { return src.id == id })
+	if i < 0 {
+		return ag.clone(), nil
+	}
+	removed := ag.sources[i]
+	if !removed.conflicting {
+		ret := ag.clone()
+		if err := ret.remove(i); err == nil {
+			return ret, nil
+		}
+	}
+
+	// Rebuild from scratch.
+	ret := a.newAggregation()
+	for _, src := range ag.sources {
+		if src == removed {
+			continue
+		}
+		if err := ret.add(a.opts, &aggregatedSource{id: src.id, spec: src.spec}); err != nil {
+			return nil, fmt.Errorf("failed to merge the spec of %s without %s: %w", src.id, id, err)
+		}
+	}
+	return ret, nil
+}
+
+// add merges src last.
+func (ag *aggregation) add(opts MergeOptions, src *aggregatedSource) error {
+	plan, err := planMerge(ag.merged, src.spec, opts)
+	if err != nil {
+		return err
+	}
+	ag.sources = append(ag.sources, src)
+	if plan == nil {
+		return nil
+	}
+	if err := plan.apply(ag.merged); err != nil {
+		return err
+	}
+
+	preferSourcePaths := map[string]bool{}
+	for _, c := range plan.pathConflicts {
+		if owner, found := ag.pathOwners[c.from]; found {
+			owner.conflicting = true
+		}
+		if c.resolution == ConflictPreferSource {
+			src.conflicting = true
+			preferSourcePaths[c.from] = true
+		}
+	}
+	ag.markConflicting(src, ag.definitions, plan.definitionConflicts, opts.Rename)
+	ag.markConflicting(src, ag.parameters, plan.parameterConflicts, opts.Rename)
+	if plan.source == nil {
+		return nil
+	}
+
+	src.merged = plan.merged
+	for k := range plan.merged.Paths.Paths {
+		if _, found := ag.pathOwners[k]; !found || preferSourcePaths[k] {
+			ag.pathOwners[k] = src
+		}
+	}
+	for k := range plan.merged.Definitions {
+		ag.definitions[k] = append(ag.definitions[k], src)
+	}
+	for k := range plan.merged.Parameters {
+		ag.parameters[k] = append(ag.parameters[k], src)
+	}
+	return nil
+}
+
+// markConflicting marks the sources contributing the conflicting entries
+// of src, and the names renamed entries had to skip, as conflicting.
+func (ag *aggregation) markConflicting(src *aggregatedSource, contributors map[string][]*aggregatedSource, conflicts []rename, renameFn func(string, int) string) {
+	if renameFn == nil {
+		renameFn = defaultRename
+	}
+	mark := func(name string) {
+		for _, c := range contributors[name] {
+			c.conflicting = true
+		}
+	}
+	for _, c := range conflicts {
+		mark(c.from)
+		switch c.resolution {
+		case ConflictPreferSource:
+			src.conflicting = true
+		case ConflictRename:
+			for n := 2; ; n++ {
+				name := renameFn(c.from, n)
+				mark(name)
+				if name == c.to {
+					break
+				}
+			}
+		}
+	}
+}
+
+// remove removes the i-th source, which must not be conflicting.
+func (ag *aggregation) remove(i int) error {
+	src := ag.sources[i]
+	ag.sources = slices.Delete(ag.sources, i, i+1)
+	if src.merged == nil {
+		return nil
+	}
+
+	for k := range src.merged.Paths.Paths {
+		if ag.pathOwners[k] == src {
+			delete(ag.pathOwners, k)
+			delete(ag.merged.Paths.Paths, k)
+		}
+	}
+	for k := range src.merged.Definitions {
+		contributors := withoutSource(ag.definitions[k], src)
+		if len(contributors) == 0 {
+			delete(ag.definitions, k)
+			delete(ag.merged.Definitions, k)
+			continue
+		}
+		ag.definitions[k] = contributors
+		// Recompute the definition as merged by the remaining sources.
+		merged := contributors[0].merged.Definitions[k]
+		for _, c := range contributors[1:] {
+			v := c.merged.Definitions[k]
+			gvks, changed, err := mergedGVKs(&merged, &v)
+			if err != nil {
+				return err
+			}
+			if changed {
+				extensions := make(spec.Extensions, len(merged.Extensions)+1)
+				maps.Copy(extensions, merged.Extensions)
+				extensions[gvkKey] = gvks
+				merged.Extensions = extensions
+			}
+		}
+		ag.merged.Definitions[k] = merged
+	}
+	for k := range src.merged.Parameters {
+		contributors := withoutSource(ag.parameters[k], src)
+		if len(contributors) == 0 {
+			delete(ag.parameters, k)
+			delete(ag.merged.Parameters, k)
+			continue
+		}
+		ag.parameters[k] = contributors
+		ag.merged.Parameters[k] = contributors[0].merged.Parameters[k]
+	}
+	return nil
+}
+
+func withoutSource(sources []*aggregatedSource, src *aggregatedSource) []*aggregatedSource {
+	return slices.DeleteFunc(slices.Clone(sources), func(s *aggregatedSource) bool { return s == src })
+}
@@ -0,0 +1,291 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
+)
+
+// expectMergedFromScratch checks that the spec of the aggregator is the same
+// as merging the specs of its sources from scratch.
+func expectMergedFromScratch(t *testing.T, a *Aggregator, template *spec.Swagger, opts MergeOptions, specs map[string]*spec.Swagger) {
+	t.Helper()
+	expected := *template
+	expected.Paths = &spec.Paths{Paths: map[string]spec.PathItem{}}
+	expected.Definitions = spec.Definitions{}
+	expected.Parameters = map[string]spec.Parameter{}
+	for _, id := range a.Sources() {
+		require.NoError(t, MergeSpecsWithOptions(&expected, specs[id], opts), "merging %s", id)
+	}
+	assert.Equal(t, DebugSpec{&expected}, DebugSpec{a.Spec()})
+}
+
+func TestAggregatorWithKubeSpec(t *testing.T) {
+	loaded, _ := loadTestData()
+	template := &spec.Swagger{SwaggerProps: spec.SwaggerProps{Swagger: "2.0", Info: loaded[0].Info}}
+	opts := legacyMergeOptions(true, true, true)
+	specs := map[string]*spec.Swagger{}
+	for i, s := range loaded {
+		specs[fmt.Sprint(i)] = s
+	}
+
+	a := NewAggregator(template, opts)
+	for _, id := range []string{"0", "1", "2"} {
+		require.NoError(t, a.Replace(id, specs[id]))
+		expectMergedFromScratch(t, a, template, opts, specs)
+	}
+	before := a.Spec()
+	beforeJSON, err := before.MarshalJSON()
+	require.NoError(t, err)
+
+	for _, step := range []struct {
+		remove bool
+		id     string
+	}{
+		{remove: true, id: "1"},
+		{id: "1"},
+		{id: "0"},
+		{remove: true, id: "2"},
+		{id: "2"},
+		{remove: true, id: "missing"},
+		{remove: true, id: "0"},
+		{remove: true, id: "1"},
+	} {
+		if step.remove {
+			require.NoError(t, a.Remove(step.id))
+		} else {
+			require.NoError(t, a.Replace(step.id, specs[step.id]))
+		}
+		expectMergedFromScratch(t, a, template, opts, specs)
+	}
+	assert.Equal(t, []string{"2"}, a.Sources())
+
+	require.NoError(t, a.Remove("2"))
+	assert.Empty(t, a.Spec().Paths.Paths)
+	assert.Empty(t, a.Spec().Definitions)
+	assert.Empty(t, a.Spec().Parameters)
+
+	afterJSON, err := before.MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, string(beforeJSON), string(afterJSON), "returned spec mutated")
+}
+
+func TestAggregatorSharedDefinitions(t *testing.T) {
+	specs := map[string]*spec.Swagger{}
+	for id, yamlSpec := range map[string]string{
+		"a": `
+swagger: "2.0"
+paths:
+  /a:
+    get:
+      parameters:
+      - $ref: "#/parameters/p"
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/Shared"
+definitions:
+  Shared:
+    type: object
+    x-kubernetes-group-version-kind:
+    - group: a
+      kind: Shared
+      version: v1
+parameters:
+  p:
+    in: query
+    name: p
+    type: string
+`,
+		"b": `
+swagger: "2.0"
+paths:
+  /b:
+    get:
+      parameters:
+      - $ref: "#/parameters/p"
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/Shared"
+definitions:
+  Shared:
+    type: object
+    x-kubernetes-group-version-kind:
+    - group: b
+      kind: Shared
+      version: v1
+parameters:
+  p:
+    in: query
+    name: p
+    type: string
+`,
+		"c": `
+swagger: "2.0"
+paths:
+  /c:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/Shared"
+definitions:
+  Shared:
+    type: string
+`,
+	} {
+		var s *spec.Swagger
+		require.NoError(t, yaml.Unmarshal([]byte(yamlSpec), &s))
+		specs[id] = s
+	}
+	template := &spec.Swagger{SwaggerProps: spec.SwaggerProps{Swagger: "2.0"}}
+	opts := MergeOptions{}
+
+	a := NewAggregator(template, opts)
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, a.Replace(id, specs[id]))
+	}
+	expectMergedFromScratch(t, a, template, opts, specs)
+	assert.Contains(t, a.Spec().Definitions, "Shared_v2")
+	assert.Len(t, a.Spec().Definitions["Shared"].Extensions[gvkKey], 2)
+
+	// Removing the first source keeps the definition and parameter it
+	// shares with the second one, with the GVK of the second one only.
+	require.NoError(t, a.Remove("a"))
+	expectMergedFromScratch(t, a, template, opts, specs)
+	assert.Equal(t, []interface{}{map[string]interface{}{"group": "b", "kind": "Shared", "version": "v1"}}, a.Spec().Definitions["Shared"].Extensions[gvkKey])
+	assert.Contains(t, a.Spec().Parameters, "p")
+
+	// The third source no longer conflicts once the second one is removed.
+	require.NoError(t, a.Remove("b"))
+	expectMergedFromScratch(t, a, template, opts, specs)
+	assert.Equal(t, []string{"Shared"}, keys(a.Spec().Definitions))
+	assert.Empty(t, a.Spec().Parameters)
+}
+
+func TestAggregatorReplaceError(t *testing.T) {
+	var s1, s2 *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /test:
+    get:
+      responses:
+        200:
+          description: OK
+`), &s1))
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /test:
+    post:
+      responses:
+        200:
+          description: OK
+`), &s2))
+
+	a := NewAggregator(&spec.Swagger{}, MergeOptions{})
+	require.NoError(t, a.Replace("1", s1))
+	before := a.Spec()
+	assert.EqualError(t, a.Replace("2", s2), "failed to merge the spec of 2: unable to merge: duplicated path /test")
+	assert.Same(t, before, a.Spec())
+	assert.Equal(t, []string{"1"}, a.Sources())
+
+	// Replacing a source with itself doesn't conflict.
+	require.NoError(t, a.Replace("1", s1))
+	require.NoError(t, a.Replace("1", s2))
+	assert.NotNil(t, a.Spec().Paths.Paths["/test"].Post)
+}
+
+func TestAggregatorConflictDoesNotMutatePreviousState(t *testing.T) {
+	var s1, s2 *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/Shared"
+definitions:
+  Shared:
+    type: object
+`), &s1))
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /b:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/Shared"
+definitions:
+  Shared:
+    type: string
+`), &s2))
+
+	a := NewAggregator(&spec.Swagger{}, MergeOptions{})
+	require.NoError(t, a.Replace("1", s1))
+	before := a.current.Load()
+	require.NoError(t, a.Replace("2", s2))
+	assert.True(t, a.current.Load().sources[0].conflicting)
+	assert.False(t, before.sources[0].conflicting, "previous state mutated")
+
+	// The source can still be removed incrementally from the previous state.
+	ret := before.clone()
+	require.NoError(t, ret.remove(0))
+	assert.Empty(t, ret.merged.Paths.Paths)
+	assert.Empty(t, ret.merged.Definitions)
+	assert.Equal(t, []string{"Shared"}, keys(before.merged.Definitions))
+}
+
+func keys[V any](m map[string]V) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	return ret
+}
+
+func BenchmarkAggregatorReplaceWithKubeSpec(b *testing.B) {
+	specs, _ := loadTestData()
+	a := NewAggregator(&spec.Swagger{}, legacyMergeOptions(true, true, true))
+	for i, s := range specs {
+		if err := a.Replace(fmt.Sprint(i), s); err != nil {
+			b.Fatal(err)
+		}
+	}
+	id := fmt.Sprint(len(specs) - 1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := a.Replace(id, specs[len(specs)-1]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"fmt"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ConflictResolution is how merging a source spec into a destination spec
+// resolves a path, definition or parameter of the source with the same
+// name as a different one in the destination.
+type ConflictResolution int
+
+const (
+	// ConflictRename renames the definition or parameter of the source,
+	// along with the references to it. It can't resolve path conflicts.
+	ConflictRename ConflictResolution = iota
+	// ConflictPreferDest keeps the path, definition or parameter of the
+	// destination. The references of the source to a definition or
+	// parameter then point to the one of the destination, and the
+	// definitions only used by a path of the source are dropped with it.
+	ConflictPreferDest
+	// ConflictPreferSource replaces the path, definition or parameter of
+	// the destination with the one of the source. The references of the
+	// destination to a definition or parameter then point to the one of
+	// the source.
+	ConflictPreferSource
+	// ConflictFail fails the merge.
+	ConflictFail
+)
+
+var conflictResolutionNames = map[ConflictResolution]string{
+	ConflictRename:       "Rename",
+	ConflictPreferDest:   "PreferDest",
+	ConflictPreferSource: "PreferSource",
+	ConflictFail:         "Fail",
+}
+
+func (r ConflictResolution) String() string {
+	if name, found := conflictResolutionNames[r]; found {
+		return name
+	}
+	return fmt.Sprintf("ConflictResolution(%d)", int(r))
+}
+
+// MarshalText marshals the resolution as its name.
+func (r ConflictResolution) MarshalText() ([]byte, error) {
+	if _, found := conflictResolutionNames[r]; !found {
+		return nil, fmt.Errorf("invalid conflict resolution %d", int(r))
+	}
+	return []byte(r.String()), nil
+}
+
+// MergeOptions configures MergeSpecsWithOptions. The zero value merges
+// like MergeSpecs: definitions and parameters are renamed with a "_v<n>"
+// suffix, and path conflicts fail the merge. Unlike MergeSpecs, a failing
+// merge never mutates the destination.
+//
+// Conflicts are resolved in the order of their names, so that merging the
+// same specs in the same order always has the same outcome.
+type MergeOptions struct {
+	// PathConflict returns how to resolve a path of the source that is
+	// also in the destination, ConflictFail if nil. It can't return
+	// ConflictRename.
+	PathConflict func(path string, dest, source *spec.PathItem) ConflictResolution
+	// DefinitionConflict returns how to resolve a definition of the source
+	// that is different from the definition of the destination with the
+	// same name, ConflictRename if nil.
+	DefinitionConflict func(name string, dest, source *spec.Schema) ConflictResolution
+	// ParameterConflict returns how to resolve a parameter of the source
+	// that is different from the parameter of the destination with the
+	// same name, once the definitions it references are renamed,
+	// ConflictRename if nil.
+	ParameterConflict func(name string, dest, source *spec.Parameter) ConflictResolution
+	// Rename returns the n-th candidate name, starting from 2, of a
+	// definition or parameter renamed by ConflictRename. The first
+	// candidate naming an identical entry of the destination, or else used
+	// neither in the destination nor in the source, is picked. The default
+	// is name + "_v<n>".
+	Rename func(name string, n int) string
+	// DefinitionsEqual returns true if two definitions with the same name
+	// don't conflict. The x-kubernetes-group-version-kind extensions of
+	// equal definitions are merged. The default compares the definitions
+	// ignoring that extension.
+	DefinitionsEqual func(dest, source *spec.Schema) bool
+	// CheckReferences fails the merge with a *ReferenceError, without
+	// mutating the destination, if a reference of the merged spec is
+	// dangling or references the wrong kind of component. This walks the
+	// whole merged spec on every merge.
+	CheckReferences bool
+}
+
+// legacyMergeOptions returns the options of the MergeSpecs variants.
+func legacyMergeOptions(renameModelConflicts, renameParameterConflicts, ignorePathConflicts bool) MergeOptions {
+	opts := MergeOptions{}
+	if !renameModelConflicts {
+		opts.DefinitionConflict = func(string, *spec.Schema, *spec.Schema) ConflictResolution { return ConflictFail }
+	}
+	if !renameParameterConflicts {
+		opts.ParameterConflict = func(string, *spec.Parameter, *spec.Parameter) ConflictResolution { return ConflictFail }
+	}
+	if ignorePathConflicts {
+		opts.PathConflict = func(string, *spec.PathItem, *spec.PathItem) ConflictResolution { return ConflictPreferDest }
+	}
+	return opts
+}
+
+func defaultRename(name string, n int) string {
+	return fmt.Sprintf("%s_v%d", name, n)
+}
@@ -0,0 +1,404 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
+)
+
+const optionsDestSpec = `
+swagger: "2.0"
+paths:
+  /a:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/A"
+  /shared:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/Shared"
+  /other:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/Other"
+definitions:
+  A:
+    type: object
+    description: "A of dest"
+  Shared:
+    type: object
+    description: "Shared of dest"
+  Other:
+    type: object
+    description: "Other of dest"
+`
+
+const optionsSourceSpec = `
+swagger: "2.0"
+paths:
+  /b:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/A"
+        201:
+          schema:
+            $ref: "#/definitions/Shared"
+        202:
+          schema:
+            $ref: "#/definitions/Other"
+  /shared:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/SharedPath"
+  /other:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/OtherPath"
+definitions:
+  A:
+    type: object
+    description: "A of source"
+  Shared:
+    type: object
+    description: "Shared of source"
+  Other:
+    type: object
+    description: "Other of source"
+  SharedPath:
+    type: string
+  OtherPath:
+    type: string
+`
+
+func TestMergeSpecsWithOptions(t *testing.T) {
+	var dest, source *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(optionsDestSpec), &dest))
+	require.NoError(t, yaml.Unmarshal([]byte(optionsSourceSpec), &source))
+	origSource, err := cloneSpec(source)
+	require.NoError(t, err)
+
+	opts := MergeOptions{
+		PathConflict: func(path string, dest, source *spec.PathItem) ConflictResolution {
+			if path == "/shared" {
+				return ConflictPreferSource
+			}
+			return ConflictPreferDest
+		},
+		DefinitionConflict: func(name string, dest, source *spec.Schema) ConflictResolution {
+			switch name {
+			case "Shared":
+				return ConflictPreferSource
+			case "Other":
+				return ConflictPreferDest
+			}
+			return ConflictRename
+		},
+		Rename: func(name string, n int) string {
+			return fmt.Sprintf("%s%d", name, n)
+		},
+	}
+
+	report, err := DryRunMergeSpecsWithOptions(dest, source, opts)
+	require.NoError(t, err)
+	assert.Equal(t, &MergeReport{
+		PathConflicts: []string{"/other", "/shared"},
+		DefinitionConflicts: []Conflict{
+			{Name: "A", Resolution: ConflictRename, RenamedTo: "A2", Differences: []Difference{
+				{Pointer: "/description", Destination: "A of dest", Source: "A of source"},
+			}},
+			{Name: "Other", Resolution: ConflictPreferDest, Differences: []Difference{
+				{Pointer: "/description", Destination: "Other of dest", Source: "Other of source"},
+			}},
+			{Name: "Shared", Resolution: ConflictPreferSource, Differences: []Difference{
+				{Pointer: "/description", Destination: "Shared of dest", Source: "Shared of source"},
+			}},
+		},
+	}, report)
+	data, err := json.Marshal(report.DefinitionConflicts[1])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"resolution":"PreferDest"`)
+
+	require.NoError(t, MergeSpecsWithOptions(dest, source, opts))
+	assert.Equal(t, origSource, source, "source mutated")
+
+	var expected *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/A"
+  /b:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/A2"
+        201:
+          schema:
+            $ref: "#/definitions/Shared"
+        202:
+          schema:
+            $ref: "#/definitions/Other"
+  /shared:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/SharedPath"
+  /other:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/Other"
+definitions:
+  A:
+    type: object
+    description: "A of dest"
+  A2:
+    type: object
+    description: "A of source"
+  Shared:
+    type: object
+    description: "Shared of source"
+  Other:
+    type: object
+    description: "Other of dest"
+  SharedPath:
+    type: string
+`), &expected))
+	assert.Equal(t, expected, dest)
+}
+
+func TestMergeSpecsWithOptionsDefinitionsEqual(t *testing.T) {
+	var dest, source *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(optionsDestSpec), &dest))
+	require.NoError(t, yaml.Unmarshal([]byte(optionsSourceSpec), &source))
+
+	// Ignoring descriptions, the definitions are the same.
+	opts := MergeOptions{
+		PathConflict: func(string, *spec.PathItem, *spec.PathItem) ConflictResolution { return ConflictPreferDest },
+		DefinitionsEqual: func(dest, source *spec.Schema) bool {
+			d, s := *dest, *source
+			d.Description, s.Description = "", ""
+			return deepEqualDefinitionsModuloGVKs(&d, &s)
+		},
+	}
+	report, err := DryRunMergeSpecsWithOptions(dest, source, opts)
+	require.NoError(t, err)
+	assert.Empty(t, report.DefinitionConflicts)
+
+	require.NoError(t, MergeSpecsWithOptions(dest, source, opts))
+	assert.Equal(t, "A of dest", dest.Definitions["A"].Description)
+	assert.Equal(t, spec.MustCreateRef("#/definitions/A"), dest.Paths.Paths["/b"].Get.Responses.StatusCodeResponses[200].Schema.Ref)
+}
+
+func TestMergeSpecsWithOptionsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		opts          MergeOptions
+		expectedError string
+	}{
+		{
+			name:          "default fails on path conflicts",
+			expectedError: "unable to merge: duplicated path /other",
+		},
+		{
+			name: "paths can't be renamed",
+			opts: MergeOptions{
+				PathConflict: func(string, *spec.PathItem, *spec.PathItem) ConflictResolution { return ConflictRename },
+			},
+			expectedError: "unable to merge: path /other can't be renamed",
+		},
+		{
+			name: "fail on a definition",
+			opts: MergeOptions{
+				PathConflict: func(string, *spec.PathItem, *spec.PathItem) ConflictResolution { return ConflictPreferDest },
+				DefinitionConflict: func(name string, dest, source *spec.Schema) ConflictResolution {
+					if name == "Other" {
+						return ConflictFail
+					}
+					return ConflictRename
+				},
+			},
+			expectedError: "model name conflict in merging OpenAPI spec: Other",
+		},
+		{
+			name: "invalid resolution",
+			opts: MergeOptions{
+				PathConflict:       func(string, *spec.PathItem, *spec.PathItem) ConflictResolution { return ConflictPreferDest },
+				DefinitionConflict: func(string, *spec.Schema, *spec.Schema) ConflictResolution { return ConflictResolution(42) },
+			},
+			expectedError: "definition: invalid conflict resolution ConflictResolution(42) of A",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var dest, source *spec.Swagger
+			require.NoError(t, yaml.Unmarshal([]byte(optionsDestSpec), &dest))
+			require.NoError(t, yaml.Unmarshal([]byte(optionsSourceSpec), &source))
+			origDest, err := cloneSpec(dest)
+			require.NoError(t, err)
+
+			assert.EqualError(t, MergeSpecsWithOptions(dest, source, tc.opts), tc.expectedError)
+			assert.Equal(t, origDest, dest, "dest mutated on error")
+		})
+	}
+}
+
+func TestMergeSpecsWithOptionsAllPathsPreferDest(t *testing.T) {
+	var dest, source *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(optionsDestSpec), &dest))
+	require.NoError(t, yaml.Unmarshal([]byte(optionsSourceSpec), &source))
+	delete(source.Paths.Paths, "/b")
+	origDest, err := cloneSpec(dest)
+	require.NoError(t, err)
+
+	require.NoError(t, MergeSpecsIgnorePathConflictRenamingDefinitionsAndParameters(dest, source))
+	assert.Equal(t, origDest, dest)
+}
+
+// The MergeSpecs variants fail on path conflicts only after copying the
+// definitions and parameters of the source, including on the conflicting
+// sub-paths of the paths they keep.
+func TestMergeSpecsLegacyPathConflicts(t *testing.T) {
+	const destSpec = `
+swagger: "2.0"
+paths:
+  /a:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/A"
+  /b/c:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/A"
+definitions:
+  A:
+    type: object
+    description: "A of dest"
+`
+	const sourceSpec = `
+swagger: "2.0"
+paths:
+  /a:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/A"
+  /b:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/A"
+  /b/c:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/A"
+definitions:
+  A:
+    type: object
+    description: "A of source"
+`
+	for _, tc := range []struct {
+		name                string
+		merge               func(dest, source *spec.Swagger) error
+		deleteSourcePaths   []string
+		expectedError       string
+		expectedDefinitions []string
+	}{
+		{
+			name:                "MergeSpecs",
+			merge:               MergeSpecs,
+			deleteSourcePaths:   []string{"/b", "/b/c"},
+			expectedError:       "unable to merge: duplicated path /a",
+			expectedDefinitions: []string{"A", "A_v2"},
+		},
+		{
+			name:                "MergeSpecsIgnorePathConflictRenamingDefinitionsAndParameters",
+			merge:               MergeSpecsIgnorePathConflictRenamingDefinitionsAndParameters,
+			expectedError:       "unable to merge: duplicated path /b/c",
+			expectedDefinitions: []string{"A", "A_v2"},
+		},
+		{
+			name:                "MergeSpecsIgnorePathConflictDeprecated",
+			merge:               MergeSpecsIgnorePathConflictDeprecated,
+			expectedError:       "unable to merge: duplicated path /b/c",
+			expectedDefinitions: []string{"A", "A_v2"},
+		},
+		{
+			name: "MergeSpecsWithOptions",
+			merge: func(dest, source *spec.Swagger) error {
+				return MergeSpecsWithOptions(dest, source, MergeOptions{
+					PathConflict: func(string, *spec.PathItem, *spec.PathItem) ConflictResolution { return ConflictPreferDest },
+				})
+			},
+			expectedDefinitions: []string{"A", "A_v2"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var dest, source *spec.Swagger
+			require.NoError(t, yaml.Unmarshal([]byte(destSpec), &dest))
+			require.NoError(t, yaml.Unmarshal([]byte(sourceSpec), &source))
+			for _, path := range tc.deleteSourcePaths {
+				delete(source.Paths.Paths, path)
+			}
+
+			err := tc.merge(dest, source)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedDefinitions, slices.Sorted(maps.Keys(dest.Definitions)))
+			assert.Equal(t, "A of source", dest.Definitions["A_v2"].Description)
+		})
+	}
+}
@@ -0,0 +1,467 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/schemamutation"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// PruneOptions configures PruneSpec and PruneSpecV3.
+type PruneOptions struct {
+	// OperationIDs, Tags, Methods and GroupVersionKinds select the
+	// operations to keep: an operation is kept if it matches an element of
+	// each of them that is not empty, and Predicate if not nil. All the
+	// operations are kept if none of them is set.
+	OperationIDs []string
+	Tags         []string
+	// Methods are HTTP methods, case-insensitive.
+	Methods []string
+	// GroupVersionKinds match the x-kubernetes-group-version-kind
+	// extension of the operations. An empty version or kind matches any
+	// version or kind, an empty group only matches the core group.
+	GroupVersionKinds []GroupVersionKind
+	Predicate         func(op *OperationInfo) bool
+
+	// Strip configures what is stripped from the kept paths, definitions
+	// and components.
+	Strip StripOptions
+}
+
+// GroupVersionKind is the value of the x-kubernetes-group-version-kind
+// extension of a Kubernetes operation.
+type GroupVersionKind struct {
+	Group   string
+	Version string
+	Kind    string
+}
+
+// OperationInfo is an operation of a spec, as seen by PruneOptions.Predicate.
+type OperationInfo struct {
+	Path string
+	// Method is the upper-case HTTP method, e.g. "GET".
+	Method      string
+	OperationID string
+	Tags        []string
+	// GroupVersionKind is nil if the operation has no
+	// x-kubernetes-group-version-kind extension.
+	GroupVersionKind *GroupVersionKind
+	Extensions       spec.Extensions
+}
+
+// StripOptions configures what is removed from the objects of a spec by
+// PruneSpec, PruneSpecV3 and StripSchema. Response descriptions are always
+// kept, they are required.
+type StripOptions struct {
+	Descriptions bool
+	Examples     bool
+	Defaults     bool
+	// Extensions strips the vendor extensions, except for KeepExtensions,
+	// e.g. x-kubernetes-group-version-kind.
+	Extensions     bool
+	KeepExtensions []string
+}
+
+func (o StripOptions) stripping() bool {
+	return o.Descriptions || o.Examples || o.Defaults || o.Extensions
+}
+
+func (o *PruneOptions) selecting() bool {
+	return len(o.OperationIDs) > 0 || len(o.Tags) > 0 || len(o.Methods) > 0 || len(o.GroupVersionKinds) > 0 || o.Predicate != nil
+}
+
+// selects returns true if the operation is kept.
+func (o *PruneOptions) selects(op *OperationInfo) bool {
+	if len(o.OperationIDs) > 0 && !slices.Contains(o.OperationIDs, op.OperationID) {
+		return false
+	}
+	if len(o.Tags) > 0 && !slices.ContainsFunc(op.Tags, func(tag string) bool { return slices.Contains(o.Tags, tag) }) {
+		return false
+	}
+	if len(o.Methods) > 0 && !slices.ContainsFunc(o.Methods, func(method string) bool { return strings.EqualFold(method, op.Method) }) {
+		return false
+	}
+	if len(o.GroupVersionKinds) > 0 && !slices.ContainsFunc(o.GroupVersionKinds, op.GroupVersionKind.matches) {
+		return false
+	}
+	return o.Predicate == nil || o.Predicate(op)
+}
+
+// matches returns true if gvk matches the selector, see
+// PruneOptions.GroupVersionKinds.
+func (gvk *GroupVersionKind) matches(selector GroupVersionKind) bool {
+	return gvk != nil && gvk.Group == selector.Group &&
+		(selector.Version == "" || gvk.Version == selector.Version) &&
+		(selector.Kind == "" || gvk.Kind == selector.Kind)
+}
+
+func newOperationInfo(path, method, id string, tags []string, extensions spec.Extensions) *OperationInfo {
+	op := &OperationInfo{Path: path, Method: method, OperationID: id, Tags: tags, Extensions: extensions}
+	switch gvk := extensions[gvkKey].(type) {
+	case map[string]interface{}:
+		group, _ := gvk["group"].(string)
+		version, _ := gvk["version"].(string)
+		kind, _ := gvk["kind"].(string)
+		op.GroupVersionKind = &GroupVersionKind{Group: group, Version: version, Kind: kind}
+	case map[string]string:
+		op.GroupVersionKind = &GroupVersionKind{Group: gvk["group"], Version: gvk["version"], Kind: gvk["kind"]}
+	}
+	return op
+}
+
+// PruneSpec returns the spec with only the selected operations, and the
+// definitions, parameters, responses and tags they use. Paths without
+// selected operations are removed. The remaining objects are stripped as
+// configured.
+//
+// The input is not modified. The maps of paths, definitions, parameters
+// and responses and the list of tags are new, and the objects stripped of
+// a value are copies, but the operations, schemas and other objects left
+// as they are are shared with the input.
+func PruneSpec(sp *spec.Swagger, opts PruneOptions) *spec.Swagger {
+	ret := *sp
+	usedTags := map[string]bool{}
+	if sp.Paths != nil {
+		ret.Paths = &spec.Paths{VendorExtensible: sp.Paths.VendorExtensible, Paths: map[string]spec.PathItem{}}
+		for path, pathItem := range sp.Paths.Paths {
+			kept := !opts.selecting()
+			for _, op := range []struct {
+				method string
+				op     **spec.Operation
+			}{
+				{http.MethodGet, &pathItem.Get},
+				{http.MethodPut, &pathItem.Put},
+				{http.MethodPost, &pathItem.Post},
+				{http.MethodDelete, &pathItem.Delete},
+				{http.MethodOptions, &pathItem.Options},
+				{http.MethodHead, &pathItem.Head},
+				{http.MethodPatch, &pathItem.Patch},
+			} {
+				if *op.op == nil {
+					continue
+				}
+				if !opts.selects(newOperationInfo(path, op.method, (*op.op).ID, (*op.op).Tags, (*op.op).Extensions)) {
+					*op.op = nil
+					continue
+				}
+				kept = true
+				for _, tag := range (*op.op).Tags {
+					usedTags[tag] = true
+				}
+			}
+			if !kept {
+				continue
+			}
+			if extensions, changed := opts.Strip.extensions(pathItem.Extensions); changed {
+				pathItem.Extensions = extensions
+			}
+			ret.Paths.Paths[path] = pathItem
+		}
+	}
+
+	usedRefs := map[string]bool{}
+	walkReferences(func(ref *spec.Ref) {
+		if refStr := ref.String(); refStr != "" {
+			usedRefs[refStr] = true
+		}
+	}, &ret, true)
+	ret.Definitions = keepUsedComponents(sp.Definitions, definitionPrefix, usedRefs)
+	ret.Parameters = keepUsedComponents(sp.Parameters, parameterPrefix, usedRefs)
+	ret.Responses = keepUsedComponents(sp.Responses, responsePrefix, usedRefs)
+	ret.Tags = nil
+	for _, tag := range sp.Tags {
+		if usedTags[tag.Name] {
+			if opts.Strip.Descriptions {
+				tag.Description = ""
+			}
+			ret.Tags = append(ret.Tags, tag)
+		}
+	}
+
+	if !opts.Strip.stripping() {
+		return &ret
+	}
+	return opts.Strip.walker().WalkRoot(&ret)
+}
+
+// PruneSpecV3 is the same as PruneSpec for OpenAPI v3 specs. Security
+// schemes and links are kept as they are, and shared with the input like
+// the other objects PruneSpec doesn't need to change.
+func PruneSpecV3(sp *spec3.OpenAPI, opts PruneOptions) *spec3.OpenAPI {
+	selected := *sp
+	var keepPaths []string
+	if sp.Paths != nil {
+		selected.Paths = &spec3.Paths{VendorExtensible: sp.Paths.VendorExtensible, Paths: map[string]*spec3.Path{}}
+		for path, pathItem := range sp.Paths.Paths {
+			if pathItem == nil {
+				continue
+			}
+			p := *pathItem
+			kept := !opts.selecting()
+			for _, op := range []struct {
+				method string
+				op     **spec3.Operation
+			}{
+				{http.MethodGet, &p.Get},
+				{http.MethodPut, &p.Put},
+				{http.MethodPost, &p.Post},
+				{http.MethodDelete, &p.Delete},
+				{http.MethodOptions, &p.Options},
+				{http.MethodHead, &p.Head},
+				{http.MethodPatch, &p.Patch},
+				{http.MethodTrace, &p.Trace},
+			} {
+				if *op.op == nil {
+					continue
+				}
+				if !opts.selects(newOperationInfo(path, op.method, (*op.op).OperationId, (*op.op).Tags, (*op.op).Extensions)) {
+					*op.op = nil
+					continue
+				}
+				kept = true
+			}
+			if !kept {
+				continue
+			}
+			if opts.Strip.Descriptions {
+				p.Description = ""
+			}
+			if extensions, changed := opts.Strip.extensions(p.Extensions); changed {
+				p.Extensions = extensions
+			}
+			selected.Paths.Paths[path] = &p
+			keepPaths = append(keepPaths, path)
+		}
+	}
+
+	ret := SelectSpecV3PathsAndSchemas(&selected, keepPaths, nil)
+	if !opts.Strip.stripping() {
+		return ret
+	}
+	ret = opts.Strip.walker().WalkRootV3(ret)
+	if opts.Strip.Examples && ret.Components != nil && ret.Components.Examples != nil {
+		// The examples aren't referenced anymore.
+		components := *ret.Components
+		components.Examples = nil
+		ret.Components = &components
+	}
+	return ret
+}
+
+// StripSchema removes the values configured by opts from the schema and
+// the schemas it contains. The input is returned if there is nothing to
+// strip. Otherwise only the schemas on the way to a stripped value are
+// copied, the other subschemas are shared with the input.
+func StripSchema(schema *spec.Schema, opts StripOptions) *spec.Schema {
+	return opts.walker().WalkSchema(schema)
+}
+
+// StripDefinitions is the same as StripSchema for all the definitions. The
+// map is copied only if a definition is stripped.
+func StripDefinitions(definitions spec.Definitions, opts StripOptions) spec.Definitions {
+	walker := opts.walker()
+	definitionsCloned := false
+	for k, v := range definitions {
+		if s := walker.WalkSchema(&v); s != &v {
+			if !definitionsCloned {
+				definitionsCloned = true
+				orig := definitions
+				definitions = make(spec.Definitions, len(orig))
+				for k2, v2 := range orig {
+					definitions[k2] = v2
+				}
+			}
+			definitions[k] = *s
+		}
+	}
+	return definitions
+}
+
+// walker returns a walker stripping the objects it walks.
+func (o StripOptions) walker() *schemamutation.Walker {
+	w := &schemamutation.Walker{
+		RefCallback:         schemamutation.RefCallbackNoop,
+		OperationCallback:   stripper(o.operation),
+		ParameterCallback:   stripper(o.parameter),
+		ResponseCallback:    stripper(o.response),
+		OperationV3Callback: stripper(o.operationV3),
+		ParameterV3Callback: stripper(o.parameterV3),
+		RequestBodyCallback: stripper(o.requestBody),
+		ResponseV3Callback:  stripper(o.responseV3),
+		MediaTypeCallback:   stripper(o.mediaType),
+		HeaderCallback:      stripper(o.header),
+	}
+	w.SchemaCallback = stripper(func(s *spec.Schema) bool {
+		changed := o.schema(s)
+		// The walker doesn't walk into dependencies.
+		dependenciesCloned := false
+		for k, v := range s.Dependencies {
+			if schema := w.WalkSchema(v.Schema); schema != v.Schema {
+				if !dependenciesCloned {
+					dependenciesCloned = true
+					orig := s.Dependencies
+					s.Dependencies = make(spec.Dependencies, len(orig))
+					for k2, v2 := range orig {
+						s.Dependencies[k2] = v2
+					}
+				}
+				v.Schema = schema
+				s.Dependencies[k] = v
+			}
+		}
+		return changed || dependenciesCloned
+	})
+	return w
+}
+
+// stripper returns a walker callback calling strip on a copy of its
+// input, and returning the input if strip didn't change anything.
+func stripper[V any](strip func(*V) bool) func(*V) *V {
+	return func(v *V) *V {
+		ret := *v
+		if !strip(&ret) {
+			return v
+		}
+		return &ret
+	}
+}
+
+// The following functions strip the values of their argument, and return
+// true if anything was stripped.
+
+func (o StripOptions) schema(s *spec.Schema) bool {
+	changed := o.description(&s.Description)
+	changed = o.example(&s.Example) || changed
+	changed = o.defaultValue(&s.Default) || changed
+	return o.stripExtensions(&s.Extensions) || changed
+}
+
+func (o StripOptions) operation(op *spec.Operation) bool {
+	changed := o.description(&op.Description)
+	return o.stripExtensions(&op.Extensions) || changed
+}
+
+func (o StripOptions) parameter(param *spec.Parameter) bool {
+	changed := o.description(&param.Description)
+	changed = o.example(&param.Example) || changed
+	changed = o.defaultValue(&param.Default) || changed
+	return o.stripExtensions(&param.Extensions) || changed
+}
+
+func (o StripOptions) response(resp *spec.Response) bool {
+	changed := false
+	if o.Examples && resp.Examples != nil {
+		resp.Examples = nil
+		changed = true
+	}
+	return o.stripExtensions(&resp.Extensions) || changed
+}
+
+func (o StripOptions) operationV3(op *spec3.Operation) bool {
+	changed := o.description(&op.Description)
+	return o.stripExtensions(&op.Extensions) || changed
+}
+
+func (o StripOptions) parameterV3(param *spec3.Parameter) bool {
+	changed := o.description(&param.Description)
+	changed = o.example(&param.Example) || changed
+	changed = o.examples(&param.Examples) || changed
+	return o.stripExtensions(&param.Extensions) || changed
+}
+
+func (o StripOptions) requestBody(body *spec3.RequestBody) bool {
+	changed := o.description(&body.Description)
+	return o.stripExtensions(&body.Extensions) || changed
+}
+
+func (o StripOptions) responseV3(resp *spec3.Response) bool {
+	return o.stripExtensions(&resp.Extensions)
+}
+
+func (o StripOptions) mediaType(mediaType *spec3.MediaType) bool {
+	changed := o.example(&mediaType.Example)
+	changed = o.examples(&mediaType.Examples) || changed
+	return o.stripExtensions(&mediaType.Extensions) || changed
+}
+
+func (o StripOptions) header(header *spec3.Header) bool {
+	changed := o.description(&header.Description)
+	changed = o.example(&header.Example) || changed
+	changed = o.examples(&header.Examples) || changed
+	return o.stripExtensions(&header.Extensions) || changed
+}
+
+func (o StripOptions) description(description *string) bool {
+	if !o.Descriptions || *description == "" {
+		return false
+	}
+	*description = ""
+	return true
+}
+
+func (o StripOptions) example(example *interface{}) bool {
+	if !o.Examples || *example == nil {
+		return false
+	}
+	*example = nil
+	return true
+}
+
+func (o StripOptions) examples(examples *map[string]*spec3.Example) bool {
+	if !o.Examples || *examples == nil {
+		return false
+	}
+	*examples = nil
+	return true
+}
+
+func (o StripOptions) defaultValue(value *interface{}) bool {
+	if !o.Defaults || *value == nil {
+		return false
+	}
+	*value = nil
+	return true
+}
+
+func (o StripOptions) stripExtensions(extensions *spec.Extensions) bool {
+	ret, changed := o.extensions(*extensions)
+	*extensions = ret
+	return changed
+}
+
+// extensions returns the extensions without the stripped ones, and true
+// if any was stripped.
+func (o StripOptions) extensions(extensions spec.Extensions) (spec.Extensions, bool) {
+	if !o.Extensions || len(extensions) == 0 {
+		return extensions, false
+	}
+	var ret spec.Extensions
+	for k, v := range extensions {
+		if slices.ContainsFunc(o.KeepExtensions, func(keep string) bool { return strings.EqualFold(keep, k) }) {
+			if ret == nil {
+				ret = spec.Extensions{}
+			}
+			ret[k] = v
+		}
+	}
+	return ret, len(ret) != len(extensions)
+}
@@ -0,0 +1,379 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
+)
+
+const pruneSpec = `
+swagger: "2.0"
+tags:
+- name: core
+  description: The core group
+- name: apps
+  description: The apps group
+paths:
+  /api/v1/pods:
+    get:
+      operationId: listPods
+      tags: [core]
+      description: list pods
+      x-kubernetes-group-version-kind:
+        group: ""
+        version: v1
+        kind: Pod
+      parameters:
+      - $ref: "#/parameters/limit"
+      responses:
+        200:
+          $ref: "#/responses/PodList"
+    post:
+      operationId: createPod
+      tags: [core]
+      x-kubernetes-group-version-kind:
+        group: ""
+        version: v1
+        kind: Pod
+      responses:
+        201:
+          description: Created
+          schema:
+            $ref: "#/definitions/Pod"
+  /apis/apps/v1/deployments:
+    get:
+      operationId: listDeployments
+      tags: [apps]
+      x-kubernetes-group-version-kind:
+        group: apps
+        version: v1
+        kind: Deployment
+      responses:
+        200:
+          description: OK
+          schema:
+            $ref: "#/definitions/Deployment"
+parameters:
+  limit:
+    name: limit
+    in: query
+    type: integer
+    description: the limit
+    default: 10
+responses:
+  PodList:
+    description: OK
+    schema:
+      $ref: "#/definitions/PodList"
+definitions:
+  PodList:
+    type: object
+    properties:
+      items:
+        type: array
+        items:
+          $ref: "#/definitions/Pod"
+  Pod:
+    type: object
+    description: A pod
+    x-kubernetes-group-version-kind:
+    - group: ""
+      version: v1
+      kind: Pod
+    x-custom: value
+    properties:
+      name:
+        type: string
+        description: The name
+        default: foo
+        example: bar
+  Deployment:
+    type: object
+    dependencies:
+      name:
+        description: depends on the name
+        properties:
+          name:
+            default: foo
+`
+
+func TestPruneSpec(t *testing.T) {
+	var sp *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(pruneSpec), &sp))
+	orig, err := cloneSpec(sp)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name                string
+		opts                PruneOptions
+		expectedOperations  []string
+		expectedDefinitions []string
+		expectedParameters  []string
+		expectedResponses   []string
+		expectedTags        []string
+	}{
+		{
+			name:                "everything",
+			expectedOperations:  []string{"createPod", "listDeployments", "listPods"},
+			expectedDefinitions: []string{"Deployment", "Pod", "PodList"},
+			expectedParameters:  []string{"limit"},
+			expectedResponses:   []string{"PodList"},
+			expectedTags:        []string{"core", "apps"},
+		},
+		{
+			name:                "operation ID",
+			opts:                PruneOptions{OperationIDs: []string{"listPods"}},
+			expectedOperations:  []string{"listPods"},
+			expectedDefinitions: []string{"Pod", "PodList"},
+			expectedParameters:  []string{"limit"},
+			expectedResponses:   []string{"PodList"},
+			expectedTags:        []string{"core"},
+		},
+		{
+			name:                "tag",
+			opts:                PruneOptions{Tags: []string{"apps"}},
+			expectedOperations:  []string{"listDeployments"},
+			expectedDefinitions: []string{"Deployment"},
+			expectedTags:        []string{"apps"},
+		},
+		{
+			name:                "method",
+			opts:                PruneOptions{Methods: []string{"post"}},
+			expectedOperations:  []string{"createPod"},
+			expectedDefinitions: []string{"Pod"},
+			expectedTags:        []string{"core"},
+		},
+		{
+			name:                "core group",
+			opts:                PruneOptions{GroupVersionKinds: []GroupVersionKind{{Group: ""}}, Methods: []string{"GET"}},
+			expectedOperations:  []string{"listPods"},
+			expectedDefinitions: []string{"Pod", "PodList"},
+			expectedParameters:  []string{"limit"},
+			expectedResponses:   []string{"PodList"},
+			expectedTags:        []string{"core"},
+		},
+		{
+			name: "predicate",
+			opts: PruneOptions{Predicate: func(op *OperationInfo) bool {
+				return op.GroupVersionKind != nil && op.GroupVersionKind.Kind == "Deployment" && op.Path == "/apis/apps/v1/deployments"
+			}},
+			expectedOperations:  []string{"listDeployments"},
+			expectedDefinitions: []string{"Deployment"},
+			expectedTags:        []string{"apps"},
+		},
+		{
+			name: "nothing",
+			opts: PruneOptions{OperationIDs: []string{"missing"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pruned := PruneSpec(sp, tc.opts)
+			var operations []string
+			for _, pathItem := range pruned.Paths.Paths {
+				for _, op := range []*spec.Operation{pathItem.Get, pathItem.Put, pathItem.Post, pathItem.Delete, pathItem.Options, pathItem.Head, pathItem.Patch} {
+					if op != nil {
+						operations = append(operations, op.ID)
+					}
+				}
+			}
+			sort.Strings(operations)
+			assert.Equal(t, tc.expectedOperations, operations)
+			assert.ElementsMatch(t, tc.expectedDefinitions, keys(pruned.Definitions))
+			assert.ElementsMatch(t, tc.expectedParameters, keys(pruned.Parameters))
+			assert.ElementsMatch(t, tc.expectedResponses, keys(pruned.Responses))
+			var tags []string
+			for _, tag := range pruned.Tags {
+				tags = append(tags, tag.Name)
+			}
+			assert.Equal(t, tc.expectedTags, tags)
+		})
+	}
+	assert.Equal(t, DebugSpec{orig}, DebugSpec{sp}, "input mutated")
+}
+
+// Unlike PruneSpec, FilterSpecByPaths doesn't follow the references into
+// responses, so it keeps all the responses of the spec and the definitions
+// only they use.
+func TestPruneSpecFollowsResponseReferences(t *testing.T) {
+	var sp *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /test:
+    get:
+      responses:
+        200:
+          $ref: "#/responses/Ok"
+responses:
+  Ok:
+    description: ok
+    schema:
+      $ref: "#/definitions/Status"
+  Unused:
+    description: unused
+definitions:
+  Status:
+    type: object
+`), &sp))
+
+	pruned := PruneSpec(sp, PruneOptions{})
+	assert.Equal(t, []string{"Ok"}, keys(pruned.Responses))
+	assert.Equal(t, []string{"Status"}, keys(pruned.Definitions))
+
+	filtered := FilterSpecByPathsWithoutSideEffects(sp, []string{"/test"})
+	assert.ElementsMatch(t, []string{"Ok", "Unused"}, keys(filtered.Responses))
+	assert.Equal(t, []string{"Status"}, keys(filtered.Definitions))
+	assert.Empty(t, usedDefinitionForSpec(sp))
+}
+
+func TestPruneSpecStrip(t *testing.T) {
+	var sp *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(pruneSpec), &sp))
+	orig, err := cloneSpec(sp)
+	require.NoError(t, err)
+
+	pruned := PruneSpec(sp, PruneOptions{Strip: StripOptions{
+		Descriptions:   true,
+		Examples:       true,
+		Defaults:       true,
+		Extensions:     true,
+		KeepExtensions: []string{"X-Kubernetes-Group-Version-Kind"},
+	}})
+	assert.Equal(t, DebugSpec{orig}, DebugSpec{sp}, "input mutated")
+
+	pod := pruned.Definitions["Pod"]
+	assert.Empty(t, pod.Description)
+	assert.Equal(t, spec.Extensions{gvkKey: orig.Definitions["Pod"].Extensions[gvkKey]}, pod.Extensions)
+	assert.Equal(t, spec.StringOrArray{"string"}, pod.Properties["name"].Type)
+	assert.Empty(t, pod.Properties["name"].Description)
+	assert.Nil(t, pod.Properties["name"].Default)
+	assert.Nil(t, pod.Properties["name"].Example)
+
+	dependency := pruned.Definitions["Deployment"].Dependencies["name"].Schema
+	assert.Empty(t, dependency.Description)
+	assert.Nil(t, dependency.Properties["name"].Default)
+
+	limit := pruned.Parameters["limit"]
+	assert.Empty(t, limit.Description)
+	assert.Nil(t, limit.Default)
+	assert.Equal(t, "integer", limit.Type)
+
+	list := pruned.Paths.Paths["/api/v1/pods"].Get
+	assert.Empty(t, list.Description)
+	assert.Contains(t, list.Extensions, gvkKey)
+	assert.Equal(t, "OK", pruned.Responses["PodList"].Description, "response descriptions are required")
+	for _, tag := range pruned.Tags {
+		assert.Empty(t, tag.Description)
+	}
+}
+
+func TestPruneSpecV3(t *testing.T) {
+	var sp *spec3.OpenAPI
+	require.NoError(t, yaml.Unmarshal([]byte(`
+openapi: 3.0.0
+info:
+  title: test
+  version: v1
+paths:
+  /api/v1/pods:
+    description: pods
+    get:
+      operationId: listPods
+      description: list pods
+      x-kubernetes-group-version-kind:
+        group: ""
+        version: v1
+        kind: Pod
+      parameters:
+      - name: limit
+        in: query
+        description: the limit
+        example: 10
+        schema:
+          type: integer
+          default: 10
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Pod"
+              examples:
+                pod:
+                  $ref: "#/components/examples/Pod"
+  /apis/apps/v1/deployments:
+    get:
+      operationId: listDeployments
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Deployment"
+components:
+  schemas:
+    Pod:
+      type: object
+      description: A pod
+      x-custom: value
+    Deployment:
+      type: object
+  examples:
+    Pod:
+      value:
+        name: foo
+`), &sp))
+	origJSON, err := json.Marshal(sp)
+	require.NoError(t, err)
+
+	pruned := PruneSpecV3(sp, PruneOptions{
+		GroupVersionKinds: []GroupVersionKind{{Kind: "Pod"}},
+		Strip:             StripOptions{Descriptions: true, Examples: true, Extensions: true, Defaults: true},
+	})
+	afterJSON, err := json.Marshal(sp)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(origJSON), string(afterJSON), "input mutated")
+
+	assert.ElementsMatch(t, []string{"/api/v1/pods"}, keys(pruned.Paths.Paths))
+	path := pruned.Paths.Paths["/api/v1/pods"]
+	assert.Empty(t, path.Description)
+	assert.Empty(t, path.Get.Description)
+	assert.Empty(t, path.Get.Extensions)
+	param := path.Get.Parameters[0]
+	assert.Empty(t, param.Description)
+	assert.Nil(t, param.Example)
+	assert.Nil(t, param.Schema.Default)
+	response := path.Get.Responses.StatusCodeResponses[200]
+	assert.Equal(t, "OK", response.Description)
+	assert.Nil(t, response.Content["application/json"].Examples)
+
+	assert.ElementsMatch(t, []string{"Pod"}, keys(pruned.Components.Schemas))
+	assert.Empty(t, pruned.Components.Schemas["Pod"].Description)
+	assert.Empty(t, pruned.Components.Schemas["Pod"].Extensions)
+	assert.Nil(t, pruned.Components.Examples)
+}
@@ -0,0 +1,542 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ReferenceProblem is a problem with the references of a spec.
+type ReferenceProblem int
+
+const (
+	// DanglingReference is a local reference to a component that doesn't
+	// exist.
+	DanglingReference ReferenceProblem = iota
+	// WrongComponentKind is a reference to a component of the wrong kind,
+	// e.g. a schema referencing a parameter.
+	WrongComponentKind
+	// UnreachableDefinition is a definition, or a schema component of an
+	// OpenAPI v3 spec, that no path uses, directly or transitively.
+	UnreachableDefinition
+	// ReferenceCycle is a reference closing a cycle of components
+	// referencing each other, e.g. a recursive schema.
+	ReferenceCycle
+)
+
+var referenceProblemNames = map[ReferenceProblem]string{
+	DanglingReference:     "DanglingReference",
+	WrongComponentKind:    "WrongComponentKind",
+	UnreachableDefinition: "UnreachableDefinition",
+	ReferenceCycle:        "ReferenceCycle",
+}
+
+func (p ReferenceProblem) String() string {
+	if name, found := referenceProblemNames[p]; found {
+		return name
+	}
+	return fmt.Sprintf("ReferenceProblem(%d)", int(p))
+}
+
+// MarshalText marshals the problem as its name.
+func (p ReferenceProblem) MarshalText() ([]byte, error) {
+	if _, found := referenceProblemNames[p]; !found {
+		return nil, fmt.Errorf("invalid reference problem %d", int(p))
+	}
+	return []byte(p.String()), nil
+}
+
+// Invalid returns true if the problem makes the spec invalid. Unreachable
+// definitions and cycles are valid, but often unintended.
+func (p ReferenceProblem) Invalid() bool {
+	return p == DanglingReference || p == WrongComponentKind
+}
+
+// ReferenceFinding is a problem found with the references of a spec.
+type ReferenceFinding struct {
+	Problem ReferenceProblem `json:"problem"`
+	// Pointer is the JSON pointer to the reference in the spec, e.g.
+	// "/definitions/Pod/properties/spec/$ref", or to the definition if it
+	// is unreachable.
+	Pointer string `json:"pointer"`
+	// Ref is the reference, empty for unreachable definitions.
+	Ref string `json:"ref,omitempty"`
+	// Cycle are the JSON pointers to the components of a reference cycle,
+	// starting and ending with the component referenced by Ref.
+	Cycle []string `json:"cycle,omitempty"`
+}
+
+func (f ReferenceFinding) String() string {
+	switch f.Problem {
+	case UnreachableDefinition:
+		return fmt.Sprintf("%s: unreachable definition", f.Pointer)
+	case ReferenceCycle:
+		return fmt.Sprintf("%s: reference cycle %s", f.Pointer, strings.Join(f.Cycle, " -> "))
+	case WrongComponentKind:
+		return fmt.Sprintf("%s: reference %s to the wrong kind of component", f.Pointer, f.Ref)
+	}
+	return fmt.Sprintf("%s: dangling reference %s", f.Pointer, f.Ref)
+}
+
+// ReferenceError is the error of a merge whose result has invalid
+// references, see MergeOptions.CheckReferences.
+type ReferenceError struct {
+	Findings []ReferenceFinding
+}
+
+func (e *ReferenceError) Error() string {
+	problems := make([]string, 0, len(e.Findings))
+	for _, f := range e.Findings {
+		problems = append(problems, f.String())
+	}
+	return fmt.Sprintf("invalid references in merged OpenAPI spec: %s", strings.Join(problems, ", "))
+}
+
+// CheckReferences returns the problems with the references of an OpenAPI v2
+// spec, sorted by pointer. References to other documents are not checked.
+func CheckReferences(sp *spec.Swagger) []ReferenceFinding {
+	c := newReferenceChecker(definitionPrefix, []string{definitionPrefix, parameterPrefix, responsePrefix})
+	if sp.Paths != nil {
+		for _, k := range slices.Sorted(maps.Keys(sp.Paths.Paths)) {
+			c.pathItem("/paths/"+escapePointerToken(k), sp.Paths.Paths[k])
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(sp.Definitions)) {
+		def := sp.Definitions[k]
+		c.component(definitionPrefix, k, true)
+		c.schema(c.current, &def)
+	}
+	for _, k := range slices.Sorted(maps.Keys(sp.Parameters)) {
+		param := sp.Parameters[k]
+		c.component(parameterPrefix, k, false)
+		c.parameter(c.current, &param)
+	}
+	for _, k := range slices.Sorted(maps.Keys(sp.Responses)) {
+		resp := sp.Responses[k]
+		c.component(responsePrefix, k, false)
+		c.response(c.current, &resp)
+	}
+	return c.findings()
+}
+
+// CheckReferencesV3 returns the problems with the references of an OpenAPI
+// v3 spec, sorted by pointer. References to other documents, and the
+// references of path items, are not checked.
+func CheckReferencesV3(sp *spec3.OpenAPI) []ReferenceFinding {
+	c := newReferenceChecker(schemaPrefixV3, []string{
+		schemaPrefixV3, parameterPrefixV3, responsePrefixV3, requestBodyPrefixV3,
+		headerPrefixV3, examplePrefixV3, linkPrefixV3, securitySchemePrefixV3,
+	})
+	if sp.Paths != nil {
+		for _, k := range slices.Sorted(maps.Keys(sp.Paths.Paths)) {
+			c.pathV3("/paths/"+escapePointerToken(k), sp.Paths.Paths[k])
+		}
+	}
+	if components := sp.Components; components != nil {
+		for _, k := range slices.Sorted(maps.Keys(components.Schemas)) {
+			c.component(schemaPrefixV3, k, true)
+			c.schema(c.current, components.Schemas[k])
+		}
+		for _, k := range slices.Sorted(maps.Keys(components.Parameters)) {
+			c.component(parameterPrefixV3, k, false)
+			c.parameterV3(c.current, components.Parameters[k])
+		}
+		for _, k := range slices.Sorted(maps.Keys(components.Responses)) {
+			c.component(responsePrefixV3, k, false)
+			c.responseV3(c.current, components.Responses[k])
+		}
+		for _, k := range slices.Sorted(maps.Keys(components.RequestBodies)) {
+			c.component(requestBodyPrefixV3, k, false)
+			c.requestBody(c.current, components.RequestBodies[k])
+		}
+		for _, k := range slices.Sorted(maps.Keys(components.Headers)) {
+			c.component(headerPrefixV3, k, false)
+			c.header(c.current, components.Headers[k])
+		}
+		for _, k := range slices.Sorted(maps.Keys(components.Examples)) {
+			c.component(examplePrefixV3, k, false)
+			if example := components.Examples[k]; example != nil {
+				c.ref(c.current, &example.Ref, examplePrefixV3)
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(components.Links)) {
+			c.component(linkPrefixV3, k, false)
+			if link := components.Links[k]; link != nil {
+				c.ref(c.current, &link.Ref, linkPrefixV3)
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(components.SecuritySchemes)) {
+			c.component(securitySchemePrefixV3, k, false)
+			if scheme := components.SecuritySchemes[k]; scheme != nil {
+				c.ref(c.current, &scheme.Ref, securitySchemePrefixV3)
+			}
+		}
+	}
+	return c.findings()
+}
+
+// referenceChecker collects the references of a spec, walked in a
+// deterministic order, along with the components they are in.
+type referenceChecker struct {
+	// schemaPrefix is the prefix of the references to schemas.
+	schemaPrefix string
+	// prefixes are the prefixes of the references to components.
+	prefixes []string
+	// components are the JSON pointers to the components, with the ones
+	// to schemas mapping to true.
+	components map[string]bool
+	// current is the JSON pointer to the component being walked, empty
+	// for the paths.
+	current string
+	// refs are the local references of each component, and of the paths.
+	refs map[string][]checkedRef
+	// problems are the dangling references and the references to the
+	// wrong kind of component.
+	problems []ReferenceFinding
+}
+
+// checkedRef is a local reference to a component.
+type checkedRef struct {
+	pointer, ref string
+	// expectedPrefix is the prefix of the references to the kind of
+	// component expected where the reference is.
+	expectedPrefix string
+}
+
+func newReferenceChecker(schemaPrefix string, prefixes []string) *referenceChecker {
+	return &referenceChecker{
+		schemaPrefix: schemaPrefix,
+		prefixes:     prefixes,
+		components:   map[string]bool{},
+		refs:         map[string][]checkedRef{},
+	}
+}
+
+// component starts walking the component with the given reference prefix
+// and name.
+func (c *referenceChecker) component(prefix, name string, schema bool) {
+	c.current = prefix[1:] + escapePointerToken(name)
+	c.components[c.current] = schema
+}
+
+func (c *referenceChecker) ref(pointer string, ref *spec.Ref, expectedPrefix string) {
+	refStr := ref.String()
+	if !strings.HasPrefix(refStr, "#") {
+		return
+	}
+	c.refs[c.current] = append(c.refs[c.current], checkedRef{pointer: pointer + "/$ref", ref: refStr, expectedPrefix: expectedPrefix})
+}
+
+// findings resolves the collected references, and returns the problems.
+func (c *referenceChecker) findings() []ReferenceFinding {
+	for _, refs := range c.refs {
+		for _, r := range refs {
+			prefix := ""
+			for _, p := range c.prefixes {
+				if strings.HasPrefix(r.ref, p) {
+					prefix = p
+					break
+				}
+			}
+			if _, found := c.components[r.ref[1:]]; !found || prefix == "" {
+				c.problems = append(c.problems, ReferenceFinding{Problem: DanglingReference, Pointer: r.pointer, Ref: r.ref})
+			} else if prefix != r.expectedPrefix {
+				c.problems = append(c.problems, ReferenceFinding{Problem: WrongComponentKind, Pointer: r.pointer, Ref: r.ref})
+			}
+		}
+	}
+
+	// The components reachable from the paths.
+	reachable := map[string]bool{}
+	queue := []string{""}
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+		for _, r := range c.refs[from] {
+			to := r.ref[1:]
+			if _, found := c.components[to]; found && !reachable[to] {
+				reachable[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+	for component, schema := range c.components {
+		if schema && !reachable[component] {
+			c.problems = append(c.problems, ReferenceFinding{Problem: UnreachableDefinition, Pointer: component})
+		}
+	}
+
+	c.findCycles()
+
+	slices.SortFunc(c.problems, func(a, b ReferenceFinding) int {
+		if a.Pointer != b.Pointer {
+			return strings.Compare(a.Pointer, b.Pointer)
+		}
+		return int(a.Problem) - int(b.Problem)
+	})
+	return c.problems
+}
+
+// findCycles reports the references closing a cycle, found by a depth-first
+// walk of the components in the order of their pointers.
+func (c *referenceChecker) findCycles() {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var stack []string
+	var visit func(component string)
+	visit = func(component string) {
+		state[component] = visiting
+		stack = append(stack, component)
+		for _, r := range c.refs[component] {
+			to := r.ref[1:]
+			if _, found := c.components[to]; !found {
+				continue
+			}
+			switch state[to] {
+			case unvisited:
+				visit(to)
+			case visiting:
+				cycle := slices.Clone(stack[slices.Index(stack, to):])
+				c.problems = append(c.problems, ReferenceFinding{Problem: ReferenceCycle, Pointer: r.pointer, Ref: r.ref, Cycle: append(cycle, to)})
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[component] = visited
+	}
+	for _, component := range slices.Sorted(maps.Keys(c.components)) {
+		if state[component] == unvisited {
+			visit(component)
+		}
+	}
+}
+
+func (c *referenceChecker) schema(pointer string, s *spec.Schema) {
+	if s == nil {
+		return
+	}
+	c.ref(pointer, &s.Ref, c.schemaPrefix)
+	for _, k := range slices.Sorted(maps.Keys(s.Definitions)) {
+		v := s.Definitions[k]
+		c.schema(pointer+"/definitions/"+escapePointerToken(k), &v)
+	}
+	for _, k := range slices.Sorted(maps.Keys(s.Properties)) {
+		v := s.Properties[k]
+		c.schema(pointer+"/properties/"+escapePointerToken(k), &v)
+	}
+	for _, k := range slices.Sorted(maps.Keys(s.PatternProperties)) {
+		v := s.PatternProperties[k]
+		c.schema(pointer+"/patternProperties/"+escapePointerToken(k), &v)
+	}
+	for _, k := range slices.Sorted(maps.Keys(s.Dependencies)) {
+		v := s.Dependencies[k]
+		c.schema(pointer+"/dependencies/"+escapePointerToken(k), v.Schema)
+	}
+	for i := range s.AllOf {
+		c.schema(pointer+"/allOf/"+strconv.Itoa(i), &s.AllOf[i])
+	}
+	for i := range s.AnyOf {
+		c.schema(pointer+"/anyOf/"+strconv.Itoa(i), &s.AnyOf[i])
+	}
+	for i := range s.OneOf {
+		c.schema(pointer+"/oneOf/"+strconv.Itoa(i), &s.OneOf[i])
+	}
+	c.schema(pointer+"/not", s.Not)
+	if s.AdditionalProperties != nil {
+		c.schema(pointer+"/additionalProperties", s.AdditionalProperties.Schema)
+	}
+	if s.AdditionalItems != nil {
+		c.schema(pointer+"/additionalItems", s.AdditionalItems.Schema)
+	}
+	if s.Items != nil {
+		c.schema(pointer+"/items", s.Items.Schema)
+		for i := range s.Items.Schemas {
+			c.schema(pointer+"/items/"+strconv.Itoa(i), &s.Items.Schemas[i])
+		}
+	}
+}
+
+func (c *referenceChecker) items(pointer string, items *spec.Items) {
+	for ; items != nil; items = items.Items {
+		pointer += "/items"
+		c.ref(pointer, &items.Ref, c.schemaPrefix)
+	}
+}
+
+func (c *referenceChecker) parameter(pointer string, param *spec.Parameter) {
+	c.ref(pointer, &param.Ref, parameterPrefix)
+	c.schema(pointer+"/schema", param.Schema)
+	c.items(pointer, param.Items)
+}
+
+func (c *referenceChecker) response(pointer string, resp *spec.Response) {
+	if resp == nil {
+		return
+	}
+	c.ref(pointer, &resp.Ref, responsePrefix)
+	c.schema(pointer+"/schema", resp.Schema)
+	for _, k := range slices.Sorted(maps.Keys(resp.Headers)) {
+		c.items(pointer+"/headers/"+escapePointerToken(k), resp.Headers[k].Items)
+	}
+}
+
+func (c *referenceChecker) operation(pointer string, op *spec.Operation) {
+	if op == nil {
+		return
+	}
+	for i := range op.Parameters {
+		c.parameter(pointer+"/parameters/"+strconv.Itoa(i), &op.Parameters[i])
+	}
+	if op.Responses == nil {
+		return
+	}
+	c.response(pointer+"/responses/default", op.Responses.Default)
+	for _, code := range slices.Sorted(maps.Keys(op.Responses.StatusCodeResponses)) {
+		resp := op.Responses.StatusCodeResponses[code]
+		c.response(pointer+"/responses/"+strconv.Itoa(code), &resp)
+	}
+}
+
+func (c *referenceChecker) pathItem(pointer string, pathItem spec.PathItem) {
+	for i := range pathItem.Parameters {
+		c.parameter(pointer+"/parameters/"+strconv.Itoa(i), &pathItem.Parameters[i])
+	}
+	c.operation(pointer+"/get", pathItem.Get)
+	c.operation(pointer+"/put", pathItem.Put)
+	c.operation(pointer+"/post", pathItem.Post)
+	c.operation(pointer+"/delete", pathItem.Delete)
+	c.operation(pointer+"/options", pathItem.Options)
+	c.operation(pointer+"/head", pathItem.Head)
+	c.operation(pointer+"/patch", pathItem.Patch)
+}
+
+func (c *referenceChecker) examples(pointer string, examples map[string]*spec3.Example) {
+	for _, k := range slices.Sorted(maps.Keys(examples)) {
+		if example := examples[k]; example != nil {
+			c.ref(pointer+"/examples/"+escapePointerToken(k), &example.Ref, examplePrefixV3)
+		}
+	}
+}
+
+func (c *referenceChecker) content(pointer string, content map[string]*spec3.MediaType) {
+	for _, k := range slices.Sorted(maps.Keys(content)) {
+		mediaType := content[k]
+		if mediaType == nil {
+			continue
+		}
+		p := pointer + "/content/" + escapePointerToken(k)
+		c.schema(p+"/schema", mediaType.Schema)
+		c.examples(p, mediaType.Examples)
+		for _, e := range slices.Sorted(maps.Keys(mediaType.Encoding)) {
+			if encoding := mediaType.Encoding[e]; encoding != nil {
+				c.headers(p+"/encoding/"+escapePointerToken(e), encoding.Headers)
+			}
+		}
+	}
+}
+
+func (c *referenceChecker) headers(pointer string, headers map[string]*spec3.Header) {
+	for _, k := range slices.Sorted(maps.Keys(headers)) {
+		c.header(pointer+"/headers/"+escapePointerToken(k), headers[k])
+	}
+}
+
+func (c *referenceChecker) header(pointer string, header *spec3.Header) {
+	if header == nil {
+		return
+	}
+	c.ref(pointer, &header.Ref, headerPrefixV3)
+	c.schema(pointer+"/schema", header.Schema)
+	c.content(pointer, header.Content)
+	c.examples(pointer, header.Examples)
+}
+
+func (c *referenceChecker) parameterV3(pointer string, param *spec3.Parameter) {
+	if param == nil {
+		return
+	}
+	c.ref(pointer, &param.Ref, parameterPrefixV3)
+	c.schema(pointer+"/schema", param.Schema)
+	c.content(pointer, param.Content)
+	c.examples(pointer, param.Examples)
+}
+
+func (c *referenceChecker) requestBody(pointer string, body *spec3.RequestBody) {
+	if body == nil {
+		return
+	}
+	c.ref(pointer, &body.Ref, requestBodyPrefixV3)
+	c.content(pointer, body.Content)
+}
+
+func (c *referenceChecker) responseV3(pointer string, resp *spec3.Response) {
+	if resp == nil {
+		return
+	}
+	c.ref(pointer, &resp.Ref, responsePrefixV3)
+	c.headers(pointer, resp.Headers)
+	c.content(pointer, resp.Content)
+	for _, k := range slices.Sorted(maps.Keys(resp.Links)) {
+		if link := resp.Links[k]; link != nil {
+			c.ref(pointer+"/links/"+escapePointerToken(k), &link.Ref, linkPrefixV3)
+		}
+	}
+}
+
+func (c *referenceChecker) operationV3(pointer string, op *spec3.Operation) {
+	if op == nil {
+		return
+	}
+	for i, param := range op.Parameters {
+		c.parameterV3(pointer+"/parameters/"+strconv.Itoa(i), param)
+	}
+	c.requestBody(pointer+"/requestBody", op.RequestBody)
+	if op.Responses == nil {
+		return
+	}
+	c.responseV3(pointer+"/responses/default", op.Responses.Default)
+	for _, code := range slices.Sorted(maps.Keys(op.Responses.StatusCodeResponses)) {
+		c.responseV3(pointer+"/responses/"+strconv.Itoa(code), op.Responses.StatusCodeResponses[code])
+	}
+}
+
+func (c *referenceChecker) pathV3(pointer string, path *spec3.Path) {
+	if path == nil {
+		return
+	}
+	for i, param := range path.Parameters {
+		c.parameterV3(pointer+"/parameters/"+strconv.Itoa(i), param)
+	}
+	c.operationV3(pointer+"/get", path.Get)
+	c.operationV3(pointer+"/put", path.Put)
+	c.operationV3(pointer+"/post", path.Post)
+	c.operationV3(pointer+"/delete", path.Delete)
+	c.operationV3(pointer+"/options", path.Options)
+	c.operationV3(pointer+"/head", path.Head)
+	c.operationV3(pointer+"/patch", path.Patch)
+	c.operationV3(pointer+"/trace", path.Trace)
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
+)
+
+func TestCheckReferences(t *testing.T) {
+	var sp *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a/{name}:
+    parameters:
+    - $ref: "#/parameters/name"
+    get:
+      parameters:
+      - $ref: "#/definitions/A"
+      responses:
+        200:
+          $ref: "#/responses/OK"
+        404:
+          schema:
+            $ref: "#/definitions/Missing"
+parameters:
+  name:
+    name: name
+    in: path
+    type: string
+responses:
+  OK:
+    description: OK
+    schema:
+      $ref: "#/definitions/A"
+definitions:
+  A:
+    type: object
+    properties:
+      b:
+        $ref: "#/definitions/B"
+      remote:
+        $ref: "other.json#/definitions/C"
+  B:
+    type: object
+    properties:
+      a:
+        type: array
+        items:
+          $ref: "#/definitions/A"
+  a/b:
+    type: object
+    properties:
+      self:
+        $ref: "#/definitions/a~1b"
+      param:
+        $ref: "#/parameters/name"
+`), &sp))
+
+	findings := CheckReferences(sp)
+	assert.Equal(t, []ReferenceFinding{
+		{Problem: ReferenceCycle, Pointer: "/definitions/B/properties/a/items/$ref", Ref: "#/definitions/A", Cycle: []string{"/definitions/A", "/definitions/B", "/definitions/A"}},
+		{Problem: UnreachableDefinition, Pointer: "/definitions/a~1b"},
+		{Problem: WrongComponentKind, Pointer: "/definitions/a~1b/properties/param/$ref", Ref: "#/parameters/name"},
+		{Problem: ReferenceCycle, Pointer: "/definitions/a~1b/properties/self/$ref", Ref: "#/definitions/a~1b", Cycle: []string{"/definitions/a~1b", "/definitions/a~1b"}},
+		{Problem: WrongComponentKind, Pointer: "/paths/~1a~1{name}/get/parameters/0/$ref", Ref: "#/definitions/A"},
+		{Problem: DanglingReference, Pointer: "/paths/~1a~1{name}/get/responses/404/schema/$ref", Ref: "#/definitions/Missing"},
+	}, findings)
+	assert.Equal(t, "/paths/~1a~1{name}/get/responses/404/schema/$ref: dangling reference #/definitions/Missing", findings[5].String())
+	assert.Equal(t, "/definitions/B/properties/a/items/$ref: reference cycle /definitions/A -> /definitions/B -> /definitions/A", findings[0].String())
+
+	data, err := json.Marshal(findings[1])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"problem":"UnreachableDefinition","pointer":"/definitions/a~1b"}`, string(data))
+}
+
+func TestCheckReferencesV3(t *testing.T) {
+	var sp *spec3.OpenAPI
+	require.NoError(t, yaml.Unmarshal([]byte(`
+openapi: 3.0.0
+info:
+  title: test
+  version: v1
+paths:
+  /a:
+    get:
+      parameters:
+      - $ref: "#/components/parameters/p"
+      requestBody:
+        $ref: "#/components/schemas/A"
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/A"
+              examples:
+                a:
+                  $ref: "#/components/examples/Missing"
+components:
+  parameters:
+    p:
+      name: p
+      in: query
+      schema:
+        $ref: "#/components/schemas/B"
+  schemas:
+    A:
+      type: object
+    B:
+      type: string
+    Unused:
+      type: string
+`), &sp))
+
+	assert.Equal(t, []ReferenceFinding{
+		{Problem: UnreachableDefinition, Pointer: "/components/schemas/Unused"},
+		{Problem: WrongComponentKind, Pointer: "/paths/~1a/get/requestBody/$ref", Ref: "#/components/schemas/A"},
+		{Problem: DanglingReference, Pointer: "/paths/~1a/get/responses/200/content/application~1json/examples/a/$ref", Ref: "#/components/examples/Missing"},
+	}, CheckReferencesV3(sp))
+}
+
+func TestCheckReferencesWithKubeSpec(t *testing.T) {
+	specs, _ := loadTestData()
+	for _, s := range specs {
+		for _, f := range CheckReferences(s) {
+			assert.False(t, f.Problem.Invalid(), "%v", f)
+		}
+	}
+}
+
+func TestMergeSpecsCheckReferences(t *testing.T) {
+	specs, _ := loadTestData()
+	opts := legacyMergeOptions(true, true, true)
+	opts.CheckReferences = true
+	merged, err := cloneSpec(specs[0])
+	require.NoError(t, err)
+	for _, s := range specs[1:] {
+		require.NoError(t, MergeSpecsWithOptions(merged, s, opts))
+	}
+
+	var dest, source *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/A"
+definitions:
+  A:
+    type: object
+`), &dest))
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /b:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/B"
+`), &source))
+	origDest, err := cloneSpec(dest)
+	require.NoError(t, err)
+
+	err = MergeSpecsWithOptions(dest, source, MergeOptions{CheckReferences: true})
+	var refErr *ReferenceError
+	require.True(t, errors.As(err, &refErr), "unexpected error %v", err)
+	assert.Equal(t, []ReferenceFinding{
+		{Problem: DanglingReference, Pointer: "/paths/~1b/get/responses/200/schema/$ref", Ref: "#/definitions/B"},
+	}, refErr.Findings)
+	assert.EqualError(t, err, "invalid references in merged OpenAPI spec: /paths/~1b/get/responses/200/schema/$ref: dangling reference #/definitions/B")
+	assert.Equal(t, origDest, dest, "dest mutated on error")
+
+	// The aggregator is left unchanged too.
+	a := NewAggregator(&spec.Swagger{}, MergeOptions{CheckReferences: true})
+	require.NoError(t, a.Replace("dest", dest))
+	assert.ErrorAs(t, a.Replace("source", source), &refErr)
+	assert.Equal(t, []string{"dest"}, a.Sources())
+
+	require.NoError(t, MergeSpecsWithOptions(dest, source, MergeOptions{}))
+	assert.Contains(t, dest.Paths.Paths, "/b")
+}
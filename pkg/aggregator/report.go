@@ -0,0 +1,230 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// MergeReport lists the conflicts between two specs, and how merging one
+// into the other resolves them. See DryRunMergeSpecs.
+type MergeReport struct {
+	// PathConflicts are the paths of the source that are also in the
+	// destination, sorted.
+	PathConflicts []string `json:"pathConflicts,omitempty"`
+	// DefinitionConflicts are the definitions of the source that are
+	// different from the definitions of the destination with the same
+	// name, ignoring the x-kubernetes-group-version-kind extension unless
+	// MergeOptions.DefinitionsEqual says otherwise, sorted by name.
+	DefinitionConflicts []Conflict `json:"definitionConflicts,omitempty"`
+	// ParameterConflicts are the parameters of the source that are
+	// different from the parameters of the destination with the same
+	// name, once the definitions they reference are renamed, sorted by
+	// name.
+	ParameterConflicts []Conflict `json:"parameterConflicts,omitempty"`
+}
+
+// Empty returns true if the report has no conflicts.
+func (r *MergeReport) Empty() bool {
+	return len(r.PathConflicts) == 0 && len(r.DefinitionConflicts) == 0 && len(r.ParameterConflicts) == 0
+}
+
+// Conflict is a definition or a parameter of the source spec with the same
+// name as a different one in the destination spec.
+type Conflict struct {
+	// Name is the name of the definition or parameter.
+	Name string `json:"name"`
+	// Resolution is how merging resolves the conflict.
+	Resolution ConflictResolution `json:"resolution"`
+	// RenamedTo is the name the source definition or parameter is renamed
+	// to when merged, along with the references to it, if Resolution is
+	// ConflictRename.
+	RenamedTo string `json:"renamedTo,omitempty"`
+	// Reused is true if the destination already has an identical
+	// definition or parameter named RenamedTo, in which case it is reused.
+	Reused bool `json:"reused,omitempty"`
+	// Differences are the differences between the destination and the
+	// source definition or parameter named Name.
+	Differences []Difference `json:"differences"`
+}
+
+// Difference is a value that differs between the destination and the
+// source definition or parameter of a conflict.
+type Difference struct {
+	// Pointer is the JSON pointer to the value in the definition or
+	// parameter, e.g. "/properties/spec/type".
+	Pointer string `json:"pointer"`
+	// Destination and Source are the JSON values, nil if missing.
+	Destination interface{} `json:"destination,omitempty"`
+	Source      interface{} `json:"source,omitempty"`
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %s != %s", d.Pointer, jsonString(d.Destination), jsonString(d.Source))
+}
+
+func jsonString(v interface{}) string {
+	if v == nil {
+		return "<missing>"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// DryRunMergeSpecs reports the conflicts that merging source into dest runs
+// into, without mutating either spec.
+//
+// If ignorePathConflicts is true, the conflicts are the ones
+// MergeSpecsIgnorePathConflictRenamingDefinitionsAndParameters resolves:
+// the conflicting paths of the source are dropped, along with the
+// definitions only they use, before checking definitions and parameters.
+// Otherwise they are the ones MergeSpecs resolves, which fails if there is
+// any path conflict. MergeSpecsFailOnDefinitionConflict fails if there is
+// any conflict.
+func DryRunMergeSpecs(dest, source *spec.Swagger, ignorePathConflicts bool) (*MergeReport, error) {
+	return DryRunMergeSpecsWithOptions(dest, source, legacyMergeOptions(true, true, ignorePathConflicts))
+}
+
+// DryRunMergeSpecsWithOptions reports the conflicts that
+// MergeSpecsWithOptions runs into with the same options, and how it
+// resolves them, without mutating either spec. It returns an error if
+// MergeSpecsWithOptions fails before resolving conflicts, e.g. on an
+// invalid resolution.
+func DryRunMergeSpecsWithOptions(dest, source *spec.Swagger, opts MergeOptions) (*MergeReport, error) {
+	report := &MergeReport{}
+	plan, err := planMerge(dest, source, opts)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		return report, nil
+	}
+	for _, c := range plan.pathConflicts {
+		report.PathConflicts = append(report.PathConflicts, c.from)
+	}
+
+	for _, r := range plan.definitionConflicts {
+		existing, v := dest.Definitions[r.from], plan.original.Definitions[r.from]
+		differences, err := diff(&existing, &v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare definition %s: %w", r.from, err)
+		}
+		// The GVK extension is merged rather than conflicting.
+		kept := differences[:0]
+		for _, d := range differences {
+			if d.Pointer != "/"+gvkKey && !strings.HasPrefix(d.Pointer, "/"+gvkKey+"/") {
+				kept = append(kept, d)
+			}
+		}
+		report.DefinitionConflicts = append(report.DefinitionConflicts, newConflict(r, kept))
+	}
+	for _, r := range plan.parameterConflicts {
+		existing, p := dest.Parameters[r.from], plan.source.Parameters[r.from]
+		differences, err := diff(&existing, &p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare parameter %s: %w", r.from, err)
+		}
+		report.ParameterConflicts = append(report.ParameterConflicts, newConflict(r, differences))
+	}
+	return report, nil
+}
+
+func newConflict(r rename, differences []Difference) Conflict {
+	c := Conflict{Name: r.from, Resolution: r.resolution, Reused: r.reused, Differences: differences}
+	if r.resolution == ConflictRename {
+		c.RenamedTo = r.to
+	}
+	return c
+}
+
+// diff returns the differences between the JSON representations of dest
+// and source, sorted by pointer.
+func diff(dest, source interface{}) ([]Difference, error) {
+	d, err := toJSONValue(dest)
+	if err != nil {
+		return nil, err
+	}
+	s, err := toJSONValue(source)
+	if err != nil {
+		return nil, err
+	}
+	var differences []Difference
+	diffJSON("", d, s, &differences)
+	return differences, nil
+}
+
+func toJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var ret interface{}
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func diffJSON(pointer string, dest, source interface{}, differences *[]Difference) {
+	switch d := dest.(type) {
+	case map[string]interface{}:
+		s, ok := source.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(d)+len(s))
+		for k := range d {
+			keys = append(keys, k)
+		}
+		for k := range s {
+			if _, found := d[k]; !found {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			diffJSON(pointer+"/"+escapePointerToken(k), d[k], s[k], differences)
+		}
+		return
+	case []interface{}:
+		s, ok := source.([]interface{})
+		if !ok || len(s) != len(d) {
+			break
+		}
+		for i := range d {
+			diffJSON(pointer+"/"+strconv.Itoa(i), d[i], s[i], differences)
+		}
+		return
+	}
+	if !reflect.DeepEqual(dest, source) {
+		*differences = append(*differences, Difference{Pointer: pointer, Destination: dest, Source: source})
+	}
+}
+
+func escapePointerToken(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
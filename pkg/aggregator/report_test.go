@@ -0,0 +1,172 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
+)
+
+func TestDryRunMergeSpecs(t *testing.T) {
+	var dest, source *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /test:
+    post:
+      parameters:
+      - name: "body"
+        schema:
+          $ref: "#/definitions/Test"
+      - $ref: "#/parameters/a"
+  /shared:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/Shared"
+definitions:
+  Test:
+    type: "object"
+    x-kubernetes-group-version-kind:
+    - group: ""
+      kind: Test
+      version: v1
+  Other:
+    type: "object"
+    properties:
+      name:
+        type: string
+  Other_v2:
+    type: "object"
+    properties:
+      name:
+        type: integer
+  Shared:
+    type: "object"
+parameters:
+  a:
+    in: query
+    name: a
+    schema:
+      $ref: "#/definitions/Test"
+`), &dest))
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /othertest:
+    post:
+      parameters:
+      - name: "body"
+        schema:
+          $ref: "#/definitions/Test"
+      - $ref: "#/parameters/a"
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/Other"
+  /shared:
+    get:
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/Shared"
+definitions:
+  Test:
+    type: "object"
+    description: "This Test has a description"
+    x-kubernetes-group-version-kind:
+    - group: ""
+      kind: Test
+      version: v2
+  Other:
+    type: "object"
+    properties:
+      name:
+        type: integer
+  Shared:
+    type: "string"
+parameters:
+  a:
+    in: query
+    name: a
+    schema:
+      $ref: "#/definitions/Test"
+`), &source))
+	origDest, err := cloneSpec(dest)
+	require.NoError(t, err)
+	origSource, err := cloneSpec(source)
+	require.NoError(t, err)
+
+	report, err := DryRunMergeSpecs(dest, source, false)
+	require.NoError(t, err)
+	assert.Equal(t, &MergeReport{
+		PathConflicts: []string{"/shared"},
+		DefinitionConflicts: []Conflict{
+			{Name: "Other", RenamedTo: "Other_v2", Reused: true, Differences: []Difference{
+				{Pointer: "/properties/name/type", Destination: "string", Source: "integer"},
+			}},
+			{Name: "Shared", RenamedTo: "Shared_v2", Differences: []Difference{
+				{Pointer: "/type", Destination: "object", Source: "string"},
+			}},
+			{Name: "Test", RenamedTo: "Test_v2", Differences: []Difference{
+				{Pointer: "/description", Source: "This Test has a description"},
+			}},
+		},
+		ParameterConflicts: []Conflict{
+			{Name: "a", RenamedTo: "a_v2", Differences: []Difference{
+				{Pointer: "/schema/$ref", Destination: "#/definitions/Test", Source: "#/definitions/Test_v2"},
+			}},
+		},
+	}, report)
+	assert.False(t, report.Empty())
+	assert.Equal(t, "/description: <missing> != \"This Test has a description\"", report.DefinitionConflicts[2].Differences[0].String())
+
+	// Ignoring the conflicting path drops the definitions only it uses.
+	report, err = DryRunMergeSpecs(dest, source, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/shared"}, report.PathConflicts)
+	names := []string{}
+	for _, c := range report.DefinitionConflicts {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"Other", "Test"}, names)
+
+	assert.Equal(t, DebugSpec{origDest}, DebugSpec{dest}, "unexpected mutation of dest")
+	assert.Equal(t, DebugSpec{origSource}, DebugSpec{source}, "unexpected mutation of source")
+
+	// The report matches what merging does.
+	require.NoError(t, MergeSpecsIgnorePathConflictRenamingDefinitionsAndParameters(dest, source))
+	assert.Contains(t, dest.Definitions, "Test_v2")
+	assert.NotContains(t, dest.Definitions, "Shared_v2")
+	assert.Equal(t, spec.MustCreateRef("#/definitions/Other_v2"), dest.Paths.Paths["/othertest"].Post.Responses.StatusCodeResponses[200].Schema.Ref)
+	assert.Equal(t, spec.MustCreateRef("#/parameters/a_v2"), dest.Paths.Paths["/othertest"].Post.Parameters[1].Ref)
+
+	report, err = DryRunMergeSpecs(dest, dest, false)
+	require.NoError(t, err)
+	assert.Empty(t, report.DefinitionConflicts)
+	assert.Empty(t, report.ParameterConflicts)
+}
+
+func TestEscapePointerToken(t *testing.T) {
+	assert.Equal(t, "a~1b~0c", escapePointerToken("a/b~c"))
+}
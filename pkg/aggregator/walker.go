@@ -25,6 +25,7 @@ import (
 const (
 	definitionPrefix = "#/definitions/"
 	parameterPrefix  = "#/parameters/"
+	responsePrefix   = "#/responses/"
 )
 
 // Run a readonlyReferenceWalker method on all references of an OpenAPI spec
@@ -39,6 +40,12 @@ type readonlyReferenceWalker struct {
 // walkOnAllReferences recursively walks on all references, while following references into definitions.
 // it calls walkRef on each found reference.
 func walkOnAllReferences(walkRef func(ref *spec.Ref), root *spec.Swagger) {
+	walkReferences(walkRef, root, false)
+}
+
+// walkReferences is walkOnAllReferences, also following references into
+// responses if followResponses is true.
+func walkReferences(walkRef func(ref *spec.Ref), root *spec.Swagger, followResponses bool) {
 	alreadyVisited := map[string]bool{}
 
 	walker := &readonlyReferenceWalker{
@@ -57,6 +64,11 @@ func walkOnAllReferences(walkRef func(ref *spec.Ref), root *spec.Swagger) {
 			if param, found := root.Parameters[paramName]; found {
 				walker.walkParam(param)
 			}
+		} else if followResponses && strings.HasPrefix(refStr, responsePrefix) {
+			if resp, found := root.Responses[refStr[len(responsePrefix):]]; found && !alreadyVisited[refStr] {
+				alreadyVisited[refStr] = true
+				walker.walkResponse(&resp)
+			}
 		} else if strings.HasPrefix(refStr, definitionPrefix) {
 			defName := refStr[len(definitionPrefix):]
 
@@ -0,0 +1,182 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+const (
+	schemaPrefixV3      = "#/components/schemas/"
+	parameterPrefixV3   = "#/components/parameters/"
+	responsePrefixV3    = "#/components/responses/"
+	requestBodyPrefixV3 = "#/components/requestBodies/"
+	headerPrefixV3      = "#/components/headers/"
+	examplePrefixV3     = "#/components/examples/"
+	linkPrefixV3        = "#/components/links/"
+
+	securitySchemePrefixV3 = "#/components/securitySchemes/"
+)
+
+// readonlyReferenceWalkerV3 walks all references of an OpenAPI v3 spec,
+// following references into components.
+type readonlyReferenceWalkerV3 struct {
+	readonlyReferenceWalker
+
+	// The spec to walk through.
+	root *spec3.OpenAPI
+
+	alreadyVisited map[string]bool
+}
+
+func newReadonlyReferenceWalkerV3(walkRef func(ref *spec.Ref), root *spec3.OpenAPI) *readonlyReferenceWalkerV3 {
+	walker := &readonlyReferenceWalkerV3{
+		root:           root,
+		alreadyVisited: map[string]bool{},
+	}
+	walker.walkRefCallback = func(ref *spec.Ref) {
+		walkRef(ref)
+
+		refStr := ref.String()
+		if refStr == "" || walker.alreadyVisited[refStr] || root.Components == nil {
+			return
+		}
+		walker.alreadyVisited[refStr] = true
+
+		components := root.Components
+		switch {
+		case strings.HasPrefix(refStr, schemaPrefixV3):
+			walker.walkSchema(components.Schemas[refStr[len(schemaPrefixV3):]])
+		case strings.HasPrefix(refStr, parameterPrefixV3):
+			walker.walkParameter(components.Parameters[refStr[len(parameterPrefixV3):]])
+		case strings.HasPrefix(refStr, responsePrefixV3):
+			walker.walkResponse(components.Responses[refStr[len(responsePrefixV3):]])
+		case strings.HasPrefix(refStr, requestBodyPrefixV3):
+			walker.walkRequestBody(components.RequestBodies[refStr[len(requestBodyPrefixV3):]])
+		case strings.HasPrefix(refStr, headerPrefixV3):
+			walker.walkHeader(components.Headers[refStr[len(headerPrefixV3):]])
+		case strings.HasPrefix(refStr, examplePrefixV3):
+			if example := components.Examples[refStr[len(examplePrefixV3):]]; example != nil {
+				walker.walkRefCallback(&example.Ref)
+			}
+		}
+	}
+	return walker
+}
+
+func (s *readonlyReferenceWalkerV3) walkExamples(examples map[string]*spec3.Example) {
+	for _, example := range examples {
+		if example != nil {
+			s.walkRefCallback(&example.Ref)
+		}
+	}
+}
+
+func (s *readonlyReferenceWalkerV3) walkContent(content map[string]*spec3.MediaType) {
+	for _, mediaType := range content {
+		if mediaType == nil {
+			continue
+		}
+		s.walkSchema(mediaType.Schema)
+		s.walkExamples(mediaType.Examples)
+		for _, encoding := range mediaType.Encoding {
+			if encoding == nil {
+				continue
+			}
+			for _, header := range encoding.Headers {
+				s.walkHeader(header)
+			}
+		}
+	}
+}
+
+func (s *readonlyReferenceWalkerV3) walkHeader(header *spec3.Header) {
+	if header == nil {
+		return
+	}
+	s.walkRefCallback(&header.Ref)
+	s.walkSchema(header.Schema)
+	s.walkContent(header.Content)
+	s.walkExamples(header.Examples)
+}
+
+func (s *readonlyReferenceWalkerV3) walkParameter(param *spec3.Parameter) {
+	if param == nil {
+		return
+	}
+	s.walkRefCallback(&param.Ref)
+	s.walkSchema(param.Schema)
+	s.walkContent(param.Content)
+	s.walkExamples(param.Examples)
+}
+
+func (s *readonlyReferenceWalkerV3) walkRequestBody(body *spec3.RequestBody) {
+	if body == nil {
+		return
+	}
+	s.walkRefCallback(&body.Ref)
+	s.walkContent(body.Content)
+}
+
+func (s *readonlyReferenceWalkerV3) walkResponse(resp *spec3.Response) {
+	if resp == nil {
+		return
+	}
+	s.walkRefCallback(&resp.Ref)
+	for _, header := range resp.Headers {
+		s.walkHeader(header)
+	}
+	s.walkContent(resp.Content)
+}
+
+func (s *readonlyReferenceWalkerV3) walkOperation(op *spec3.Operation) {
+	if op == nil {
+		return
+	}
+	for _, param := range op.Parameters {
+		s.walkParameter(param)
+	}
+	s.walkRequestBody(op.RequestBody)
+	if op.Responses == nil {
+		return
+	}
+	s.walkResponse(op.Responses.Default)
+	for _, r := range op.Responses.StatusCodeResponses {
+		s.walkResponse(r)
+	}
+}
+
+func (s *readonlyReferenceWalkerV3) walkPath(path *spec3.Path) {
+	if path == nil {
+		return
+	}
+	s.walkRefCallback(&path.Ref)
+	for _, param := range path.Parameters {
+		s.walkParameter(param)
+	}
+	s.walkOperation(path.Delete)
+	s.walkOperation(path.Get)
+	s.walkOperation(path.Head)
+	s.walkOperation(path.Options)
+	s.walkOperation(path.Patch)
+	s.walkOperation(path.Post)
+	s.walkOperation(path.Put)
+	s.walkOperation(path.Trace)
+}
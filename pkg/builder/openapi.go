@@ -308,6 +308,7 @@ func (o *openAPI) buildOperations(route common.Route, inPathCommonParamsMap map[
 	}
 
 	// Build responses
+	stream := routeStream(route)
 	for _, resp := range route.StatusCodeResponses() {
 		ret.Responses.StatusCodeResponses[resp.Code()], err = o.buildResponse(resp.Model(), resp.Message())
 		if err != nil {
@@ -321,6 +322,16 @@ func (o *openAPI) buildOperations(route common.Route, inPathCommonParamsMap map[
 			return ret, err
 		}
 	}
+	// Swagger 2.0 can't describe the events of the streaming media types, so
+	// the successful responses of streaming routes are only marked.
+	if stream != "" {
+		for code, resp := range ret.Responses.StatusCodeResponses {
+			if code >= 200 && code < 300 {
+				resp.AddExtension(common.ExtensionStream, stream)
+				ret.Responses.StatusCodeResponses[code] = resp
+			}
+		}
+	}
 	for code, resp := range o.config.CommonResponses {
 		if _, exists := ret.Responses.StatusCodeResponses[code]; !exists {
 			ret.Responses.StatusCodeResponses[code] = resp
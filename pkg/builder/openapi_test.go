@@ -653,3 +653,35 @@ func TestEscapeJsonPointerInDefinitionName(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildOpenAPISpecStreaming(t *testing.T) {
+	config, _, assert := setUp(t, false)
+	ws := new(restful.WebService)
+	ws.Path("/watch")
+	ws.Route(ws.Method("get").
+		Path("/test").
+		Operation("watchTest").
+		Produces(restful.MIME_JSON, "application/json;stream=watch", "application/vnd.kubernetes.protobuf;stream=watch").
+		Returns(200, "OK", TestOutput{}).
+		Returns(401, "Unauthorized", TestOutput{}).
+		Metadata(openapi.StreamingResponseMetadataKey, openapi.StreamingResponse{}).
+		To(noOp))
+	ws.Route(ws.Method("get").
+		Path("/list").
+		Operation("listTest").
+		Produces(restful.MIME_JSON, "application/json;stream=watch").
+		Returns(200, "OK", TestOutput{}).
+		To(noOp))
+
+	swagger, err := BuildOpenAPISpec([]*restful.WebService{ws}, config)
+	if !assert.NoError(err) {
+		return
+	}
+	op := swagger.Paths.Paths["/watch/test"].Get
+	assert.NotContains(op.Extensions, openapi.StreamingResponseMetadataKey)
+	assert.Equal(spec.Extensions{openapi.ExtensionStream: "watch"}, op.Responses.StatusCodeResponses[200].Extensions)
+	assert.Equal(spec.MustCreateRef("#/definitions/builder.TestOutput"), op.Responses.StatusCodeResponses[200].Schema.Ref)
+	assert.Empty(op.Responses.StatusCodeResponses[401].Extensions)
+	// Routes that aren't marked as streaming aren't, whatever they produce.
+	assert.Empty(swagger.Paths.Paths["/watch/list"].Get.Responses.StatusCodeResponses[200].Extensions)
+}
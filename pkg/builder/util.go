@@ -17,6 +17,7 @@ limitations under the License.
 package builder
 
 import (
+	"mime"
 	"sort"
 
 	"k8s.io/kube-openapi/pkg/common"
@@ -59,3 +60,18 @@ func mapKeyFromParam(param common.Parameter) interface{} {
 		Kind: param.Kind(),
 	}
 }
+
+// routeStream returns the stream parameter of the first streaming media type
+// produced by a route marked with common.StreamingResponseMetadataKey, e.g.
+// watch for application/json;stream=watch, or "" if it has none.
+func routeStream(route common.Route) string {
+	if common.GetStreamingResponse(route) == nil {
+		return ""
+	}
+	for _, mediaType := range route.Produces() {
+		if _, params, err := mime.ParseMediaType(mediaType); err == nil && params["stream"] != "" {
+			return params["stream"]
+		}
+	}
+	return ""
+}
@@ -50,7 +50,9 @@ func groupRoutesByPath(routes []common.Route) map[string][]common.Route {
 	return pathToRoutes
 }
 
-func (o *openAPI) buildResponse(model interface{}, description string, content []string) (*spec3.Response, error) {
+// buildResponse builds a response of the given model. If streaming is not nil,
+// the streaming media types of the response describe its events instead.
+func (o *openAPI) buildResponse(model interface{}, description string, content []string, streaming *common.StreamingResponse) (*spec3.Response, error) {
 	response := &spec3.Response{
 		ResponseProps: spec3.ResponseProps{
 			Description: description,
@@ -62,8 +64,25 @@ func (o *openAPI) buildResponse(model interface{}, description string, content [
 	if err != nil {
 		return nil, err
 	}
+	eventSchema := s
+	if streaming != nil && streaming.EventModel != nil {
+		if eventSchema, err = o.toSchema(util.GetCanonicalTypeName(streaming.EventModel)); err != nil {
+			return nil, err
+		}
+	}
 
 	for _, contentType := range content {
+		if stream := mediaTypeStream(contentType); streaming != nil && stream != "" {
+			response.ResponseProps.Content[contentType] = &spec3.MediaType{
+				MediaTypeProps: spec3.MediaTypeProps{
+					Schema: eventSchema,
+				},
+				VendorExtensible: spec.VendorExtensible{
+					Extensions: spec.Extensions{common.ExtensionStream: stream},
+				},
+			}
+			continue
+		}
 		response.ResponseProps.Content[contentType] = &spec3.MediaType{
 			MediaTypeProps: spec3.MediaTypeProps{
 				Schema: s,
@@ -99,8 +118,13 @@ func (o *openAPI) buildOperations(route common.Route, inPathCommonParamsMap map[
 	}
 
 	// Build responses
+	streaming := common.GetStreamingResponse(route)
 	for _, resp := range route.StatusCodeResponses() {
-		ret.Responses.StatusCodeResponses[resp.Code()], err = o.buildResponse(resp.Model(), resp.Message(), route.Produces())
+		var respStreaming *common.StreamingResponse
+		if resp.Code() >= 200 && resp.Code() < 300 {
+			respStreaming = streaming
+		}
+		ret.Responses.StatusCodeResponses[resp.Code()], err = o.buildResponse(resp.Model(), resp.Message(), route.Produces(), respStreaming)
 		if err != nil {
 			return ret, err
 		}
@@ -108,7 +132,7 @@ func (o *openAPI) buildOperations(route common.Route, inPathCommonParamsMap map[
 
 	// If there is no response but a write sample, assume that write sample is an http.StatusOK response.
 	if len(ret.Responses.StatusCodeResponses) == 0 && route.ResponsePayloadSample() != nil {
-		ret.Responses.StatusCodeResponses[http.StatusOK], err = o.buildResponse(route.ResponsePayloadSample(), "OK", route.Produces())
+		ret.Responses.StatusCodeResponses[http.StatusOK], err = o.buildResponse(route.ResponsePayloadSample(), "OK", route.Produces(), streaming)
 		if err != nil {
 			return ret, err
 		}
@@ -581,3 +581,75 @@ func TestEscapeJsonPointerInSchemaName(t *testing.T) {
 		})
 	}
 }
+
+// Test watch event
+type TestWatchEvent struct {
+	// Type of the event
+	Type string `json:"type"`
+	// Object of the event
+	Object TestOutput `json:"object"`
+}
+
+func (_ TestWatchEvent) OpenAPIDefinition() openapi.OpenAPIDefinition {
+	schema := spec.Schema{}
+	schema.Description = "Test watch event"
+	schema.Properties = map[string]spec.Schema{
+		"type": {
+			SchemaProps: spec.SchemaProps{
+				Description: "Type of the event",
+				Type:        []string{"string"},
+			},
+		},
+		"object": {
+			SchemaProps: spec.SchemaProps{
+				Description: "Object of the event",
+				Ref:         spec.MustCreateRef("#/components/schemas/builder3.TestOutput"),
+			},
+		},
+	}
+	return openapi.OpenAPIDefinition{
+		Schema:       schema,
+		Dependencies: []string{"k8s.io/kube-openapi/pkg/builder3.TestOutput"},
+	}
+}
+
+func TestBuildOpenAPISpecStreaming(t *testing.T) {
+	config, _, assert := setUp(t, false)
+	getDefinitions := config.GetDefinitions
+	config.GetDefinitions = func(ref openapi.ReferenceCallback) map[string]openapi.OpenAPIDefinition {
+		defs := getDefinitions(ref)
+		defs["k8s.io/kube-openapi/pkg/builder3.TestWatchEvent"] = TestWatchEvent{}.OpenAPIDefinition()
+		return defs
+	}
+	ws := new(restful.WebService)
+	ws.Path("/watch")
+	ws.Route(ws.Method("get").
+		Path("/test").
+		Operation("watchTest").
+		Produces(restful.MIME_JSON, "application/json;stream=watch", "application/vnd.kubernetes.protobuf;stream=watch").
+		Returns(200, "OK", TestOutput{}).
+		Returns(401, "Unauthorized", TestOutput{}).
+		Metadata(openapi.StreamingResponseMetadataKey, openapi.StreamingResponse{EventModel: TestWatchEvent{}}).
+		To(noOp))
+
+	swagger, err := BuildOpenAPISpec([]*restful.WebService{ws}, config)
+	if !assert.NoError(err) {
+		return
+	}
+	op := swagger.Paths.Paths["/watch/test"].Get
+	assert.NotContains(op.Extensions, openapi.StreamingResponseMetadataKey)
+
+	outputSchema := getRefSchema("#/components/schemas/builder3.TestOutput")
+	eventSchema := getRefSchema("#/components/schemas/builder3.TestWatchEvent")
+	ok := op.Responses.StatusCodeResponses[200].Content
+	assert.Equal(outputSchema, ok[restful.MIME_JSON].Schema)
+	assert.Empty(ok[restful.MIME_JSON].Extensions)
+	for _, mediaType := range []string{"application/json;stream=watch", "application/vnd.kubernetes.protobuf;stream=watch"} {
+		assert.Equal(eventSchema, ok[mediaType].Schema, mediaType)
+		assert.Equal(spec.Extensions{openapi.ExtensionStream: "watch"}, ok[mediaType].Extensions, mediaType)
+	}
+	unauthorized := op.Responses.StatusCodeResponses[401].Content
+	assert.Equal(outputSchema, unauthorized["application/json;stream=watch"].Schema)
+	assert.Empty(unauthorized["application/json;stream=watch"].Extensions)
+	assert.Contains(swagger.Components.Schemas, "builder3.TestWatchEvent")
+}
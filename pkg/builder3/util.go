@@ -17,6 +17,7 @@ limitations under the License.
 package builder3
 
 import (
+	"mime"
 	"sort"
 
 	"k8s.io/kube-openapi/pkg/common"
@@ -33,6 +34,16 @@ func mapKeyFromParam(param common.Parameter) interface{} {
 	}
 }
 
+// mediaTypeStream returns the stream parameter of a media type, e.g. watch for
+// application/json;stream=watch, or "" if it has none.
+func mediaTypeStream(mediaType string) string {
+	_, params, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return ""
+	}
+	return params["stream"]
+}
+
 func (s parameters) Len() int      { return len(s) }
 func (s parameters) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
@@ -37,6 +37,36 @@ limitations under the License.
 //   - [Atomic]: A cache adapter that atomically replaces the source with a new one.
 //   - [LastSuccess]: A cache adapter that caches the last successful and returns
 //     it if the next call fails. It extends [Atomic].
+//   - [Expiring]: A cache adapter that caches the result for a TTL.
+//   - [Refreshing]: A cache adapter that refreshes the result
+//     periodically in the background and returns the last success.
+//   - [Persistent]: A cache adapter that stores the last successful value
+//     in a directory, and returns it after a restart until the fresh value
+//     is computed.
+//
+// # Contexts
+//
+// [ValueCtx] is the same as [Value], except that Get takes a
+// [context.Context] that is passed down the tree, so that an expensive
+// build can be cancelled, given a deadline or traced. [FuncCtx], [OnceCtx],
+// [TransformCtx], [MergeCtx], [MergeListCtx], [AtomicCtx] and
+// [LastSuccessCtx] are the context-aware variants of the caches above, and
+// [WithContext] and [WithoutContext] adapt one interface to the other.
+//
+// # Subscriptions
+//
+// [Atomic], [LastSuccess], their context-aware variants, and the caches
+// returned by [Merge], [MergeList] and [Transform] implement
+// [Subscribable], which lets a consumer be told about changes instead of
+// polling. Atomic values signal after every Store, merged values signal
+// when an evaluation returns a different etag than the previous one.
+//
+// # Introspection
+//
+// [Named] and [NamedCtx] record the last result, time and duration of a
+// cache under a name, and [Dump] returns the graph of the named caches
+// reachable from a cache, which tells which node of a chain didn't
+// recompute.
 //
 // # Etags
 //
@@ -51,6 +81,7 @@ limitations under the License.
 package cached
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -104,28 +135,16 @@ func Static[T any](value T, etag string) Value[T] {
 // Also note that Golang map iteration is not stable. If the mergeFn
 // depends on the order iteration to be stable, it will need to
 // implement its own sorting or iteration order.
-func Merge[K comparable, T, V any](mergeFn func(results map[K]Result[T]) (V, string, error), caches map[K]Value[T]) Value[V] {
-	list := make([]Value[T], 0, len(caches))
-
-	// map from index to key
-	indexes := make(map[int]K, len(caches))
-	i := 0
-	for k := range caches {
-		list = append(list, caches[k])
-		indexes[i] = k
-		i++
+//
+// The delegates are evaluated as described in MergeList.
+func Merge[K comparable, T, V any](mergeFn func(results map[K]Result[T]) (V, string, error), caches map[K]Value[T], opts ...MergeOption) Value[V] {
+	delegates := make(map[K]ValueCtx[T], len(caches))
+	for k, c := range caches {
+		delegates[k] = WithContext(c)
 	}
-
-	return MergeList(func(results []Result[T]) (V, string, error) {
-		if len(results) != len(indexes) {
-			panic(fmt.Errorf("invalid result length %d, expected %d", len(results), len(indexes)))
-		}
-		m := make(map[K]Result[T], len(results))
-		for i := range results {
-			m[indexes[i]] = results[i]
-		}
-		return mergeFn(m)
-	}, list)
+	return WithoutContext(MergeCtx(func(_ context.Context, results map[K]Result[T]) (V, string, error) {
+		return mergeFn(results)
+	}, delegates, opts...))
 }
 
 // MergeList merges a list of cached values. The function only gets called if
@@ -143,41 +162,112 @@ func Merge[K comparable, T, V any](mergeFn func(results map[K]Result[T]) (V, str
 // function will remerge all the dependencies together everytime. Since
 // the list of dependencies is constant, there is no way to save some
 // partial merge information either.
-func MergeList[T, V any](mergeFn func(results []Result[T]) (V, string, error), delegates []Value[T]) Value[V] {
-	return &listMerger[T, V]{
-		mergeFn:   mergeFn,
-		delegates: delegates,
+//
+// The delegates are evaluated concurrently, up to the limit set with
+// WithParallelism. Concurrent calls to Get don't evaluate the delegates
+// again while an evaluation is in progress, they wait for it and share its
+// result.
+//
+// The returned value is Subscribable: subscribers are signaled when a Get
+// re-runs the mergeFn because a dependency changed, and the result has a
+// different etag or error state than the previous one.
+func MergeList[T, V any](mergeFn func(results []Result[T]) (V, string, error), delegates []Value[T], opts ...MergeOption) Value[V] {
+	delegatesCtx := make([]ValueCtx[T], len(delegates))
+	for i := range delegates {
+		delegatesCtx[i] = WithContext(delegates[i])
+	}
+	return WithoutContext(MergeListCtx(func(_ context.Context, results []Result[T]) (V, string, error) {
+		return mergeFn(results)
+	}, delegatesCtx, opts...))
+}
+
+// MergeOption configures how Merge, MergeList and their context-aware
+// variants evaluate their delegates.
+type MergeOption func(*mergeOptions)
+
+type mergeOptions struct {
+	parallelism int
+}
+
+// WithParallelism bounds the number of delegates evaluated concurrently.
+// By default, or if n isn't positive, all the delegates are evaluated
+// concurrently.
+func WithParallelism(n int) MergeOption {
+	return func(o *mergeOptions) {
+		o.parallelism = n
 	}
 }
 
 type listMerger[T, V any] struct {
-	lock      sync.Mutex
-	mergeFn   func([]Result[T]) (V, string, error)
-	delegates []Value[T]
-	cache     []Result[T]
-	result    Result[V]
+	// lock protects inflight.
+	lock sync.Mutex
+	// inflight is the evaluation in progress, whose result is shared by
+	// the concurrent calls to Get.
+	inflight *mergeCall[V]
+
+	mergeFn     func(context.Context, []Result[T]) (V, string, error)
+	delegates   []ValueCtx[T]
+	parallelism int
+
+	// changes is signaled when an evaluation changes the result.
+	changes notifier
+
+	// cache and result are only accessed by the evaluation in progress.
+	cache  []Result[T]
+	result Result[V]
 }
 
-func (c *listMerger[T, V]) prepareResultsLocked() []Result[T] {
+// mergeCall is an evaluation of a listMerger.
+type mergeCall[V any] struct {
+	done   chan struct{}
+	result Result[V]
+	// cancelled is true if the evaluation failed because the context of
+	// its caller was done, in which case the result isn't shared.
+	cancelled bool
+}
+
+func (c *listMerger[T, V]) prepareResultsLocked(ctx context.Context) ([]Result[T], error) {
 	cacheResults := make([]Result[T], len(c.delegates))
 	ch := make(chan struct {
 		int
 		Result[T]
 	}, len(c.delegates))
+	workers := c.parallelism
+	if workers <= 0 || workers > len(c.delegates) {
+		workers = len(c.delegates)
+	}
+	indexes := make(chan int, len(c.delegates))
 	for i := range c.delegates {
-		go func(index int) {
-			value, etag, err := c.delegates[index].Get()
-			ch <- struct {
-				int
-				Result[T]
-			}{index, Result[T]{Value: value, Etag: etag, Err: err}}
-		}(i)
+		indexes <- i
+	}
+	close(indexes)
+	for w := 0; w < workers; w++ {
+		go func() {
+			for index := range indexes {
+				var value T
+				var etag string
+				err := ctx.Err()
+				if err == nil {
+					value, etag, err = c.delegates[index].Get(ctx)
+				}
+				ch <- struct {
+					int
+					Result[T]
+				}{index, Result[T]{Value: value, Etag: etag, Err: err}}
+			}
+		}()
 	}
 	for i := 0; i < len(c.delegates); i++ {
-		res := <-ch
-		cacheResults[res.int] = res.Result
+		select {
+		case res := <-ch:
+			cacheResults[res.int] = res.Result
+		case <-ctx.Done():
+			// The remaining delegates write to the buffered channel
+			// and are discarded.
+			return nil, ctx.Err()
+		}
 	}
-	return cacheResults
+	return cacheResults, nil
 }
 
 func (c *listMerger[T, V]) needsRunningLocked(results []Result[T]) bool {
@@ -199,17 +289,71 @@ func (c *listMerger[T, V]) needsRunningLocked(results []Result[T]) bool {
 	return false
 }
 
-func (c *listMerger[T, V]) Get() (V, string, error) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	cacheResults := c.prepareResultsLocked()
+func (c *listMerger[T, V]) Get(ctx context.Context) (V, string, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			var zero V
+			return zero, "", err
+		}
+		c.lock.Lock()
+		call := c.inflight
+		if call == nil {
+			call = &mergeCall[V]{done: make(chan struct{})}
+			c.inflight = call
+			c.lock.Unlock()
+			c.evaluate(ctx, call)
+			return call.result.Get()
+		}
+		c.lock.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			var zero V
+			return zero, "", ctx.Err()
+		}
+		if !call.cancelled {
+			return call.result.Get()
+		}
+		// The context of the caller running the evaluation was done,
+		// try again.
+	}
+}
+
+// evaluate runs the call, which must be the one in flight.
+func (c *listMerger[T, V]) evaluate(ctx context.Context, call *mergeCall[V]) {
+	changed := false
+	defer func() {
+		c.lock.Lock()
+		c.inflight = nil
+		c.lock.Unlock()
+		close(call.done)
+		if changed {
+			c.changes.notify()
+		}
+	}()
+	cacheResults, err := c.prepareResultsLocked(ctx)
+	if err != nil {
+		call.result.Err = err
+		call.cancelled = true
+		return
+	}
 	if c.needsRunningLocked(cacheResults) {
+		evaluated := c.cache != nil
+		previous := c.result
 		c.cache = cacheResults
-		c.result.Value, c.result.Etag, c.result.Err = c.mergeFn(c.cache)
+		c.result.Value, c.result.Etag, c.result.Err = c.mergeFn(ctx, c.cache)
+		changed = evaluated && (c.result.Etag != previous.Etag || (c.result.Err == nil) != (previous.Err == nil))
 	}
-	return c.result.Value, c.result.Etag, c.result.Err
+	call.result = c.result
+	call.cancelled = call.result.Err != nil && ctx.Err() != nil
 }
 
+// Subscribe returns a channel signaled when an evaluation of the merge
+// returns a different etag than the previous evaluation, or starts or
+// stops failing.
+func (c *listMerger[T, V]) Subscribe() (<-chan struct{}, func()) { return c.changes.subscribe() }
+
 // Transform the result of another cached value. The transformFn will only be called
 // if the source has updated, otherwise, the result will be returned.
 //
@@ -253,16 +397,24 @@ type Replaceable[T any] interface {
 }
 
 // Atomic wraps a Value[T] as an atomic value that can be replaced. It implements
-// Replaceable[T].
+// Replaceable[T] and Subscribable.
 type Atomic[T any] struct {
-	value atomic.Pointer[Value[T]]
+	value   atomic.Pointer[Value[T]]
+	changes notifier
 }
 
 var _ Replaceable[[]byte] = &Atomic[[]byte]{}
+var _ Subscribable = &Atomic[[]byte]{}
 
-func (x *Atomic[T]) Store(val Value[T])      { x.value.Store(&val) }
+func (x *Atomic[T]) Store(val Value[T]) {
+	x.value.Store(&val)
+	x.changes.notify()
+}
 func (x *Atomic[T]) Get() (T, string, error) { return (*x.value.Load()).Get() }
 
+// Subscribe returns a channel signaled after every Store.
+func (x *Atomic[T]) Subscribe() (<-chan struct{}, func()) { return x.changes.subscribe() }
+
 // LastSuccess calls Value[T].Get(), but hides errors by returning the last
 // success if there has been any.
 type LastSuccess[T any] struct {
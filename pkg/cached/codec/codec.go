@@ -0,0 +1,61 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package codec provides [cached.Codec] implementations for OpenAPI
+// specs, to be used with [cached.Persistent].
+package codec
+
+import (
+	"encoding/json"
+
+	"k8s.io/kube-openapi/pkg/cached"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// Swagger encodes OpenAPI v2 specs as JSON.
+type Swagger struct{}
+
+var _ cached.Codec[*spec.Swagger] = Swagger{}
+
+func (Swagger) Encode(swagger *spec.Swagger) ([]byte, error) {
+	return json.Marshal(swagger)
+}
+
+func (Swagger) Decode(data []byte) (*spec.Swagger, error) {
+	swagger := &spec.Swagger{}
+	if err := swagger.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return swagger, nil
+}
+
+// OpenAPIV3 encodes OpenAPI v3 specs as JSON.
+type OpenAPIV3 struct{}
+
+var _ cached.Codec[*spec3.OpenAPI] = OpenAPIV3{}
+
+func (OpenAPIV3) Encode(openapi *spec3.OpenAPI) ([]byte, error) {
+	return json.Marshal(openapi)
+}
+
+func (OpenAPIV3) Decode(data []byte) (*spec3.OpenAPI, error) {
+	openapi := &spec3.OpenAPI{}
+	if err := openapi.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return openapi, nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package codec
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/kube-openapi/pkg/cached"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func roundTrip[T any](t *testing.T, codec cached.Codec[T], value T) T {
+	t.Helper()
+	dir := t.TempDir()
+	if _, _, err := cached.Persistent(cached.Static(value, "etag"), dir, codec).Get(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	restored, etag, err := cached.Persistent(cached.Func(func() (T, string, error) {
+		var zero T
+		return zero, "", errors.New("source error")
+	}), dir, codec).Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if etag != "etag" {
+		t.Fatalf("expected etag %q, got %q", "etag", etag)
+	}
+	return restored
+}
+
+func TestSwagger(t *testing.T) {
+	swagger := &spec.Swagger{}
+	if err := swagger.UnmarshalJSON([]byte(`{
+  "swagger": "2.0",
+  "info": {"title": "Kubernetes", "version": "v1.30.0"},
+  "paths": {"/version": {"get": {"responses": {"200": {"description": "OK", "schema": {"$ref": "#/definitions/Info"}}}}}},
+  "definitions": {"Info": {"type": "object", "properties": {"major": {"type": "string"}}}}
+  }`)); err != nil {
+		t.Fatal(err)
+	}
+	if restored := roundTrip[*spec.Swagger](t, Swagger{}, swagger); !reflect.DeepEqual(restored, swagger) {
+		t.Errorf("expected %v, got %v", swagger, restored)
+	}
+}
+
+func TestOpenAPIV3(t *testing.T) {
+	openapi := &spec3.OpenAPI{}
+	if err := openapi.UnmarshalJSON([]byte(`{
+  "openapi": "3.0.0",
+  "info": {"title": "Kubernetes", "version": "v1.30.0"},
+  "paths": {"/version": {"get": {"responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Info"}}}}}}}},
+  "components": {"schemas": {"Info": {"type": "object", "properties": {"major": {"type": "string"}}}}}
+  }`)); err != nil {
+		t.Fatal(err)
+	}
+	if restored := roundTrip[*spec3.OpenAPI](t, OpenAPIV3{}, openapi); !reflect.DeepEqual(restored, openapi) {
+		t.Errorf("expected %v, got %v", openapi, restored)
+	}
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cached
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// ValueCtx is the same as Value[T], except that Get takes a context, which
+// lets the caller cancel the evaluation, give it a deadline, or carry
+// values such as trace spans down to the delegates.
+//
+// Every cache of this package has a variant returning a ValueCtx[T], and
+// WithContext and WithoutContext adapt the two interfaces.
+type ValueCtx[T any] interface {
+	Get(ctx context.Context) (value T, etag string, err error)
+}
+
+// WithContext adapts a Value[T] to a ValueCtx[T]. The context is ignored.
+func WithContext[T any](v Value[T]) ValueCtx[T] {
+	switch w := v.(type) {
+	case withoutContext[T]:
+		return w.value
+	case subscribableWithoutContext[T]:
+		return w.value
+	case Subscribable:
+		return subscribableWithContext[T]{withContext[T]{value: v}, w}
+	}
+	return withContext[T]{value: v}
+}
+
+type withContext[T any] struct {
+	value Value[T]
+}
+
+func (c withContext[T]) Get(context.Context) (T, string, error) {
+	return c.value.Get()
+}
+
+// WithoutContext adapts a ValueCtx[T] to a Value[T]. The value is
+// evaluated with context.Background().
+func WithoutContext[T any](v ValueCtx[T]) Value[T] {
+	switch w := v.(type) {
+	case withContext[T]:
+		return w.value
+	case subscribableWithContext[T]:
+		return w.value
+	case Subscribable:
+		return subscribableWithoutContext[T]{withoutContext[T]{value: v}, w}
+	}
+	return withoutContext[T]{value: v}
+}
+
+type withoutContext[T any] struct {
+	value ValueCtx[T]
+}
+
+func (c withoutContext[T]) Get() (T, string, error) {
+	return c.value.Get(context.Background())
+}
+
+// FuncCtx wraps a (thread-safe) function as a ValueCtx[T].
+func FuncCtx[T any](fn func(ctx context.Context) (T, string, error)) ValueCtx[T] {
+	return valueFuncCtx[T](fn)
+}
+
+type valueFuncCtx[T any] func(context.Context) (T, string, error)
+
+func (c valueFuncCtx[T]) Get(ctx context.Context) (T, string, error) {
+	return c(ctx)
+}
+
+// MergeCtx is the same as Merge, with the context of Get passed to the
+// caches and to the merge function.
+func MergeCtx[K comparable, T, V any](mergeFn func(ctx context.Context, results map[K]Result[T]) (V, string, error), caches map[K]ValueCtx[T], opts ...MergeOption) ValueCtx[V] {
+	list := make([]ValueCtx[T], 0, len(caches))
+
+	// map from index to key
+	indexes := make(map[int]K, len(caches))
+	i := 0
+	for k := range caches {
+		list = append(list, caches[k])
+		indexes[i] = k
+		i++
+	}
+
+	return MergeListCtx(func(ctx context.Context, results []Result[T]) (V, string, error) {
+		if len(results) != len(indexes) {
+			panic(fmt.Errorf("invalid result length %d, expected %d", len(results), len(indexes)))
+		}
+		m := make(map[K]Result[T], len(results))
+		for i := range results {
+			m[indexes[i]] = results[i]
+		}
+		return mergeFn(ctx, m)
+	}, list, opts...)
+}
+
+// MergeListCtx is the same as MergeList, with the context of Get passed
+// to the delegates and to the merge function. If the context is done
+// while waiting for the delegates, Get returns the error of the context
+// and the cached result is left untouched.
+func MergeListCtx[T, V any](mergeFn func(ctx context.Context, results []Result[T]) (V, string, error), delegates []ValueCtx[T], opts ...MergeOption) ValueCtx[V] {
+	options := mergeOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &listMerger[T, V]{
+		mergeFn:     mergeFn,
+		delegates:   delegates,
+		parallelism: options.parallelism,
+	}
+}
+
+// TransformCtx is the same as Transform, with the context of Get passed to
+// the source and to the transform function.
+func TransformCtx[T, V any](transformerFn func(ctx context.Context, value T, etag string, err error) (V, string, error), source ValueCtx[T]) ValueCtx[V] {
+	return MergeListCtx(func(ctx context.Context, delegates []Result[T]) (V, string, error) {
+		if len(delegates) != 1 {
+			panic(fmt.Errorf("invalid cache for transformer cache: %v", delegates))
+		}
+		return transformerFn(ctx, delegates[0].Value, delegates[0].Etag, delegates[0].Err)
+	}, []ValueCtx[T]{source})
+}
+
+// OnceCtx calls ValueCtx[T].Get() lazily and only once, even in case of an
+// error result, unless the error happened because the context of the call
+// was done, in which case the next call tries again.
+func OnceCtx[T any](d ValueCtx[T]) ValueCtx[T] {
+	return &onceCtx[T]{
+		lock: make(chan struct{}, 1),
+		data: d,
+	}
+}
+
+type onceCtx[T any] struct {
+	// lock is a channel rather than a mutex so that waiting for it can
+	// be cancelled.
+	lock   chan struct{}
+	done   bool
+	data   ValueCtx[T]
+	result Result[T]
+}
+
+func (c *onceCtx[T]) Get(ctx context.Context) (T, string, error) {
+	// Check the context first, select picks randomly when both are ready.
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, "", err
+	}
+	select {
+	case c.lock <- struct{}{}:
+	case <-ctx.Done():
+		var zero T
+		return zero, "", ctx.Err()
+	}
+	defer func() { <-c.lock }()
+	if !c.done {
+		value, etag, err := c.data.Get(ctx)
+		if err != nil && ctx.Err() != nil {
+			return value, etag, err
+		}
+		c.result = Result[T]{Value: value, Etag: etag, Err: err}
+		c.done = true
+	}
+	return c.result.Get()
+}
+
+// ReplaceableCtx extends the ValueCtx[T] interface with the ability to
+// change the underlying ValueCtx[T] after construction.
+type ReplaceableCtx[T any] interface {
+	ValueCtx[T]
+	Store(ValueCtx[T])
+}
+
+// AtomicCtx wraps a ValueCtx[T] as an atomic value that can be replaced.
+// It implements ReplaceableCtx[T] and Subscribable.
+type AtomicCtx[T any] struct {
+	value   atomic.Pointer[ValueCtx[T]]
+	changes notifier
+}
+
+var _ ReplaceableCtx[[]byte] = &AtomicCtx[[]byte]{}
+var _ Subscribable = &AtomicCtx[[]byte]{}
+
+func (x *AtomicCtx[T]) Store(val ValueCtx[T]) {
+	x.value.Store(&val)
+	x.changes.notify()
+}
+
+// Subscribe returns a channel signaled after every Store.
+func (x *AtomicCtx[T]) Subscribe() (<-chan struct{}, func()) { return x.changes.subscribe() }
+
+func (x *AtomicCtx[T]) Get(ctx context.Context) (T, string, error) {
+	return (*x.value.Load()).Get(ctx)
+}
+
+// LastSuccessCtx calls ValueCtx[T].Get(), but hides errors by returning
+// the last success if there has been any.
+type LastSuccessCtx[T any] struct {
+	AtomicCtx[T]
+	success atomic.Pointer[Result[T]]
+}
+
+var _ ReplaceableCtx[[]byte] = &LastSuccessCtx[[]byte]{}
+
+func (c *LastSuccessCtx[T]) Get(ctx context.Context) (T, string, error) {
+	success := c.success.Load()
+	value, etag, err := c.AtomicCtx.Get(ctx)
+	if err == nil {
+		if success == nil {
+			c.success.CompareAndSwap(nil, &Result[T]{Value: value, Etag: etag, Err: err})
+		}
+		return value, etag, err
+	}
+
+	if success != nil {
+		return success.Value, success.Etag, success.Err
+	}
+
+	return value, etag, err
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cached_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"k8s.io/kube-openapi/pkg/cached"
+)
+
+type ctxKey struct{}
+
+func TestContextAdapters(t *testing.T) {
+	source := cached.Static("value", "etag")
+	if got := cached.WithoutContext(cached.WithContext(source)); got != source {
+		t.Fatalf("expected the adapters to unwrap each other")
+	}
+
+	valueCtx := cached.FuncCtx(func(ctx context.Context) (string, string, error) {
+		if ctx == nil || ctx.Err() != nil {
+			return "", "", errors.New("invalid context")
+		}
+		return "value", "etag", nil
+	})
+	if got, ok := cached.WithContext(cached.WithoutContext(valueCtx)).(cached.ValueCtx[string]); !ok || got == nil {
+		t.Fatalf("expected a ValueCtx")
+	}
+	value, etag, err := cached.WithoutContext(valueCtx).Get()
+	if err != nil || value != "value" || etag != "etag" {
+		t.Fatalf("unexpected result: %v %v %v", value, etag, err)
+	}
+}
+
+func TestTransformCtx(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	sourceCount, transformCount := 0, 0
+	source := cached.FuncCtx(func(ctx context.Context) (string, string, error) {
+		sourceCount += 1
+		return fmt.Sprintf("source-%v", ctx.Value(ctxKey{})), "source", nil
+	})
+	transformer := cached.TransformCtx(func(ctx context.Context, value string, etag string, err error) (string, string, error) {
+		transformCount += 1
+		if err != nil {
+			return "", "", err
+		}
+		return fmt.Sprintf("%s-%v", value, ctx.Value(ctxKey{})), "transformed " + etag, nil
+	}, source)
+	for i := 0; i < 2; i++ {
+		value, etag, err := transformer.Get(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if value != "source-request-request" || etag != "transformed source" {
+			t.Fatalf("unexpected result: %v %v", value, etag)
+		}
+	}
+	if sourceCount != 2 || transformCount != 1 {
+		t.Fatalf("expected source called twice and transform once, called: %v and %v", sourceCount, transformCount)
+	}
+}
+
+func TestMergeCtx(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	merger := cached.MergeCtx(func(ctx context.Context, results map[string]cached.Result[string]) (string, string, error) {
+		if len(results) != 2 {
+			return "", "", fmt.Errorf("unexpected results: %v", results)
+		}
+		return fmt.Sprintf("%s+%s-%v", results["a"].Value, results["b"].Value, ctx.Value(ctxKey{})), "merged", nil
+	}, map[string]cached.ValueCtx[string]{
+		"a": cached.WithContext(cached.Static("a", "a")),
+		"b": cached.FuncCtx(func(ctx context.Context) (string, string, error) {
+			return fmt.Sprintf("b-%v", ctx.Value(ctxKey{})), "b", nil
+		}),
+	})
+	value, etag, err := merger.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "a+b-request-request" || etag != "merged" {
+		t.Fatalf("unexpected result: %v %v", value, etag)
+	}
+}
+
+func TestMergeListCtxCancel(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	slow := cached.FuncCtx(func(ctx context.Context) (string, string, error) {
+		started <- struct{}{}
+		<-release
+		return "slow", "slow", nil
+	})
+	count := 0
+	merger := cached.MergeListCtx(func(_ context.Context, results []cached.Result[string]) (string, string, error) {
+		count += 1
+		return results[0].Value, results[0].Etag, results[0].Err
+	}, []cached.ValueCtx[string]{slow})
+
+	// The delegate doesn't look at the context, but Get stops waiting for
+	// it when the context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, _, err := merger.Get(ctx)
+		done <- err
+	}()
+	<-started
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected merge function not to be called, called: %v", count)
+	}
+
+	close(release)
+	value, _, err := merger.Get(context.Background())
+	<-started
+	if err != nil || value != "slow" {
+		t.Fatalf("unexpected result: %v %v", value, err)
+	}
+	if count != 1 {
+		t.Fatalf("expected merge function called once, called: %v", count)
+	}
+}
+
+func TestOnceCtx(t *testing.T) {
+	count := 0
+	once := cached.OnceCtx(cached.FuncCtx(func(ctx context.Context) (string, string, error) {
+		if err := ctx.Err(); err != nil {
+			return "", "", err
+		}
+		count += 1
+		return "", "", errors.New("source error")
+	}))
+
+	// Errors caused by the context are not cached.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := once.Get(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got: %v", err)
+	}
+
+	// Other errors are.
+	for i := 0; i < 2; i++ {
+		if _, _, err := once.Get(context.Background()); err == nil {
+			t.Fatalf("expected error, found none")
+		}
+	}
+	if count != 1 {
+		t.Fatalf("expected function called once, called: %v", count)
+	}
+}
+
+func TestLastSuccessCtx(t *testing.T) {
+	var c cached.LastSuccessCtx[string]
+	c.Store(cached.WithContext(cached.Static("first", "1")))
+	if value, _, err := c.Get(context.Background()); err != nil || value != "first" {
+		t.Fatalf("unexpected result: %v %v", value, err)
+	}
+	c.Store(cached.FuncCtx(func(ctx context.Context) (string, string, error) {
+		return "", "", ctx.Err()
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if value, etag, err := c.Get(ctx); err != nil || value != "first" || etag != "1" {
+		t.Fatalf("expected the last success, got: %v %v %v", value, etag, err)
+	}
+
+	var a cached.AtomicCtx[string]
+	a.Store(cached.WithContext(cached.Static("atomic", "a")))
+	if value, _, err := a.Get(context.Background()); err != nil || value != "atomic" {
+		t.Fatalf("unexpected result: %v %v", value, err)
+	}
+}
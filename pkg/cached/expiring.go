@@ -0,0 +1,177 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cached
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// defaultJitter is the default jitter factor of Refreshing.
+const defaultJitter = 0.1
+
+// TimeOption configures Expiring and Refreshing.
+type TimeOption func(*timeOptions)
+
+type timeOptions struct {
+	clock  clock.Clock
+	jitter float64
+}
+
+func newTimeOptions(opts []TimeOption) timeOptions {
+	options := timeOptions{clock: clock.RealClock{}, jitter: defaultJitter}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithClock sets the clock used to measure time, for tests.
+func WithClock(c clock.Clock) TimeOption {
+	return func(o *timeOptions) {
+		o.clock = c
+	}
+}
+
+// WithJitter sets the jitter factor of Refreshing: every interval is
+// extended by a random duration up to factor times the interval. The
+// default is 0.1. It's ignored by Expiring.
+func WithJitter(factor float64) TimeOption {
+	return func(o *timeOptions) {
+		o.jitter = factor
+	}
+}
+
+// Expiring caches the result of value for the given TTL. The first call
+// after the TTL has elapsed calls value again. Errors aren't cached.
+func Expiring[T any](ttl time.Duration, value Value[T], opts ...TimeOption) Value[T] {
+	return &expiring[T]{
+		ttl:   ttl,
+		value: value,
+		clock: newTimeOptions(opts).clock,
+	}
+}
+
+type expiring[T any] struct {
+	ttl   time.Duration
+	value Value[T]
+	clock clock.PassiveClock
+
+	lock    sync.Mutex
+	result  *Result[T]
+	expires time.Time
+}
+
+func (c *expiring[T]) Get() (T, string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.result != nil && c.clock.Now().Before(c.expires) {
+		return c.result.Get()
+	}
+	value, etag, err := c.value.Get()
+	if err != nil {
+		c.result = nil
+		return value, etag, err
+	}
+	c.result = &Result[T]{Value: value, Etag: etag}
+	c.expires = c.clock.Now().Add(c.ttl)
+	return c.result.Get()
+}
+
+// RefreshingValue is a Value[T] refreshed in the background, see
+// Refreshing.
+type RefreshingValue[T any] struct {
+	interval time.Duration
+	value    Value[T]
+	options  timeOptions
+
+	start sync.Once
+	stop  chan struct{}
+	// stopOnce protects stop from being closed twice.
+	stopOnce sync.Once
+
+	lock sync.Mutex
+	// success is the last successful result, err the error of the last
+	// call if it failed.
+	success *Result[T]
+	err     error
+}
+
+// Refreshing calls value every interval, extended by a random jitter, in
+// a background goroutine started by the first call to Get, which calls
+// value synchronously. Get returns the last successful result without
+// waiting, or the last error if there has never been a success. Stop must
+// be called to stop the goroutine.
+func Refreshing[T any](interval time.Duration, value Value[T], opts ...TimeOption) *RefreshingValue[T] {
+	return &RefreshingValue[T]{
+		interval: interval,
+		value:    value,
+		options:  newTimeOptions(opts),
+		stop:     make(chan struct{}),
+	}
+}
+
+var _ Value[[]byte] = &RefreshingValue[[]byte]{}
+
+func (c *RefreshingValue[T]) Get() (T, string, error) {
+	c.start.Do(func() {
+		c.refresh()
+		go c.run()
+	})
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.success != nil {
+		return c.success.Get()
+	}
+	var zero T
+	return zero, "", c.err
+}
+
+// Stop stops refreshing the value. Get keeps returning the last result.
+func (c *RefreshingValue[T]) Stop() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+func (c *RefreshingValue[T]) refresh() {
+	value, etag, err := c.value.Get()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.err = err
+	if err == nil {
+		c.success = &Result[T]{Value: value, Etag: etag}
+	}
+}
+
+func (c *RefreshingValue[T]) run() {
+	for {
+		interval := c.interval
+		if c.options.jitter > 0 {
+			interval += time.Duration(rand.Float64() * c.options.jitter * float64(c.interval))
+		}
+		timer := c.options.clock.NewTimer(interval)
+		select {
+		case <-c.stop:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		c.refresh()
+	}
+}
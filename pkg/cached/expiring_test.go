@@ -0,0 +1,172 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cached_test
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/kube-openapi/pkg/cached"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestExpiring(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	count := 0
+	source := cached.Func(func() (string, string, error) {
+		count += 1
+		if count == 3 {
+			return "", "", errors.New("source error")
+		}
+		return fmt.Sprintf("value-%d", count), fmt.Sprint(count), nil
+	})
+	expiring := cached.Expiring(time.Minute, source, cached.WithClock(fakeClock))
+
+	expectResult(t, expiring, "value-1", "1")
+	fakeClock.Step(59 * time.Second)
+	expectResult(t, expiring, "value-1", "1")
+	if count != 1 {
+		t.Fatalf("Expected function called once, called: %v", count)
+	}
+
+	fakeClock.Step(time.Second)
+	expectResult(t, expiring, "value-2", "2")
+	if count != 2 {
+		t.Fatalf("Expected function called twice, called: %v", count)
+	}
+
+	// Errors are not cached.
+	fakeClock.Step(time.Minute)
+	if _, _, err := expiring.Get(); err == nil {
+		t.Fatalf("expected error, found none")
+	}
+	expectResult(t, expiring, "value-4", "4")
+	expectResult(t, expiring, "value-4", "4")
+	if count != 4 {
+		t.Fatalf("Expected function called 4x, called: %v", count)
+	}
+}
+
+// stepWhenWaiting waits for the refreshing goroutine to wait on the clock
+// and steps it.
+func stepWhenWaiting(t *testing.T, fakeClock *testingclock.FakeClock, d time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for !fakeClock.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the refresh to wait on the clock")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	fakeClock.Step(d)
+}
+
+// waitForCount waits until count reaches n.
+func waitForCount(t *testing.T, count *atomic.Int32, n int32) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for count.Load() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d calls, got %d", n, count.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRefreshing(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	var count atomic.Int32
+	var refreshed atomic.Int32
+	source := cached.Func(func() (string, string, error) {
+		defer refreshed.Add(1)
+		n := count.Add(1)
+		if n == 3 {
+			return "", "", errors.New("source error")
+		}
+		return fmt.Sprintf("value-%d", n), fmt.Sprint(n), nil
+	})
+	refreshing := cached.Refreshing(time.Minute, source, cached.WithClock(fakeClock), cached.WithJitter(0))
+	defer refreshing.Stop()
+
+	// The first call is synchronous.
+	expectResult(t, refreshing, "value-1", "1")
+	expectResult(t, refreshing, "value-1", "1")
+	if got := count.Load(); got != 1 {
+		t.Fatalf("Expected function called once, called: %v", got)
+	}
+
+	stepWhenWaiting(t, fakeClock, time.Minute)
+	waitForCount(t, &refreshed, 2)
+	expectResult(t, refreshing, "value-2", "2")
+
+	// The last success is served when a refresh fails.
+	stepWhenWaiting(t, fakeClock, time.Minute)
+	waitForCount(t, &refreshed, 3)
+	expectResult(t, refreshing, "value-2", "2")
+
+	stepWhenWaiting(t, fakeClock, time.Minute)
+	waitForCount(t, &refreshed, 4)
+	expectResult(t, refreshing, "value-4", "4")
+
+	// No refresh happens after Stop.
+	refreshing.Stop()
+	deadline := time.Now().Add(30 * time.Second)
+	for fakeClock.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the refresh to stop")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	fakeClock.Step(time.Hour)
+	expectResult(t, refreshing, "value-4", "4")
+	if got := count.Load(); got != 4 {
+		t.Fatalf("Expected function called 4x, called: %v", got)
+	}
+}
+
+func TestRefreshingJitter(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	var count atomic.Int32
+	refreshing := cached.Refreshing(time.Minute, cached.Func(func() (string, string, error) {
+		n := count.Add(1)
+		return fmt.Sprintf("value-%d", n), fmt.Sprint(n), nil
+	}), cached.WithClock(fakeClock), cached.WithJitter(0.5))
+	defer refreshing.Stop()
+
+	expectResult(t, refreshing, "value-1", "1")
+	// The interval is at least a minute, and at most a minute and a half.
+	stepWhenWaiting(t, fakeClock, time.Minute-time.Nanosecond)
+	if got := count.Load(); got != 1 {
+		t.Fatalf("Expected no refresh before the interval, got %v calls", got)
+	}
+	fakeClock.Step(30 * time.Second)
+	waitForCount(t, &count, 2)
+}
+
+func TestRefreshingError(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	refreshing := cached.Refreshing(time.Minute, cached.Func(func() (string, string, error) {
+		return "", "", errors.New("source error")
+	}), cached.WithClock(fakeClock))
+	defer refreshing.Stop()
+	if _, _, err := refreshing.Get(); err == nil {
+		t.Fatalf("expected error, found none")
+	}
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cached_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/kube-openapi/pkg/cached"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func TestMergeListParallelism(t *testing.T) {
+	for _, parallelism := range []int{0, 1, 3, 10} {
+		t.Run(fmt.Sprintf("parallelism=%d", parallelism), func(t *testing.T) {
+			var running, maxRunning atomic.Int32
+			delegates := []cached.Value[int]{}
+			for i := 0; i < 10; i++ {
+				i := i
+				delegates = append(delegates, cached.Func(func() (int, string, error) {
+					n := running.Add(1)
+					defer running.Add(-1)
+					for {
+						m := maxRunning.Load()
+						if n <= m || maxRunning.CompareAndSwap(m, n) {
+							break
+						}
+					}
+					time.Sleep(5 * time.Millisecond)
+					return i, fmt.Sprint(i), nil
+				}))
+			}
+			merger := cached.MergeList(func(results []cached.Result[int]) (int, string, error) {
+				sum := 0
+				for _, r := range results {
+					sum += r.Value
+				}
+				return sum, fmt.Sprint(sum), nil
+			}, delegates, cached.WithParallelism(parallelism))
+			sum, _, err := merger.Get()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sum != 45 {
+				t.Fatalf("expected 45, got %v", sum)
+			}
+			limit := int32(parallelism)
+			if parallelism <= 0 {
+				limit = int32(len(delegates))
+			}
+			if got := maxRunning.Load(); got > limit {
+				t.Fatalf("expected at most %d delegates running concurrently, got %d", limit, got)
+			}
+			if parallelism == 1 && maxRunning.Load() != 1 {
+				t.Fatalf("expected delegates to run serially, got %d concurrently", maxRunning.Load())
+			}
+		})
+	}
+}
+
+func TestMergeListSingleflight(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	merger := cached.MergeList(func(results []cached.Result[string]) (string, string, error) {
+		return results[0].Value, results[0].Etag, results[0].Err
+	}, []cached.Value[string]{cached.Func(func() (string, string, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return "value", "etag", nil
+	})})
+
+	var wg sync.WaitGroup
+	results := make(chan string, 10)
+	get := func() {
+		defer wg.Done()
+		value, _, err := merger.Get()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		results <- value
+	}
+	wg.Add(1)
+	go get()
+	<-started
+	for i := 0; i < 9; i++ {
+		wg.Add(1)
+		go get()
+	}
+	// Give the other calls some time to wait for the one in flight.
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+	for value := range results {
+		if value != "value" {
+			t.Fatalf("unexpected value: %v", value)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected the delegate to be evaluated once, got %d", got)
+	}
+}
+
+func TestMergeListCtxSingleflightCancelled(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	merger := cached.MergeListCtx(func(_ context.Context, results []cached.Result[string]) (string, string, error) {
+		return results[0].Value, results[0].Etag, results[0].Err
+	}, []cached.ValueCtx[string]{cached.FuncCtx(func(ctx context.Context) (string, string, error) {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return "value", "etag", nil
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		}
+	})})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, _, err := merger.Get(ctx)
+		first <- err
+	}()
+	<-started
+	second := make(chan error)
+	go func() {
+		_, _, err := merger.Get(context.Background())
+		second <- err
+	}()
+	// The cancellation of the first call doesn't fail the second one, which
+	// evaluates the delegates again.
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got: %v", err)
+	}
+	<-started
+	close(release)
+	if err := <-second; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// loadKubeSpecs loads the Kubernetes specs used as fixtures by the
+// aggregator and the converter.
+func loadKubeSpecs(b *testing.B) []*spec.Swagger {
+	files := []string{
+		"../../test/integration/testdata/aggregator/openapi-0.json",
+		"../../test/integration/testdata/aggregator/openapi-1.json",
+		"../../test/integration/testdata/aggregator/openapi-2.json",
+	}
+	matches, err := filepath.Glob("../openapiconv/testdata_generated_from_k8s/v2_*.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	files = append(files, matches...)
+	specs := []*spec.Swagger{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			b.Fatal(err)
+		}
+		s := &spec.Swagger{}
+		if err := json.Unmarshal(data, s); err != nil {
+			b.Fatal(err)
+		}
+		specs = append(specs, s)
+	}
+	return specs
+}
+
+// BenchmarkMergeListKubeSpecs serializes the Kubernetes specs in the
+// delegates of a MergeList, with various parallelisms.
+func BenchmarkMergeListKubeSpecs(b *testing.B) {
+	specs := loadKubeSpecs(b)
+	for _, parallelism := range []int{1, 2, 4, 0} {
+		b.Run(fmt.Sprintf("parallelism=%d", parallelism), func(b *testing.B) {
+			var generation atomic.Int64
+			delegates := []cached.Value[[]byte]{}
+			for _, s := range specs {
+				// Every generation changes the etag, so the delegates are
+				// serialized on every Get.
+				delegates = append(delegates, cached.Func(func() ([]byte, string, error) {
+					data, err := json.Marshal(s)
+					return data, fmt.Sprint(generation.Load()), err
+				}))
+			}
+			merger := cached.MergeList(func(results []cached.Result[[]byte]) (int, string, error) {
+				size := 0
+				for _, r := range results {
+					if r.Err != nil {
+						return 0, "", r.Err
+					}
+					size += len(r.Value)
+				}
+				return size, fmt.Sprint(generation.Load()), nil
+			}, delegates, cached.WithParallelism(parallelism))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				generation.Add(1)
+				if _, _, err := merger.Get(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkMergeListKubeSpecsConcurrentGets shows the deduplication of
+// concurrent calls to Get.
+func BenchmarkMergeListKubeSpecsConcurrentGets(b *testing.B) {
+	specs := loadKubeSpecs(b)
+	var generation atomic.Int64
+	delegates := []cached.Value[[]byte]{}
+	for _, s := range specs {
+		delegates = append(delegates, cached.Func(func() ([]byte, string, error) {
+			data, err := json.Marshal(s)
+			return data, fmt.Sprint(generation.Load()), err
+		}))
+	}
+	merger := cached.MergeList(func(results []cached.Result[[]byte]) (int, string, error) {
+		return len(results), fmt.Sprint(generation.Load()), nil
+	}, delegates)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			generation.Add(1)
+			if _, _, err := merger.Get(); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cached
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NodeStatus is the state of a named cache, as returned by Dump.
+type NodeStatus struct {
+	// Name is the name given to Named or NamedCtx.
+	Name string `json:"name"`
+	// Etag is the etag returned by the last call to Get.
+	Etag string `json:"etag,omitempty"`
+	// LastError is the error returned by the last call to Get, if it
+	// failed.
+	LastError string `json:"lastError,omitempty"`
+	// Evaluations is the number of calls to Get.
+	Evaluations uint64 `json:"evaluations"`
+	// LastEvaluated is when the last call to Get started, and
+	// LastDuration how long it took.
+	LastEvaluated time.Time     `json:"lastEvaluated,omitzero"`
+	LastDuration  time.Duration `json:"lastDuration"`
+	// LastChanged is when a call to Get last returned a different etag
+	// or error state than the call before.
+	LastChanged time.Time `json:"lastChanged,omitzero"`
+	// Dependencies are the names of the closest named caches this cache
+	// is built from.
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+// Named records the result, time and duration of the calls to the Get
+// method of value under the given name, so that they can be inspected with
+// Dump. The name should be unique among the caches dumped together.
+func Named[T any](name string, value Value[T]) Value[T] {
+	return WithoutContext(NamedCtx(name, WithContext(value)))
+}
+
+// NamedCtx is the same as Named for a ValueCtx.
+func NamedCtx[T any](name string, value ValueCtx[T]) ValueCtx[T] {
+	n := &named[T]{node: &node{name: name}, value: value}
+	if s, ok := value.(Subscribable); ok {
+		return subscribableNamed[T]{n, s}
+	}
+	return n
+}
+
+// node is the state of a named cache.
+type node struct {
+	name string
+
+	lock        sync.Mutex
+	result      Result[struct{}]
+	evaluations uint64
+	evaluated   time.Time
+	duration    time.Duration
+	changed     time.Time
+}
+
+func (n *node) record(etag string, err error, start time.Time, duration time.Duration) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.evaluations == 0 || etag != n.result.Etag || (err == nil) != (n.result.Err == nil) {
+		n.changed = start
+	}
+	n.evaluations++
+	n.result.Etag, n.result.Err = etag, err
+	n.evaluated, n.duration = start, duration
+}
+
+func (n *node) status() NodeStatus {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	s := NodeStatus{
+		Name:          n.name,
+		Etag:          n.result.Etag,
+		Evaluations:   n.evaluations,
+		LastEvaluated: n.evaluated,
+		LastDuration:  n.duration,
+		LastChanged:   n.changed,
+	}
+	if n.result.Err != nil {
+		s.LastError = n.result.Err.Error()
+	}
+	return s
+}
+
+type named[T any] struct {
+	*node
+	value ValueCtx[T]
+}
+
+func (n *named[T]) Get(ctx context.Context) (T, string, error) {
+	start := time.Now()
+	value, etag, err := n.value.Get(ctx)
+	n.record(etag, err, start, time.Since(start))
+	return value, etag, err
+}
+
+func (n *named[T]) namedNode() *node    { return n.node }
+func (n *named[T]) dependencies() []any { return []any{n.value} }
+
+// subscribableNamed is returned by NamedCtx for Subscribable values, so
+// that they stay Subscribable.
+type subscribableNamed[T any] struct {
+	*named[T]
+	Subscribable
+}
+
+// dependent is implemented by the caches built from other caches.
+type dependent interface {
+	dependencies() []any
+}
+
+// namedCache is implemented by the caches returned by Named and NamedCtx.
+type namedCache interface {
+	dependent
+	namedNode() *node
+}
+
+func (c *listMerger[T, V]) dependencies() []any {
+	deps := make([]any, len(c.delegates))
+	for i, d := range c.delegates {
+		deps[i] = d
+	}
+	return deps
+}
+
+func (c *once[T]) dependencies() []any            { return []any{c.data} }
+func (c *onceCtx[T]) dependencies() []any         { return []any{c.data} }
+func (c withContext[T]) dependencies() []any      { return []any{c.value} }
+func (c withoutContext[T]) dependencies() []any   { return []any{c.value} }
+func (c *expiring[T]) dependencies() []any        { return []any{c.value} }
+func (c *RefreshingValue[T]) dependencies() []any { return []any{c.value} }
+func (c *persistent[T]) dependencies() []any      { return []any{c.delegate} }
+func (x *Atomic[T]) dependencies() []any          { return loadDependency(&x.value) }
+func (x *AtomicCtx[T]) dependencies() []any       { return loadDependency(&x.value) }
+
+func loadDependency[V any](p *atomic.Pointer[V]) []any {
+	if v := p.Load(); v != nil {
+		return []any{*v}
+	}
+	return nil
+}
+
+// Dump returns the status of the named caches reachable from the given
+// caches, in depth-first order. Caches that are not named are skipped,
+// the dependencies of a named cache being the closest named caches it is
+// built from. The roots can be of any Value or ValueCtx type.
+//
+// Dump doesn't call Get, the status of a cache is the one of its last
+// evaluation. A cache that hasn't been evaluated yet doesn't change while
+// it isn't evaluated, so a stale cache shows as an old LastEvaluated on a
+// node whose dependencies were evaluated more recently.
+func Dump(roots ...any) []NodeStatus {
+	d := dumper{
+		dependencies: map[*node][]*node{},
+		visited:      map[pointerKey][]*node{},
+	}
+	for _, root := range roots {
+		d.closest(root)
+	}
+	statuses := make([]NodeStatus, 0, len(d.order))
+	for _, n := range d.order {
+		s := n.status()
+		for _, dep := range d.dependencies[n] {
+			s.Dependencies = append(s.Dependencies, dep.name)
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+type pointerKey struct {
+	t reflect.Type
+	p uintptr
+}
+
+type dumper struct {
+	order        []*node
+	dependencies map[*node][]*node
+	// visited holds the closest named caches of the unnamed caches that
+	// are pointers, to avoid walking shared caches twice.
+	visited map[pointerKey][]*node
+}
+
+// closest returns the closest named caches reachable from v, including
+// v itself.
+func (d *dumper) closest(v any) []*node {
+	if n, ok := v.(namedCache); ok {
+		nn := n.namedNode()
+		if _, ok := d.dependencies[nn]; !ok {
+			// Mark the node as visited before walking the dependencies
+			// in case of a cycle.
+			d.dependencies[nn] = nil
+			d.order = append(d.order, nn)
+			d.dependencies[nn] = d.walk(n)
+		}
+		return []*node{nn}
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || rv.Kind() != reflect.Pointer {
+		return d.walk(v)
+	}
+	key := pointerKey{t: rv.Type(), p: rv.Pointer()}
+	if nodes, ok := d.visited[key]; ok {
+		return nodes
+	}
+	d.visited[key] = nil
+	nodes := d.walk(v)
+	d.visited[key] = nodes
+	return nodes
+}
+
+// walk returns the closest named caches of the dependencies of v.
+func (d *dumper) walk(v any) []*node {
+	dep, ok := v.(dependent)
+	if !ok {
+		return nil
+	}
+	var nodes []*node
+	seen := map[*node]bool{}
+	for _, c := range dep.dependencies() {
+		for _, n := range d.closest(c) {
+			if !seen[n] {
+				seen[n] = true
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cached_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/kube-openapi/pkg/cached"
+)
+
+func TestDump(t *testing.T) {
+	var source cached.Atomic[string]
+	source.Store(cached.Static("a", "a1"))
+	a := cached.Named[string]("a", &source)
+	b := cached.Named("b", cached.Static("b", "b1"))
+	merged := cached.Named("merged", cached.MergeList(func(results []cached.Result[string]) (string, string, error) {
+		value, etag := "", ""
+		for _, result := range results {
+			if result.Err != nil {
+				return "", "", result.Err
+			}
+			value += result.Value
+			etag += result.Etag
+		}
+		return value, etag, nil
+	}, []cached.Value[string]{
+		a,
+		// Unnamed caches are skipped.
+		cached.Once(cached.Transform(func(value string, etag string, err error) (string, string, error) {
+			return value, etag, err
+		}, b)),
+		// Named caches reachable twice are listed once.
+		a,
+	}))
+	root := cached.WithContext(cached.Transform(func(value string, etag string, err error) (string, string, error) {
+		return value, etag, err
+	}, merged))
+
+	names := func(statuses []cached.NodeStatus) map[string][]string {
+		ret := map[string][]string{}
+		for _, s := range statuses {
+			ret[s.Name] = s.Dependencies
+		}
+		return ret
+	}
+	want := map[string][]string{"merged": {"a", "b"}, "a": nil, "b": nil}
+	statuses := cached.Dump(root)
+	if got := names(statuses); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Dump() = %v, want %v", got, want)
+	}
+	if statuses[0].Name != "merged" {
+		t.Errorf("expected the first node to be the root, got %q", statuses[0].Name)
+	}
+	for _, s := range statuses {
+		if s.Evaluations != 0 || !s.LastEvaluated.IsZero() {
+			t.Errorf("expected %q not to be evaluated, got %+v", s.Name, s)
+		}
+	}
+
+	if _, _, err := root.Get(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	source.Store(cached.Result[string]{Err: errors.New("failed")})
+	if _, _, err := root.Get(t.Context()); err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, s := range cached.Dump(root) {
+		switch s.Name {
+		case "a":
+			// a is a delegate of merged twice.
+			if s.Evaluations != 4 || s.LastError != "failed" || s.Etag != "" {
+				t.Errorf("unexpected status %+v", s)
+			}
+		case "merged":
+			if s.Evaluations != 2 || s.LastError != "failed" || s.Etag != "" {
+				t.Errorf("unexpected status %+v", s)
+			}
+		case "b":
+			if s.Evaluations != 1 || s.LastError != "" || s.Etag != "b1" {
+				t.Errorf("unexpected status %+v", s)
+			}
+		}
+		if s.LastEvaluated.IsZero() || s.LastChanged.IsZero() {
+			t.Errorf("expected %q to be evaluated, got %+v", s.Name, s)
+		}
+	}
+}
+
+func TestDumpCycle(t *testing.T) {
+	var loop cached.Atomic[string]
+	named := cached.Named[string]("loop", &loop)
+	loop.Store(cached.Transform(func(value string, etag string, err error) (string, string, error) {
+		return value, etag, err
+	}, named))
+
+	statuses := cached.Dump(named)
+	if len(statuses) != 1 || !reflect.DeepEqual(statuses[0].Dependencies, []string{"loop"}) {
+		t.Fatalf("unexpected statuses %+v", statuses)
+	}
+}
+
+func TestNamedSubscribable(t *testing.T) {
+	var source cached.Atomic[string]
+	source.Store(cached.Static("a", "a"))
+	named := cached.Named[string]("source", &source)
+	subscribable, ok := named.(cached.Subscribable)
+	if !ok {
+		t.Fatalf("%T is not Subscribable", named)
+	}
+	changes, cancel := subscribable.Subscribe()
+	defer cancel()
+	source.Store(cached.Static("b", "b"))
+	expectSignaled(t, changes)
+	expectResult(t, named, "b", "b")
+
+	if _, ok := cached.Named("static", cached.Static("a", "a")).(cached.Subscribable); ok {
+		t.Error("expected a named static value not to be Subscribable")
+	}
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cached
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// persistentFile is the name of the file holding the value in the
+// directory of a Persistent cache.
+const persistentFile = "value"
+
+// Codec encodes values to bytes and decodes them back, to store them
+// outside of memory.
+type Codec[T any] interface {
+	Encode(value T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// Persistent stores the last successful value of the delegate, with its
+// etag, in the given directory. After a restart, the stored value is
+// returned right away while the delegate computes the fresh one in the
+// background. The fresh value replaces the stored one once it's ready, if
+// its etag differs. From then on, the delegate is called on every Get and
+// the value is stored again every time its etag changes.
+//
+// Like LastSuccess, the last successful value, stored or not, is returned
+// if the delegate fails. Errors reading or writing the directory are
+// logged and otherwise ignored.
+func Persistent[T any](delegate Value[T], dir string, codec Codec[T]) Value[T] {
+	return &persistent[T]{
+		delegate: delegate,
+		dir:      dir,
+		codec:    codec,
+	}
+}
+
+type persistent[T any] struct {
+	delegate Value[T]
+	dir      string
+	codec    Codec[T]
+
+	lock sync.Mutex
+	// loaded is true once the stored value has been read.
+	loaded bool
+	// fresh is true once the delegate returned successfully.
+	fresh bool
+	// refreshing is true while the delegate is called in the
+	// background.
+	refreshing bool
+	// success is the last successful value, stored or fresh.
+	success *Result[T]
+	// storedEtag is the etag of the value in the directory.
+	storedEtag string
+	// storing is true while a value is written to the directory, which
+	// is done without holding the lock so that Get isn't blocked on the
+	// disk.
+	storing bool
+}
+
+func (c *persistent[T]) Get() (T, string, error) {
+	c.lock.Lock()
+	if !c.loaded {
+		c.loaded = true
+		c.load()
+	}
+	if !c.fresh && c.success != nil {
+		// Serve the stored value while the delegate computes the
+		// fresh one.
+		if !c.refreshing {
+			c.refreshing = true
+			go c.refresh()
+		}
+		success := *c.success
+		c.lock.Unlock()
+		return success.Get()
+	}
+	c.lock.Unlock()
+	return c.refresh()
+}
+
+// refresh calls the delegate, records a successful result and stores it
+// if its etag changed.
+func (c *persistent[T]) refresh() (T, string, error) {
+	value, etag, err := c.delegate.Get()
+
+	c.lock.Lock()
+	c.refreshing = false
+	if err != nil {
+		defer c.lock.Unlock()
+		if c.success != nil {
+			return c.success.Get()
+		}
+		return value, etag, err
+	}
+	c.fresh = true
+	if c.success == nil || c.success.Etag != etag {
+		c.success = &Result[T]{Value: value, Etag: etag}
+	}
+	success := *c.success
+	store := !c.storing && success.Etag != c.storedEtag
+	if store {
+		c.storing = true
+	}
+	c.lock.Unlock()
+
+	if store {
+		c.storeLatest()
+	}
+	return success.Get()
+}
+
+// storeLatest stores the last successful value until the stored one is
+// the latest, since it may change while writing. Only one call runs at a
+// time, see storing, so that an older value never replaces a newer one.
+func (c *persistent[T]) storeLatest() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for c.success.Etag != c.storedEtag {
+		success := *c.success
+		c.lock.Unlock()
+		err := c.store(success.Value, success.Etag)
+		c.lock.Lock()
+		if err != nil {
+			klog.Errorf("Failed to store cached value in %s: %v", c.dir, err)
+			break
+		}
+		c.storedEtag = success.Etag
+	}
+	c.storing = false
+}
+
+// load reads the stored value, if any.
+func (c *persistent[T]) load() {
+	data, err := os.ReadFile(filepath.Join(c.dir, persistentFile))
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		klog.Errorf("Failed to read cached value in %s: %v", c.dir, err)
+		return
+	}
+	etag, data, err := decodeEtag(data)
+	if err != nil {
+		klog.Errorf("Failed to read cached value in %s: %v", c.dir, err)
+		return
+	}
+	value, err := c.codec.Decode(data)
+	if err != nil {
+		klog.Errorf("Failed to decode cached value in %s: %v", c.dir, err)
+		return
+	}
+	c.success = &Result[T]{Value: value, Etag: etag}
+	c.storedEtag = etag
+}
+
+// store atomically and durably replaces the stored value: the file is
+// synced before it replaces the previous one, and the directory after.
+func (c *persistent[T]) store(value T, etag string) error {
+	data, err := c.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.dir, persistentFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(binary.AppendUvarint(nil, uint64(len(etag))))
+	if err == nil {
+		_, err = f.WriteString(etag)
+	}
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), filepath.Join(c.dir, persistentFile)); err != nil {
+		return err
+	}
+	return syncDir(c.dir)
+}
+
+// syncDir syncs a directory, so that the files renamed into it persist.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// decodeEtag splits the content of a stored file into the etag and the
+// encoded value.
+func decodeEtag(data []byte) (string, []byte, error) {
+	length, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < length {
+		return "", nil, fmt.Errorf("invalid etag header")
+	}
+	data = data[n:]
+	return string(data[:length]), data[length:], nil
+}
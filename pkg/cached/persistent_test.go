@@ -0,0 +1,203 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cached_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/kube-openapi/pkg/cached"
+)
+
+type stringCodec struct{}
+
+func (stringCodec) Encode(value string) ([]byte, error) { return []byte(value), nil }
+func (stringCodec) Decode(data []byte) (string, error)  { return string(data), nil }
+
+func expectResult(t *testing.T, value cached.Value[string], expectedValue, expectedEtag string) {
+	t.Helper()
+	result, etag, err := value.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != expectedValue || etag != expectedEtag {
+		t.Fatalf("expected %q (%q), got %q (%q)", expectedValue, expectedEtag, result, etag)
+	}
+}
+
+// eventually waits for condition to be true.
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// storedValue returns the value stored in dir, as read after a restart.
+func storedValue(dir string) string {
+	result, _, _ := cached.Persistent(cached.Func(func() (string, string, error) {
+		return "", "", errors.New("source error")
+	}), dir, stringCodec{}).Get()
+	return result
+}
+
+func TestPersistent(t *testing.T) {
+	dir := t.TempDir()
+
+	count := 0
+	source := cached.Func(func() (string, string, error) {
+		count += 1
+		return "first", "1", nil
+	})
+	expectResult(t, cached.Persistent(source, dir, stringCodec{}), "first", "1")
+	if count != 1 {
+		t.Fatalf("Expected function called once, called: %v", count)
+	}
+
+	// After a restart, the stored value is returned while the delegate
+	// computes the fresh one.
+	var computingOnce sync.Once
+	computing := make(chan struct{})
+	release := make(chan struct{})
+	persistent := cached.Persistent(cached.Func(func() (string, string, error) {
+		computingOnce.Do(func() { close(computing) })
+		<-release
+		return "second", "2", nil
+	}), dir, stringCodec{})
+	expectResult(t, persistent, "first", "1")
+	<-computing
+	expectResult(t, persistent, "first", "1")
+	close(release)
+	eventually(t, func() bool {
+		result, _, _ := persistent.Get()
+		return result == "second"
+	})
+
+	// The fresh value is stored.
+	eventually(t, func() bool { return storedValue(dir) == "second" })
+}
+
+// blockingCodec blocks encoding until release is closed.
+type blockingCodec struct {
+	stringCodec
+	encoding chan struct{}
+	release  chan struct{}
+}
+
+func (c blockingCodec) Encode(value string) ([]byte, error) {
+	select {
+	case c.encoding <- struct{}{}:
+	default:
+	}
+	<-c.release
+	return c.stringCodec.Encode(value)
+}
+
+func TestPersistentStoreDoesNotBlock(t *testing.T) {
+	dir := t.TempDir()
+	var source cached.Atomic[string]
+	source.Store(cached.Static("first", "1"))
+	codec := blockingCodec{encoding: make(chan struct{}, 1), release: make(chan struct{})}
+	persistent := cached.Persistent[string](&source, dir, codec)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if result, _, err := persistent.Get(); err != nil || result != "first" {
+			t.Errorf("expected %q, got %q (%v)", "first", result, err)
+		}
+	}()
+	<-codec.encoding
+	// Get isn't blocked while the value is written, and the value that is
+	// the latest once the write is done is written next.
+	source.Store(cached.Static("second", "2"))
+	expectResult(t, persistent, "second", "2")
+	close(codec.release)
+	<-done
+	if stored := storedValue(dir); stored != "second" {
+		t.Fatalf("expected the latest value to be stored, got %q", stored)
+	}
+}
+
+func TestPersistentSameEtag(t *testing.T) {
+	dir := t.TempDir()
+	expectResult(t, cached.Persistent(cached.Static("stored", "etag"), dir, stringCodec{}), "stored", "etag")
+	info, err := os.Stat(filepath.Join(dir, "value"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var doneOnce sync.Once
+	done := make(chan struct{})
+	persistent := cached.Persistent(cached.Func(func() (string, string, error) {
+		defer doneOnce.Do(func() { close(done) })
+		return "recomputed", "etag", nil
+	}), dir, stringCodec{})
+	expectResult(t, persistent, "stored", "etag")
+	<-done
+	// The etag didn't change, so the stored value is kept and not
+	// written again.
+	expectResult(t, persistent, "stored", "etag")
+	if newInfo, err := os.Stat(filepath.Join(dir, "value")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !newInfo.ModTime().Equal(info.ModTime()) {
+		t.Fatalf("expected the stored value not to be written again")
+	}
+}
+
+func TestPersistentError(t *testing.T) {
+	dir := t.TempDir()
+	count := 0
+	persistent := cached.Persistent(cached.Func(func() (string, string, error) {
+		count += 1
+		if count%2 == 0 {
+			return "", "", errors.New("source error")
+		}
+		return "value", "etag", nil
+	}), dir, stringCodec{})
+	expectResult(t, persistent, "value", "etag")
+	// The last success is returned when the delegate fails.
+	expectResult(t, persistent, "value", "etag")
+	if count != 2 {
+		t.Fatalf("Expected function called twice, called: %v", count)
+	}
+
+	if _, _, err := cached.Persistent(cached.Func(func() (string, string, error) {
+		return "", "", errors.New("source error")
+	}), t.TempDir(), stringCodec{}).Get(); err == nil {
+		t.Fatalf("expected error, found none")
+	}
+}
+
+func TestPersistentCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "value"), []byte{0xff}, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectResult(t, cached.Persistent(cached.Static("value", "etag"), dir, stringCodec{}), "value", "etag")
+	expectResult(t, cached.Persistent(cached.Func(func() (string, string, error) {
+		return "", "", errors.New("source error")
+	}), dir, stringCodec{}), "value", "etag")
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cached
+
+import "sync"
+
+// Subscribable is implemented by the caches that can tell when they
+// change: Atomic, LastSuccess, AtomicCtx and LastSuccessCtx after every
+// Store, and the caches returned by Merge, MergeList, Transform and their
+// context-aware variants when an evaluation changes their result. Since
+// caches are lazy, the latter are only signaled when Get is called.
+//
+// Subscribe returns a channel, buffered so that the signals coalesce
+// while the subscriber is busy, and a function to cancel the
+// subscription. The channel is signaled after the change: a Get following
+// the receive returns the changed value, or a later one.
+type Subscribable interface {
+	Subscribe() (changes <-chan struct{}, cancel func())
+}
+
+// notifier signals subscribers of changes. The zero value is ready to
+// use.
+type notifier struct {
+	lock        sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+func (n *notifier) subscribe() (<-chan struct{}, func()) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.subscribers == nil {
+		n.subscribers = map[chan struct{}]struct{}{}
+	}
+	ch := make(chan struct{}, 1)
+	n.subscribers[ch] = struct{}{}
+	return ch, func() {
+		n.lock.Lock()
+		defer n.lock.Unlock()
+		delete(n.subscribers, ch)
+	}
+}
+
+func (n *notifier) notify() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for ch := range n.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// subscribableWithContext is returned by WithContext for Subscribable
+// values, so that they stay Subscribable.
+type subscribableWithContext[T any] struct {
+	withContext[T]
+	Subscribable
+}
+
+// subscribableWithoutContext is returned by WithoutContext for
+// Subscribable values, so that they stay Subscribable.
+type subscribableWithoutContext[T any] struct {
+	withoutContext[T]
+	Subscribable
+}
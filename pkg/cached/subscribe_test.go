@@ -0,0 +1,202 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cached_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/kube-openapi/pkg/cached"
+)
+
+func expectSignaled(t *testing.T, changes <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-changes:
+	case <-time.After(30 * time.Second):
+		t.Fatal("expected a change notification")
+	}
+}
+
+func expectNotSignaled(t *testing.T, changes <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-changes:
+		t.Fatal("unexpected change notification")
+	default:
+	}
+}
+
+func TestAtomicSubscribe(t *testing.T) {
+	var value cached.Atomic[string]
+	value.Store(cached.Static("a", "a"))
+
+	changes, cancel := value.Subscribe()
+	expectNotSignaled(t, changes)
+
+	value.Store(cached.Static("b", "b"))
+	expectSignaled(t, changes)
+	expectResult(t, &value, "b", "b")
+
+	// Notifications coalesce while the subscriber is busy.
+	value.Store(cached.Static("c", "c"))
+	value.Store(cached.Static("d", "d"))
+	expectSignaled(t, changes)
+	expectNotSignaled(t, changes)
+	expectResult(t, &value, "d", "d")
+
+	cancel()
+	value.Store(cached.Static("e", "e"))
+	expectNotSignaled(t, changes)
+}
+
+func TestLastSuccessCtxSubscribe(t *testing.T) {
+	var value cached.LastSuccessCtx[string]
+	value.Store(cached.WithContext(cached.Static("a", "a")))
+
+	changes, cancel := value.Subscribe()
+	defer cancel()
+	value.Store(cached.WithContext(cached.Static("b", "b")))
+	expectSignaled(t, changes)
+	expectResult(t, cached.WithoutContext[string](&value), "b", "b")
+}
+
+func TestMergeSubscribe(t *testing.T) {
+	var a, b cached.Atomic[string]
+	a.Store(cached.Static("a", "a1"))
+	b.Store(cached.Static("b", "b1"))
+	merged := cached.MergeList(func(results []cached.Result[string]) (string, string, error) {
+		value, etag := "", ""
+		for _, result := range results {
+			if result.Err != nil {
+				return "", "", result.Err
+			}
+			value += result.Value
+			etag += result.Etag
+		}
+		return value, etag, nil
+	}, []cached.Value[string]{&a, &b})
+
+	subscribable, ok := merged.(cached.Subscribable)
+	if !ok {
+		t.Fatalf("%T is not Subscribable", merged)
+	}
+	changes, cancel := subscribable.Subscribe()
+	defer cancel()
+
+	// The first evaluation is not a change.
+	expectResult(t, merged, "ab", "a1b1")
+	expectNotSignaled(t, changes)
+
+	// Changes are only noticed on the next evaluation.
+	a.Store(cached.Static("A", "a2"))
+	expectNotSignaled(t, changes)
+	expectResult(t, merged, "Ab", "a2b1")
+	expectSignaled(t, changes)
+
+	// A dependency with a new etag but the same merged etag is not a change.
+	b.Store(cached.Static("b", "b1"))
+	expectResult(t, merged, "Ab", "a2b1")
+	expectNotSignaled(t, changes)
+
+	// Starting to fail is a change.
+	b.Store(cached.Result[string]{Err: errors.New("failed")})
+	if _, _, err := merged.Get(); err == nil {
+		t.Fatal("expected an error")
+	}
+	expectSignaled(t, changes)
+
+	// Transforms of merges keep being subscribable.
+	transformed := cached.Transform(func(value string, etag string, err error) (string, string, error) {
+		return value, etag, err
+	}, &b)
+	if _, ok := transformed.(cached.Subscribable); !ok {
+		t.Fatalf("%T is not Subscribable", transformed)
+	}
+	if _, ok := cached.WithContext(merged).(cached.Subscribable); !ok {
+		t.Fatal("WithContext(merged) is not Subscribable")
+	}
+}
+
+func TestSubscribeOrdering(t *testing.T) {
+	var value cached.Atomic[int]
+	value.Store(cached.Static(0, "0"))
+	changes, cancel := value.Subscribe()
+	defer cancel()
+
+	const stores = 100
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= stores; i++ {
+			value.Store(cached.Static(i, fmt.Sprint(i)))
+		}
+	}()
+
+	// Every notification is sent after the store, so the value observed
+	// after a receive never goes backwards and ends with the last store.
+	last := 0
+	for last < stores {
+		<-changes
+		v, _, _ := value.Get()
+		if v < last {
+			t.Fatalf("observed %d after %d", v, last)
+		}
+		last = v
+	}
+	wg.Wait()
+}
+
+func TestMergeSubscribeConcurrent(t *testing.T) {
+	var source cached.Atomic[int]
+	source.Store(cached.Static(0, "0"))
+	merged := cached.Transform(func(value int, etag string, err error) (int, string, error) {
+		return value, etag, err
+	}, &source)
+	changes, cancel := merged.(cached.Subscribable).Subscribe()
+	defer cancel()
+	merged.Get()
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				merged.Get()
+			}
+		}()
+	}
+	for i := 1; i <= 10; i++ {
+		source.Store(cached.Static(i, fmt.Sprint(i)))
+		expectSignaled(t, changes)
+		for {
+			if v, _, _ := merged.Get(); v == i {
+				break
+			}
+		}
+	}
+	stop()
+	wg.Wait()
+}
@@ -30,6 +30,10 @@ const (
 	// TODO: Make this configurable.
 	ExtensionPrefix   = "x-kubernetes-"
 	ExtensionV2Schema = ExtensionPrefix + "v2-schema"
+	// ExtensionStream marks the media types of a response that are streams of
+	// events, or in Swagger 2.0 the response. Its value is the stream parameter
+	// of the media type, e.g. watch.
+	ExtensionStream = ExtensionPrefix + "stream"
 )
 
 // OpenAPIDefinition describes single type. Normally these definitions are auto-generated using gen-openapi.
@@ -38,6 +38,37 @@ type Route interface {
 	StatusCodeResponses() []StatusCodeResponse
 }
 
+// StreamingResponseMetadataKey is the key of the Route metadata marking the
+// successful responses of a route as streams of events, e.g. watch responses.
+// Its value is a StreamingResponse or a *StreamingResponse.
+//
+// In OpenAPI v3, the media types of such responses with a stream parameter,
+// e.g. application/json;stream=watch, describe an event of the stream instead
+// of the response model, and are marked with the ExtensionStream extension.
+// Swagger 2.0 can't describe responses per media type, so the v2 builder
+// marks the responses themselves with the ExtensionStream extension, and
+// their schema stays the response model.
+const StreamingResponseMetadataKey = "openapi.streaming-response"
+
+// StreamingResponse describes the events of a streaming response.
+type StreamingResponse struct {
+	// EventModel defines an example event of the stream, e.g. a WatchEvent.
+	// If nil, the events are described by the response model.
+	EventModel interface{}
+}
+
+// GetStreamingResponse returns the StreamingResponse of a route, or nil if
+// the responses of the route are not streaming.
+func GetStreamingResponse(route Route) *StreamingResponse {
+	switch v := route.Metadata()[StreamingResponseMetadataKey].(type) {
+	case StreamingResponse:
+		return &v
+	case *StreamingResponse:
+		return v
+	}
+	return nil
+}
+
 // StatusCodeResponse is an explicit response type with an HTTP Status Code.
 type StatusCodeResponse interface {
 	// Code defines the HTTP Status Code.
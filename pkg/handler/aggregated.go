@@ -0,0 +1,186 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+
+	klog "k8s.io/klog/v2"
+	"k8s.io/kube-openapi/pkg/aggregator"
+	"k8s.io/kube-openapi/pkg/cached"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// baseSpecKey is the key of the base spec among the specs merged with the
+// group versions. Group versions can't be empty, so it sorts first.
+const baseSpecKey = ""
+
+// baseSpecName and mergedSpecName are the names of the base spec and of
+// the merged spec in the cache graph, see RegisterDebugHandler. Group
+// versions are named after themselves.
+const (
+	baseSpecName   = "base"
+	mergedSpecName = "merged"
+)
+
+// UpdateGroupVersionLazy sets the spec of a group version, e.g.
+// "apis/apps/v1". The specs of all the group versions are lazily merged
+// into the spec of the service, given to NewOpenAPIService or
+// UpdateSpecLazy, which provides the info and takes precedence on path
+// conflicts. Conflicting definitions and parameters are renamed.
+//
+// Updating a group version only re-merges the specs, the values of the
+// other group versions are not rebuilt unless their etag changed. A group
+// version whose spec fails to build is left out of the merged spec until
+// it succeeds.
+func (o *OpenAPIService) UpdateGroupVersionLazy(group string, swagger cached.Value[*spec.Swagger]) error {
+	if group == baseSpecKey {
+		return fmt.Errorf("group version can't be empty")
+	}
+	o.groupsLock.Lock()
+	defer o.groupsLock.Unlock()
+	if o.groups == nil {
+		o.groups = map[string]cached.Value[*spec.Swagger]{}
+	}
+	o.groups[group] = cached.Named(group, swagger)
+	o.storeSpecLocked()
+	return nil
+}
+
+// UpdateGroupVersion sets the spec of a group version. See
+// UpdateGroupVersionLazy. The etag of the spec is a hash of its JSON
+// serialization, computed lazily, so that updating a group version with an
+// identical spec doesn't change the merged spec.
+func (o *OpenAPIService) UpdateGroupVersion(group string, swagger *spec.Swagger) error {
+	return o.UpdateGroupVersionLazy(group, &contentHashedSpec{swagger: swagger})
+}
+
+// contentHashedSpec is a static spec whose etag is a hash of its JSON
+// serialization. The etag is kept once successfully computed.
+type contentHashedSpec struct {
+	swagger *spec.Swagger
+
+	lock sync.Mutex
+	etag string
+}
+
+func (c *contentHashedSpec) Get() (*spec.Swagger, string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.etag == "" {
+		data, err := json.Marshal(c.swagger)
+		if err != nil {
+			return nil, "", err
+		}
+		c.etag = computeETag(data)
+	}
+	return c.swagger, c.etag, nil
+}
+
+// DeleteGroupVersion removes the spec of a group version from the merged
+// spec.
+func (o *OpenAPIService) DeleteGroupVersion(group string) {
+	o.groupsLock.Lock()
+	defer o.groupsLock.Unlock()
+	if _, ok := o.groups[group]; !ok {
+		return
+	}
+	delete(o.groups, group)
+	o.storeSpecLocked()
+}
+
+// storeSpecLocked updates the served spec with the base spec merged with
+// the group versions.
+func (o *OpenAPIService) storeSpecLocked() {
+	if len(o.groups) == 0 {
+		o.specCache.Store(o.observeBuildErrors(o.base))
+		return
+	}
+	caches := make(map[string]cached.ValueCtx[*spec.Swagger], len(o.groups)+1)
+	for group, swagger := range o.groups {
+		caches[group] = cached.WithContext(swagger)
+	}
+	caches[baseSpecKey] = o.base
+	o.specCache.Store(o.observeBuildErrors(cached.NamedCtx(mergedSpecName, cached.MergeCtx(mergeGroupVersions, caches))))
+}
+
+// observeBuildErrors reports the errors of the spec to the observer, before
+// specCache hides them behind the last success.
+func (o *OpenAPIService) observeBuildErrors(swagger cached.ValueCtx[*spec.Swagger]) cached.ValueCtx[*spec.Swagger] {
+	return cached.TransformCtx(func(_ context.Context, spec *spec.Swagger, etag string, err error) (*spec.Swagger, string, error) {
+		if err != nil {
+			o.observer.Load().BuildError("", err)
+		}
+		return spec, etag, err
+	}, swagger)
+}
+
+// mergeGroupVersions merges the specs of the group versions into the base
+// spec, in the order of the group versions so that renames are stable.
+// Neither the base spec nor the group versions are mutated.
+func mergeGroupVersions(_ context.Context, results map[string]cached.Result[*spec.Swagger]) (*spec.Swagger, string, error) {
+	base := results[baseSpecKey]
+	if base.Err != nil {
+		return nil, "", base.Err
+	}
+
+	groups := make([]string, 0, len(results)-1)
+	for group := range results {
+		if group != baseSpecKey {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+
+	merged := shallowCopySpec(base.Value)
+	etags := []string{base.Etag}
+	for _, group := range groups {
+		result := results[group]
+		if result.Err != nil {
+			klog.Warningf("Skipping OpenAPI spec of %s: %v", group, result.Err)
+			continue
+		}
+		if err := aggregator.MergeSpecsIgnorePathConflictRenamingDefinitionsAndParameters(merged, result.Value); err != nil {
+			return nil, "", fmt.Errorf("failed to merge OpenAPI spec of %s: %w", group, err)
+		}
+		etags = append(etags, group+"="+result.Etag)
+	}
+	return merged, computeETag([]byte(strings.Join(etags, "\n"))), nil
+}
+
+// shallowCopySpec copies the spec and the maps that merging specs into it
+// mutates.
+func shallowCopySpec(swagger *spec.Swagger) *spec.Swagger {
+	ret := &spec.Swagger{}
+	if swagger != nil {
+		*ret = *swagger
+	}
+	ret.Paths = &spec.Paths{}
+	if swagger != nil && swagger.Paths != nil {
+		ret.Paths.VendorExtensible = swagger.Paths.VendorExtensible
+		ret.Paths.Paths = maps.Clone(swagger.Paths.Paths)
+	}
+	ret.Definitions = maps.Clone(ret.Definitions)
+	ret.Parameters = maps.Clone(ret.Parameters)
+	return ret
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	klog "k8s.io/klog/v2"
+	"k8s.io/kube-openapi/pkg/cached"
+	"k8s.io/kube-openapi/pkg/common"
+)
+
+// RegisterDebugHandler registers a handler serving the graph of the caches
+// the spec is built from as a JSON list of cached.NodeStatus: the base
+// spec, the group versions, the merged spec and each representation, with
+// their last etag, error and evaluation time. It is meant to find out why
+// a stale spec is served, and is not registered by
+// RegisterOpenAPIVersionedService.
+func (o *OpenAPIService) RegisterDebugHandler(servePath string, handler common.PathHandler) {
+	handler.Handle(servePath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		roots := []any{o.jsonCache, o.protoCache, o.yamlCache}
+		o.serializersLock.Lock()
+		for _, s := range o.serializers {
+			roots = append(roots, s.cache)
+		}
+		o.serializersLock.Unlock()
+
+		w.Header().Set("Content-Type", contentTypeJSON)
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(cached.Dump(roots...)); err != nil {
+			klog.Errorf("Error writing OpenAPI cache status: %v", err)
+		}
+	}))
+}
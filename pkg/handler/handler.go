@@ -18,10 +18,13 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha512"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/NYTimes/gziphandler"
@@ -36,13 +39,21 @@ import (
 	"k8s.io/kube-openapi/pkg/cached"
 	"k8s.io/kube-openapi/pkg/common"
 	"k8s.io/kube-openapi/pkg/common/restfuladapter"
+	"k8s.io/kube-openapi/pkg/internal/serving"
+	"k8s.io/kube-openapi/pkg/metrics"
 	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
 )
 
 const (
 	subTypeProtobufDeprecated = "com.github.proto-openapi.spec.v2@v1.0+protobuf"
 	subTypeProtobuf           = "com.github.proto-openapi.spec.v2.v1.0+protobuf"
 	subTypeJSON               = "json"
+	subTypeYAML               = "yaml"
+
+	contentTypeJSON     = "application/" + subTypeJSON
+	contentTypeProtobuf = "application/" + subTypeProtobuf
+	contentTypeYAML     = "application/" + subTypeYAML
 )
 
 func computeETag(data []byte) string {
@@ -57,12 +68,41 @@ type timedSpec struct {
 	lastModified time.Time
 }
 
+// serializedSwagger is a spec along with its JSON serialization. The hash
+// of the JSON is the etag of all the representations of the spec.
+type serializedSwagger struct {
+	swagger *spec.Swagger
+	json    timedSpec
+}
+
+// serializer is a representation of the spec registered with
+// OpenAPIService.RegisterSerializer.
+type serializer struct {
+	mediaType string
+	cache     cached.ValueCtx[timedSpec]
+}
+
 // OpenAPIService is the service responsible for serving OpenAPI spec. It has
 // the ability to safely change the spec while serving it.
 type OpenAPIService struct {
-	specCache  cached.LastSuccess[*spec.Swagger]
-	jsonCache  cached.Value[timedSpec]
-	protoCache cached.Value[timedSpec]
+	specCache       cached.LastSuccessCtx[*spec.Swagger]
+	serializedCache cached.ValueCtx[serializedSwagger]
+	jsonCache       cached.ValueCtx[timedSpec]
+	protoCache      cached.ValueCtx[timedSpec]
+	yamlCache       cached.ValueCtx[timedSpec]
+
+	// groups are the specs of the group versions merged with the base
+	// spec, see UpdateGroupVersionLazy.
+	groupsLock sync.Mutex
+	base       cached.ValueCtx[*spec.Swagger]
+	groups     map[string]cached.Value[*spec.Swagger]
+
+	// serializers are the additional representations of the spec, in
+	// order of registration.
+	serializersLock sync.Mutex
+	serializers     []*serializer
+
+	observer metrics.AtomicObserver
 }
 
 // NewOpenAPIService builds an OpenAPIService starting with the given spec.
@@ -72,30 +112,50 @@ func NewOpenAPIService(swagger *spec.Swagger) *OpenAPIService {
 
 // NewOpenAPIServiceLazy builds an OpenAPIService from lazy spec.
 func NewOpenAPIServiceLazy(swagger cached.Value[*spec.Swagger]) *OpenAPIService {
+	return NewOpenAPIServiceLazyCtx(cached.WithContext(swagger))
+}
+
+// NewOpenAPIServiceLazyCtx builds an OpenAPIService from lazy spec, built
+// with the context of the request.
+func NewOpenAPIServiceLazyCtx(swagger cached.ValueCtx[*spec.Swagger]) *OpenAPIService {
 	o := &OpenAPIService{}
-	o.UpdateSpecLazy(swagger)
+	o.UpdateSpecLazyCtx(swagger)
 
-	o.jsonCache = cached.Transform[*spec.Swagger](func(spec *spec.Swagger, etag string, err error) (timedSpec, string, error) {
+	o.serializedCache = cached.NamedCtx("serialized", cached.TransformCtx[*spec.Swagger](func(ctx context.Context, spec *spec.Swagger, etag string, err error) (serializedSwagger, string, error) {
+		if err != nil {
+			return serializedSwagger{}, "", err
+		}
+		json, err := o.serialize(ctx, contentTypeJSON, spec.MarshalJSON)
+		if err != nil {
+			return serializedSwagger{}, "", err
+		}
+		return serializedSwagger{swagger: spec, json: timedSpec{spec: json, lastModified: time.Now()}}, computeETag(json), nil
+	}, &o.specCache))
+	o.jsonCache = cached.NamedCtx(contentTypeJSON, cached.TransformCtx(func(_ context.Context, s serializedSwagger, etag string, err error) (timedSpec, string, error) {
+		return s.json, etag, err
+	}, o.serializedCache))
+	o.protoCache = cached.NamedCtx(contentTypeProtobuf, cached.TransformCtx(func(ctx context.Context, ts timedSpec, etag string, err error) (timedSpec, string, error) {
 		if err != nil {
 			return timedSpec{}, "", err
 		}
-		json, err := spec.MarshalJSON()
+		proto, err := o.serialize(ctx, contentTypeProtobuf, func() ([]byte, error) { return ToProtoBinary(ts.spec) })
 		if err != nil {
 			return timedSpec{}, "", err
 		}
-		return timedSpec{spec: json, lastModified: time.Now()}, computeETag(json), nil
-	}, &o.specCache)
-	o.protoCache = cached.Transform(func(ts timedSpec, etag string, err error) (timedSpec, string, error) {
+		// We can re-use the same etag as json because of the Vary header.
+		return timedSpec{spec: proto, lastModified: ts.lastModified}, etag, nil
+	}, o.jsonCache))
+	o.yamlCache = cached.NamedCtx(contentTypeYAML, cached.TransformCtx(func(ctx context.Context, ts timedSpec, etag string, err error) (timedSpec, string, error) {
 		if err != nil {
 			return timedSpec{}, "", err
 		}
-		proto, err := ToProtoBinary(ts.spec)
+		yaml, err := o.serialize(ctx, contentTypeYAML, func() ([]byte, error) { return yaml.JSONToYAML(ts.spec) })
 		if err != nil {
 			return timedSpec{}, "", err
 		}
 		// We can re-use the same etag as json because of the Vary header.
-		return timedSpec{spec: proto, lastModified: ts.lastModified}, etag, nil
-	}, o.jsonCache)
+		return timedSpec{spec: yaml, lastModified: ts.lastModified}, etag, nil
+	}, o.jsonCache))
 	return o
 }
 
@@ -105,7 +165,61 @@ func (o *OpenAPIService) UpdateSpec(swagger *spec.Swagger) error {
 }
 
 func (o *OpenAPIService) UpdateSpecLazy(swagger cached.Value[*spec.Swagger]) {
-	o.specCache.Store(swagger)
+	o.UpdateSpecLazyCtx(cached.WithContext(swagger))
+}
+
+// UpdateSpecLazyCtx is the same as UpdateSpecLazy, with the context of the
+// request building the spec passed to the cache.
+func (o *OpenAPIService) UpdateSpecLazyCtx(swagger cached.ValueCtx[*spec.Swagger]) {
+	o.groupsLock.Lock()
+	defer o.groupsLock.Unlock()
+	o.base = cached.NamedCtx(baseSpecName, swagger)
+	o.storeSpecLocked()
+}
+
+// SetObserver sets the observer notified when the spec is built and
+// served. It can be called at any time. The group version reported to the
+// observer is always empty.
+func (o *OpenAPIService) SetObserver(observer metrics.Observer) {
+	o.observer.Store(observer)
+}
+
+// RegisterSerializer adds a representation of the spec, served when
+// requested with the given media type (e.g. "application/cbor"). The
+// representation is built lazily from the spec, cached, and shares its etag
+// with the JSON representation. Built-in media types can't be replaced.
+func (o *OpenAPIService) RegisterSerializer(mediaType string, fn func(*spec.Swagger) ([]byte, error)) error {
+	mediaType, err := serving.ParseSerializerMediaType(mediaType, contentTypeJSON, contentTypeProtobuf, "application/"+subTypeProtobufDeprecated, contentTypeYAML)
+	if err != nil {
+		return err
+	}
+
+	o.serializersLock.Lock()
+	defer o.serializersLock.Unlock()
+	for _, s := range o.serializers {
+		if s.mediaType == mediaType {
+			return fmt.Errorf("media type %q is already registered", mediaType)
+		}
+	}
+	cache := cached.NamedCtx(mediaType, cached.TransformCtx(func(ctx context.Context, s serializedSwagger, etag string, err error) (timedSpec, string, error) {
+		if err != nil {
+			return timedSpec{}, "", err
+		}
+		data, err := o.serialize(ctx, mediaType, func() ([]byte, error) { return fn(s.swagger) })
+		if err != nil {
+			return timedSpec{}, "", err
+		}
+		// We can re-use the same etag as json because of the Vary header.
+		return timedSpec{spec: data, lastModified: s.json.lastModified}, etag, nil
+	}, o.serializedCache))
+	o.serializers = append(o.serializers, &serializer{mediaType: mediaType, cache: cache})
+	return nil
+}
+
+// serialize calls fn and reports it to the observer as a serialization
+// of the document with the given content type.
+func (o *OpenAPIService) serialize(ctx context.Context, contentType string, fn func() ([]byte, error)) ([]byte, error) {
+	return serving.Serialize(ctx, o.observer.Load(), "", contentType, fn)
 }
 
 func ToProtoBinary(json []byte) ([]byte, error) {
@@ -127,19 +241,30 @@ func RegisterOpenAPIVersionedService(spec *spec.Swagger, servePath string, handl
 
 // RegisterOpenAPIVersionedService registers a handler to provide access to provided swagger spec.
 func (o *OpenAPIService) RegisterOpenAPIVersionedService(servePath string, handler common.PathHandler) {
-	accepted := []struct {
+	type acceptedType struct {
 		Type                string
 		SubType             string
 		ReturnedContentType string
-		GetDataAndEtag      cached.Value[timedSpec]
-	}{
-		{"application", subTypeJSON, "application/" + subTypeJSON, o.jsonCache},
-		{"application", subTypeProtobufDeprecated, "application/" + subTypeProtobuf, o.protoCache},
-		{"application", subTypeProtobuf, "application/" + subTypeProtobuf, o.protoCache},
+		GetDataAndEtag      cached.ValueCtx[timedSpec]
+	}
+	builtins := []acceptedType{
+		{"application", subTypeJSON, contentTypeJSON, o.jsonCache},
+		{"application", subTypeProtobufDeprecated, contentTypeProtobuf, o.protoCache},
+		{"application", subTypeProtobuf, contentTypeProtobuf, o.protoCache},
+		{"application", subTypeYAML, contentTypeYAML, o.yamlCache},
 	}
 
-	handler.Handle(servePath, gziphandler.GzipHandler(http.HandlerFunc(
+	gzipped := gziphandler.GzipHandler(http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
+			o.serializersLock.Lock()
+			accepted := make([]acceptedType, 0, len(builtins)+len(o.serializers))
+			accepted = append(accepted, builtins...)
+			for _, s := range o.serializers {
+				t, subType, _ := strings.Cut(s.mediaType, "/")
+				accepted = append(accepted, acceptedType{t, subType, s.mediaType, s.cache})
+			}
+			o.serializersLock.Unlock()
+
 			decipherableFormats := r.Header.Get("Accept")
 			if decipherableFormats == "" {
 				decipherableFormats = "*/*"
@@ -155,7 +280,7 @@ func (o *OpenAPIService) RegisterOpenAPIVersionedService(servePath string, handl
 						continue
 					}
 					// serve the first matching media type in the sorted clause list
-					ts, etag, err := accepts.GetDataAndEtag.Get()
+					ts, etag, err := serving.Get(r.Context(), o.observer.Load(), "", accepts.ReturnedContentType, accepts.GetDataAndEtag)
 					if err != nil {
 						klog.Errorf("Error in OpenAPI handler: %s", err)
 						// only return a 503 if we have no older cache data to serve
@@ -178,7 +303,18 @@ func (o *OpenAPIService) RegisterOpenAPIVersionedService(servePath string, handl
 			w.WriteHeader(406)
 			return
 		}),
-	))
+	)
+	// The counting writer wraps the writer gziphandler writes the
+	// compressed body to, so that the bytes served are counted after
+	// compression, like in OpenAPI v3.
+	handler.Handle(servePath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cw := &serving.CountingResponseWriter{ResponseWriter: w}
+		gzipped.ServeHTTP(cw, r)
+		// The Content-Type is only set when a document is served.
+		if contentType := cw.Header().Get("Content-Type"); contentType != "" {
+			o.observer.Load().Served("", contentType, cw.Written)
+		}
+	}))
 }
 
 // BuildAndRegisterOpenAPIVersionedService builds the spec and registers a handler to provide access to it.
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"context"
 	json "encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
@@ -9,10 +11,15 @@ import (
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	"k8s.io/kube-openapi/pkg/cached"
+	"k8s.io/kube-openapi/pkg/metrics"
 	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
 )
 
 var returnedSwagger = []byte(`{
@@ -38,6 +45,10 @@ func TestRegisterOpenAPIVersionedService(t *testing.T) {
 	if err != nil {
 		t.Errorf("Unexpected error in preparing returnedPb: %v", err)
 	}
+	returnedYAML, err := yaml.JSONToYAML(returnedJSON)
+	if err != nil {
+		t.Errorf("Unexpected error in preparing returnedYAML: %v", err)
+	}
 
 	mux := http.NewServeMux()
 	o := NewOpenAPIService(&s)
@@ -68,6 +79,9 @@ func TestRegisterOpenAPIVersionedService(t *testing.T) {
 		{"application/json, application/com.github.proto-openapi.spec.v2@v1.0+protobuf", 200, "application/json", returnedJSON},
 		{"application/com.github.proto-openapi.spec.v2@v1.0+protobuf, application/json", 200, "application/com.github.proto-openapi.spec.v2.v1.0+protobuf", returnedPb},
 		{"application/com.github.proto-openapi.spec.v2@v1.0+protobuf; q=0.5, application/json", 200, "application/json", returnedJSON},
+		{"application/yaml", 200, "application/yaml", returnedYAML},
+		{"application/yaml, application/json", 200, "application/yaml", returnedYAML},
+		{"application/yaml; q=0.5, application/json", 200, "application/json", returnedJSON},
 	}
 
 	for _, tc := range tcs {
@@ -255,3 +269,445 @@ func TestConcurrentReadStaleCache(t *testing.T) {
 		<-updateSpecChan
 	}
 }
+
+func TestRegisterSerializer(t *testing.T) {
+	var s spec.Swagger
+	if err := s.UnmarshalJSON(returnedSwagger); err != nil {
+		t.Fatalf("Unexpected error in unmarshalling SwaggerJSON: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	o := NewOpenAPIService(&s)
+	o.RegisterOpenAPIVersionedService("/openapi/v2", mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	calls := 0
+	if err := o.RegisterSerializer("application/x-title; charset=utf-8", func(swagger *spec.Swagger) ([]byte, error) {
+		calls++
+		return []byte(swagger.Info.Title), nil
+	}); err != nil {
+		t.Fatalf("Unexpected error registering serializer: %v", err)
+	}
+	for _, mediaType := range []string{"application/x-title", "application/json", "application/com.github.proto-openapi.spec.v2@v1.0+protobuf", "application/*", "invalid"} {
+		if err := o.RegisterSerializer(mediaType, func(*spec.Swagger) ([]byte, error) { return nil, nil }); err == nil {
+			t.Errorf("Expected an error registering %v", mediaType)
+		}
+	}
+
+	get := func(accept string) *http.Response {
+		req, err := http.NewRequest("GET", server.URL+"/openapi/v2", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error in creating new request: %v", err)
+		}
+		req.Header.Set("Accept", accept)
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+		}
+		return resp
+	}
+
+	jsonResp := get("application/json")
+	jsonResp.Body.Close()
+	for i := 0; i < 2; i++ {
+		resp := get("application/x-title")
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Unexpected error in reading response body: %v", err)
+		}
+		if resp.StatusCode != 200 {
+			t.Fatalf("Unexpected response status code, want: 200, got: %v", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Content-Type"); got != "application/x-title" {
+			t.Errorf("Unexpected content type in response, want: application/x-title, got: %v", got)
+		}
+		if string(body) != "Kubernetes" {
+			t.Errorf("Unexpected response body, want: Kubernetes, got: %s", body)
+		}
+		if got, want := resp.Header.Get("Etag"), jsonResp.Header.Get("Etag"); got != want {
+			t.Errorf("Expected Etag to match the JSON Etag %v, got %v", want, got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected serializer to be called once, got %d", calls)
+	}
+
+	// JSON is still the default.
+	resp := get("*/*")
+	resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Unexpected content type in response, want: application/json, got: %v", got)
+	}
+
+	// The serializer follows updates of the spec.
+	var updated spec.Swagger
+	if err := updated.UnmarshalJSON(updatedSwagger); err != nil {
+		t.Fatalf("Unexpected error in unmarshalling SwaggerJSON: %v", err)
+	}
+	updated.Info.Title = "Updated"
+	o.UpdateSpec(&updated)
+	resp = get("application/x-title")
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error in reading response body: %v", err)
+	}
+	if string(body) != "Updated" {
+		t.Errorf("Unexpected response body, want: Updated, got: %s", body)
+	}
+}
+
+func TestUpdateGroupVersionLazy(t *testing.T) {
+	var base spec.Swagger
+	if err := base.UnmarshalJSON([]byte(`{
+  "swagger": "2.0",
+  "info": {"title": "Kubernetes", "version": "v1.11.0"},
+  "paths": {"/version": {"get": {"responses": {"200": {"description": "OK"}}}}}
+  }`)); err != nil {
+		t.Fatalf("Unexpected error in unmarshalling SwaggerJSON: %v", err)
+	}
+	groupSpec := func(path, description string) *spec.Swagger {
+		var s spec.Swagger
+		if err := s.UnmarshalJSON([]byte(fmt.Sprintf(`{
+  "swagger": "2.0",
+  "paths": {%q: {"get": {"responses": {"200": {"description": "OK", "schema": {"$ref": "#/definitions/ObjectMeta"}}}}}},
+  "definitions": {"ObjectMeta": {"type": "object", "description": %q}}
+  }`, path, description))); err != nil {
+			t.Fatalf("Unexpected error in unmarshalling SwaggerJSON: %v", err)
+		}
+		return &s
+	}
+
+	mux := http.NewServeMux()
+	o := NewOpenAPIService(&base)
+	o.RegisterOpenAPIVersionedService("/openapi/v2", mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	getSpec := func() *spec.Swagger {
+		var s spec.Swagger
+		if err := s.UnmarshalJSON(getJSONBodyOrDie(server)); err != nil {
+			t.Fatalf("Unexpected error in unmarshalling SwaggerJSON: %v", err)
+		}
+		return &s
+	}
+	keys := func(s *spec.Swagger) (paths []string, definitions []string) {
+		for p := range s.Paths.Paths {
+			paths = append(paths, p)
+		}
+		for d := range s.Definitions {
+			definitions = append(definitions, d)
+		}
+		sort.Strings(paths)
+		sort.Strings(definitions)
+		return paths, definitions
+	}
+
+	appsBuilds := 0
+	apps := groupSpec("/apis/apps/v1/deployments", "apps")
+	if err := o.UpdateGroupVersionLazy("apis/apps/v1", cached.Transform(func(s *spec.Swagger, etag string, err error) (*spec.Swagger, string, error) {
+		appsBuilds++
+		return s, etag, err
+	}, cached.Static(apps, "apps"))); err != nil {
+		t.Fatalf("Unexpected error updating group version: %v", err)
+	}
+	if err := o.UpdateGroupVersion("apis/batch/v1", groupSpec("/apis/batch/v1/jobs", "batch")); err != nil {
+		t.Fatalf("Unexpected error updating group version: %v", err)
+	}
+	if err := o.UpdateGroupVersion("", &base); err == nil {
+		t.Errorf("Expected an error updating an empty group version")
+	}
+
+	merged := getSpec()
+	paths, definitions := keys(merged)
+	if want := []string{"/apis/apps/v1/deployments", "/apis/batch/v1/jobs", "/version"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Unexpected paths, want: %v, got: %v", want, paths)
+	}
+	if want := []string{"ObjectMeta", "ObjectMeta_v2"}; !reflect.DeepEqual(definitions, want) {
+		t.Errorf("Unexpected definitions, want: %v, got: %v", want, definitions)
+	}
+	if got := merged.Definitions["ObjectMeta_v2"].Description; got != "batch" {
+		t.Errorf("Expected the definition of the later group version to be renamed, got description %q", got)
+	}
+	if merged.Info == nil || merged.Info.Title != "Kubernetes" {
+		t.Errorf("Expected the info of the base spec, got %v", merged.Info)
+	}
+
+	// Updating a group version doesn't rebuild the others.
+	o.UpdateGroupVersionLazy("apis/batch/v1", cached.Func(func() (*spec.Swagger, string, error) {
+		return nil, "", fmt.Errorf("spec is not ready")
+	}))
+	paths, _ = keys(getSpec())
+	if want := []string{"/apis/apps/v1/deployments", "/version"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Expected failing group versions to be skipped, want paths: %v, got: %v", want, paths)
+	}
+	if appsBuilds != 1 {
+		t.Errorf("Expected apps to be built once, got %d", appsBuilds)
+	}
+
+	o.DeleteGroupVersion("apis/batch/v1")
+	o.DeleteGroupVersion("apis/apps/v1")
+	paths, definitions = keys(getSpec())
+	if want := []string{"/version"}; !reflect.DeepEqual(paths, want) || len(definitions) != 0 {
+		t.Errorf("Expected only the base spec, got paths: %v, definitions: %v", paths, definitions)
+	}
+
+	if len(base.Paths.Paths) != 1 || len(base.Definitions) != 0 {
+		t.Errorf("Expected the base spec not to be mutated, got %v", base)
+	}
+	if _, ok := apps.Definitions["ObjectMeta_v2"]; ok || len(apps.Paths.Paths) != 1 {
+		t.Errorf("Expected the group version spec not to be mutated, got %v", apps)
+	}
+}
+
+func TestUpdateGroupVersionStableEtag(t *testing.T) {
+	groupSpec := func(description string) *spec.Swagger {
+		var s spec.Swagger
+		if err := s.UnmarshalJSON([]byte(fmt.Sprintf(`{
+  "swagger": "2.0",
+  "paths": {"/apis/batch/v1/jobs": {"get": {"responses": {"200": {"description": %q}}}}}
+  }`, description))); err != nil {
+			t.Fatalf("Unexpected error in unmarshalling SwaggerJSON: %v", err)
+		}
+		return &s
+	}
+
+	mux := http.NewServeMux()
+	o := NewOpenAPIService(&spec.Swagger{SwaggerProps: spec.SwaggerProps{Swagger: "2.0"}})
+	o.RegisterOpenAPIVersionedService("/openapi/v2", mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	getEtag := func() string {
+		t.Helper()
+		resp, err := server.Client().Get(server.URL + "/openapi/v2")
+		if err != nil {
+			t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Fatalf("Unexpected response status code, want: 200, got: %v", resp.StatusCode)
+		}
+		return resp.Header.Get("Etag")
+	}
+
+	if err := o.UpdateGroupVersion("apis/batch/v1", groupSpec("OK")); err != nil {
+		t.Fatalf("Unexpected error updating group version: %v", err)
+	}
+	etag := getEtag()
+
+	// Updating with an identical spec doesn't serialize the merged spec
+	// again.
+	observer := &recordingObserver{}
+	o.SetObserver(observer)
+	if err := o.UpdateGroupVersion("apis/batch/v1", groupSpec("OK")); err != nil {
+		t.Fatalf("Unexpected error updating group version: %v", err)
+	}
+	if got := getEtag(); got != etag {
+		t.Errorf("Expected an identical spec to keep the etag %v, got %v", etag, got)
+	}
+	if observer.serialized != 0 {
+		t.Errorf("Expected no serialization for an identical spec, got %d", observer.serialized)
+	}
+	if err := o.UpdateGroupVersion("apis/batch/v1", groupSpec("Updated")); err != nil {
+		t.Fatalf("Unexpected error updating group version: %v", err)
+	}
+	if got := getEtag(); got == etag {
+		t.Errorf("Expected the etag to change with the spec")
+	}
+}
+
+type testContextKey struct{}
+
+func TestNewOpenAPIServiceLazyCtx(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIServiceLazyCtx(cached.FuncCtx(func(ctx context.Context) (*spec.Swagger, string, error) {
+		title, ok := ctx.Value(testContextKey{}).(string)
+		if !ok {
+			return nil, "", fmt.Errorf("missing request context")
+		}
+		var s spec.Swagger
+		if err := s.UnmarshalJSON(returnedSwagger); err != nil {
+			return nil, "", err
+		}
+		s.Info.Title = title
+		return &s, title, nil
+	}))
+	o.RegisterOpenAPIVersionedService("/openapi/v2", mux)
+
+	req := httptest.NewRequest("GET", "/openapi/v2", nil)
+	req = req.WithContext(context.WithValue(req.Context(), testContextKey{}, "from-request"))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("Unexpected response status code, want: 200, got: %v", w.Code)
+	}
+	var got spec.Swagger
+	if err := got.UnmarshalJSON(w.Body.Bytes()); err != nil {
+		t.Fatalf("Unexpected error in unmarshalling SwaggerJSON: %v", err)
+	}
+	if got.Info.Title != "from-request" {
+		t.Errorf("Expected the spec to be built with the request context, got title %q", got.Info.Title)
+	}
+}
+
+func TestRegisterDebugHandler(t *testing.T) {
+	var base spec.Swagger
+	if err := base.UnmarshalJSON(returnedSwagger); err != nil {
+		t.Fatalf("Unexpected error in unmarshalling SwaggerJSON: %v", err)
+	}
+	mux := http.NewServeMux()
+	o := NewOpenAPIService(&base)
+	if err := o.UpdateGroupVersion("apis/apps/v1", &spec.Swagger{}); err != nil {
+		t.Fatalf("Unexpected error updating group version: %v", err)
+	}
+	o.RegisterOpenAPIVersionedService("/openapi/v2", mux)
+	o.RegisterDebugHandler("/debug/openapi/v2", mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	getJSONBodyOrDie(server)
+	resp, err := http.Get(server.URL + "/debug/openapi/v2")
+	if err != nil {
+		t.Fatalf("Unexpected error getting the debug handler: %v", err)
+	}
+	defer resp.Body.Close()
+	var nodes []cached.NodeStatus
+	if err := json.NewDecoder(resp.Body).Decode(&nodes); err != nil {
+		t.Fatalf("Unexpected error decoding the cache status: %v", err)
+	}
+
+	got := map[string]cached.NodeStatus{}
+	for _, node := range nodes {
+		sort.Strings(node.Dependencies)
+		got[node.Name] = node
+	}
+	wantDependencies := map[string][]string{
+		contentTypeJSON:     {"serialized"},
+		contentTypeProtobuf: {contentTypeJSON},
+		contentTypeYAML:     {contentTypeJSON},
+		"serialized":        {mergedSpecName},
+		mergedSpecName:      {"apis/apps/v1", baseSpecName},
+		baseSpecName:        nil,
+		"apis/apps/v1":      nil,
+	}
+	if len(got) != len(wantDependencies) {
+		t.Errorf("Expected nodes %v, got %v", wantDependencies, got)
+	}
+	for name, deps := range wantDependencies {
+		node, ok := got[name]
+		if !ok {
+			t.Errorf("Missing node %q", name)
+			continue
+		}
+		if !reflect.DeepEqual(node.Dependencies, deps) {
+			t.Errorf("Expected the dependencies of %q to be %v, got %v", name, deps, node.Dependencies)
+		}
+	}
+	if node := got[mergedSpecName]; node.Evaluations == 0 || node.Etag == "" {
+		t.Errorf("Expected the merged spec to be evaluated, got %+v", node)
+	}
+	if node := got[contentTypeYAML]; node.Evaluations != 0 {
+		t.Errorf("Expected the YAML spec not to be evaluated, got %+v", node)
+	}
+}
+
+// recordingObserver records the serializations, build errors and served
+// sizes reported to a metrics.Observer.
+type recordingObserver struct {
+	metrics.NoopObserver
+
+	lock        sync.Mutex
+	serialized  int
+	buildErrors int
+	served      []int
+}
+
+func (r *recordingObserver) Serialized(string, string, time.Duration, int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.serialized++
+}
+
+func (r *recordingObserver) BuildError(string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.buildErrors++
+}
+
+func (r *recordingObserver) Served(_, _ string, size int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.served = append(r.served, size)
+}
+
+func TestObserverBuildErrors(t *testing.T) {
+	broken := false
+	o := NewOpenAPIServiceLazy(cached.Func(func() (*spec.Swagger, string, error) {
+		if broken {
+			return nil, "", errors.New("broken")
+		}
+		return &spec.Swagger{SwaggerProps: spec.SwaggerProps{Swagger: "2.0"}}, "v2", nil
+	}))
+	observer := &recordingObserver{}
+	o.SetObserver(observer)
+	mux := http.NewServeMux()
+	o.RegisterOpenAPIVersionedService("/openapi/v2", mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	getJSONBodyOrDie(server)
+	broken = true
+	// The last success is still served.
+	getJSONBodyOrDie(server)
+	getJSONBodyOrDie(server)
+	if observer.buildErrors != 2 {
+		t.Errorf("Expected 2 build errors after the first success, got %v", observer.buildErrors)
+	}
+}
+
+func TestObserverServedCompressedBytes(t *testing.T) {
+	// The spec is large enough to be compressed.
+	paths := map[string]spec.PathItem{}
+	for i := 0; i < 100; i++ {
+		paths[fmt.Sprintf("/apis/group%d/v1", i)] = spec.PathItem{}
+	}
+	o := NewOpenAPIService(&spec.Swagger{SwaggerProps: spec.SwaggerProps{Swagger: "2.0", Paths: &spec.Paths{Paths: paths}}})
+	observer := &recordingObserver{}
+	o.SetObserver(observer)
+	mux := http.NewServeMux()
+	o.RegisterOpenAPIVersionedService("/openapi/v2", mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+
+	var sizes []int
+	for _, encoding := range []string{"identity", "gzip"} {
+		req, err := http.NewRequest("GET", server.URL+"/openapi/v2", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error in creating new request: %v", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Accept-Encoding", encoding)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Unexpected error in reading response body: %v", err)
+		}
+		if encoding == "gzip" && resp.Header.Get("Content-Encoding") != "gzip" {
+			t.Fatalf("Expected a gzip response, got Content-Encoding %q", resp.Header.Get("Content-Encoding"))
+		}
+		sizes = append(sizes, len(body))
+	}
+	if sizes[1] >= sizes[0] {
+		t.Fatalf("Expected the gzip response to be smaller, got %v", sizes)
+	}
+	if !reflect.DeepEqual(observer.served, sizes) {
+		t.Errorf("Expected served sizes %v, got %v", sizes, observer.served)
+	}
+}
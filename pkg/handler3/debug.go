@@ -0,0 +1,96 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler3
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"k8s.io/klog/v2"
+	"k8s.io/kube-openapi/pkg/cached"
+	"k8s.io/kube-openapi/pkg/common"
+)
+
+// discoveryName is the name of the discovery document in the cache graph.
+// The specs of the group versions are named after the group versions, and
+// their representations after the group version, the media type and the
+// content-coding.
+const discoveryName = "discovery"
+
+// RegisterDebugHandler registers a handler serving the graph of the caches
+// the discovery document and the group versions are built from as a JSON
+// list of cached.NodeStatus, with their last etag, error and evaluation
+// time. It is meant to find out why a stale spec is served, and is not
+// registered by RegisterOpenAPIV3VersionedService.
+func (o *OpenAPIService) RegisterDebugHandler(servePath string, handler common.PathHandler) {
+	handler.Handle(servePath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentTypeJSON)
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(cached.Dump(o.debugRoots()...)); err != nil {
+			klog.Errorf("Error writing OpenAPI cache status: %v", err)
+		}
+	}))
+}
+
+// debugRoots returns the caches served by the service, starting with the
+// discovery document, then the documents of each group version sorted by
+// name.
+func (o *OpenAPIService) debugRoots() []any {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	var roots []any
+	appendEncoded := func(e encodedSpecs) {
+		for _, encoding := range append([]string{encodingIdentity}, supportedEncodings...) {
+			roots = append(roots, e[encoding])
+		}
+	}
+	appendDocuments := func(d *openAPIV3Documents) {
+		appendEncoded(d.jsonEncoded)
+		appendEncoded(d.pbEncoded)
+		appendEncoded(d.yamlEncoded)
+		mediaTypes := make([]string, 0, len(d.custom))
+		for mediaType := range d.custom {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+		sort.Strings(mediaTypes)
+		for _, mediaType := range mediaTypes {
+			appendEncoded(d.custom[mediaType])
+		}
+	}
+
+	appendEncoded(o.discoveryEncoded)
+	groups := make([]string, 0, len(o.v3Schema))
+	for group := range o.v3Schema {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		g := o.v3Schema[group]
+		appendDocuments(g.documents)
+		filters := make([]string, 0, len(g.filtered))
+		for key := range g.filtered {
+			filters = append(filters, key)
+		}
+		sort.Strings(filters)
+		for _, key := range filters {
+			appendDocuments(g.filtered[key])
+		}
+	}
+	return roots
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"k8s.io/kube-openapi/pkg/cached"
+)
+
+const (
+	encodingIdentity = "identity"
+	encodingGzip     = "gzip"
+	encodingZstd     = "zstd"
+)
+
+// supportedEncodings is the list of content-codings that can be served,
+// in order of preference when the client weighs them equally.
+var supportedEncodings = []string{encodingZstd, encodingGzip}
+
+var zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+	// A nil writer is allowed when only EncodeAll is used, which is
+	// safe for concurrent use.
+	return zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+})
+
+func gzipCompress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func zstdCompress(data []byte) ([]byte, error) {
+	encoder, err := zstdEncoder()
+	if err != nil {
+		return nil, err
+	}
+	return encoder.EncodeAll(data, make([]byte, 0, len(data)/4)), nil
+}
+
+// encodedSpecs maps a content-coding to the cache holding the spec encoded
+// with it.
+type encodedSpecs map[string]cached.ValueCtx[timedSpec]
+
+// newEncodedSpecs returns the given cache along with compressed variants of
+// it. The compressed variants are computed lazily and re-use the etag of
+// the source, so they are only recomputed when the source changes. They are
+// named after the source name and their content-coding. The etags served to
+// clients are derived per content-coding with encodedETag.
+func newEncodedSpecs(name string, source cached.ValueCtx[timedSpec]) encodedSpecs {
+	compressed := func(encoding string, compress func([]byte) ([]byte, error)) cached.ValueCtx[timedSpec] {
+		return cached.NamedCtx(name+" "+encoding, cached.TransformCtx(func(_ context.Context, ts timedSpec, etag string, err error) (timedSpec, string, error) {
+			if err != nil {
+				return timedSpec{}, "", err
+			}
+			data, err := compress(ts.spec)
+			if err != nil {
+				return timedSpec{}, "", err
+			}
+			return timedSpec{spec: data, lastModified: ts.lastModified}, etag, nil
+		}, source))
+	}
+	return encodedSpecs{
+		encodingIdentity: source,
+		encodingGzip:     compressed(encodingGzip, gzipCompress),
+		encodingZstd:     compressed(encodingZstd, zstdCompress),
+	}
+}
+
+// encodedETag returns the etag of the representation of a document with the
+// given etag encoded with the given content-coding. Each content-coding has
+// different bytes, so it must have its own strong validator.
+func encodedETag(etag, encoding string) string {
+	if encoding == encodingIdentity {
+		return etag
+	}
+	return etag + "-" + encoding
+}
+
+// get returns the cache for the given content-coding, falling back to the
+// uncompressed cache for unknown codings.
+func (e encodedSpecs) get(encoding string) cached.ValueCtx[timedSpec] {
+	if c, ok := e[encoding]; ok {
+		return c
+	}
+	return e[encodingIdentity]
+}
+
+// negotiateEncoding returns the preferred supported content-coding from the
+// request's Accept-Encoding header, or identity if none is acceptable.
+func negotiateEncoding(r *http.Request) string {
+	header := r.Header.Get("Accept-Encoding")
+	if header == "" {
+		return encodingIdentity
+	}
+	weights := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(param, "=")
+			if !ok || strings.TrimSpace(key) != "q" {
+				continue
+			}
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		weights[coding] = q
+	}
+
+	best, bestQ := encodingIdentity, 0.0
+	for _, encoding := range supportedEncodings {
+		q, ok := weights[encoding]
+		if !ok {
+			q, ok = weights["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// setEncodingHeaders sets the headers describing a response encoded with
+// the given content-coding.
+func setEncodingHeaders(w http.ResponseWriter, encoding string) {
+	w.Header().Add("Vary", "Accept-Encoding")
+	if encoding != encodingIdentity {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+}
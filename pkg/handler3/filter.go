@@ -0,0 +1,91 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler3
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxFilteredDocuments is the maximum number of filters cached per
+	// group version.
+	maxFilteredDocuments = 32
+
+	filterParamPaths   = "paths"
+	filterParamSchemas = "schemas"
+)
+
+// documentFilter selects a subset of a group version document, built from
+// the `paths` and `schemas` query parameters. Both parameters can be
+// repeated and hold comma-separated values, e.g.
+// ?schemas=io.k8s.api.apps.v1.Deployment&paths=/apis/apps/v1/deployments
+type documentFilter struct {
+	paths   []string
+	schemas []string
+}
+
+func parseDocumentFilter(query url.Values) documentFilter {
+	return documentFilter{
+		paths:   parseFilterValues(query[filterParamPaths]),
+		schemas: parseFilterValues(query[filterParamSchemas]),
+	}
+}
+
+// parseFilterValues splits comma-separated values, and returns them sorted
+// and without duplicates so that equivalent filters share a cache.
+func parseFilterValues(values []string) []string {
+	set := map[string]struct{}{}
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				set[v] = struct{}{}
+			}
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	ret := make([]string, 0, len(set))
+	for v := range set {
+		ret = append(ret, v)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func (f documentFilter) empty() bool {
+	return len(f.paths) == 0 && len(f.schemas) == 0
+}
+
+// encode sets the query parameters that select the filter.
+func (f documentFilter) encode(query url.Values) {
+	if len(f.paths) > 0 {
+		query.Set(filterParamPaths, strings.Join(f.paths, ","))
+	}
+	if len(f.schemas) > 0 {
+		query.Set(filterParamSchemas, strings.Join(f.schemas, ","))
+	}
+}
+
+// key uniquely identifies the filter.
+func (f documentFilter) key() string {
+	query := url.Values{}
+	f.encode(query)
+	return query.Encode()
+}
@@ -18,34 +18,51 @@ package handler3
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha512"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	openapi_v3 "github.com/google/gnostic-models/openapiv3"
-	"github.com/google/uuid"
 	"github.com/munnerz/goautoneg"
 	"google.golang.org/protobuf/proto"
 
 	"k8s.io/klog/v2"
+	"k8s.io/kube-openapi/pkg/aggregator"
 	"k8s.io/kube-openapi/pkg/cached"
 	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/internal/serving"
+	"k8s.io/kube-openapi/pkg/metrics"
 	"k8s.io/kube-openapi/pkg/spec3"
+	"sigs.k8s.io/yaml"
 )
 
 const (
 	subTypeProtobufDeprecated = "com.github.proto-openapi.spec.v3@v1.0+protobuf"
 	subTypeProtobuf           = "com.github.proto-openapi.spec.v3.v1.0+protobuf"
 	subTypeJSON               = "json"
+	subTypeYAML               = "yaml"
+
+	contentTypeJSON     = "application/" + subTypeJSON
+	contentTypeProtobuf = "application/" + subTypeProtobuf
+	contentTypeYAML     = "application/" + subTypeYAML
+
+	// retryAfterSeconds is sent with 503 responses when a spec fails to build.
+	retryAfterSeconds = 1
 )
 
+var errGroupVersionNotFound = errors.New("cannot find group version")
+
 // OpenAPIV3Discovery is the format of the Discovery document for OpenAPI V3
 // It maps Discovery paths to their corresponding URLs with a hash parameter included
 type OpenAPIV3Discovery struct {
@@ -72,40 +89,239 @@ type timedSpec struct {
 	lastModified time.Time
 }
 
+// serializedSpec is a spec along with its JSON serialization. The hash of
+// the JSON is the etag of all the representations of the spec.
+type serializedSpec struct {
+	openapi *spec3.OpenAPI
+	json    timedSpec
+}
+
+// serializer is a representation of the spec registered with
+// OpenAPIService.RegisterSerializer.
+type serializer struct {
+	mediaType string
+	fn        func(*spec3.OpenAPI) ([]byte, error)
+}
+
+// openAPIV3Documents holds the serializations of an OpenAPI v3 spec.
+type openAPIV3Documents struct {
+	// name prefixes the names of the caches in the cache graph.
+	name string
+
+	serializedCache cached.ValueCtx[serializedSpec]
+	pbCache         cached.ValueCtx[timedSpec]
+	jsonCache       cached.ValueCtx[timedSpec]
+	yamlCache       cached.ValueCtx[timedSpec]
+
+	// pbCache, jsonCache and yamlCache with their compressed variants, keyed
+	// by content-coding.
+	pbEncoded   encodedSpecs
+	jsonEncoded encodedSpecs
+	yamlEncoded encodedSpecs
+
+	// custom holds the representations of the registered serializers,
+	// created the first time they are requested and keyed by media type.
+	custom map[string]encodedSpecs
+}
+
 // This type is protected by the lock on OpenAPIService.
 type openAPIV3Group struct {
-	specCache cached.LastSuccess[*spec3.OpenAPI]
-	pbCache   cached.Value[timedSpec]
-	jsonCache cached.Value[timedSpec]
+	specCache cached.LastSuccessCtx[*spec3.OpenAPI]
+	// documents are the serializations of the whole spec.
+	documents *openAPIV3Documents
+	// filtered are the serializations of subsets of the spec, keyed by
+	// documentFilter.key().
+	filtered map[string]*openAPIV3Documents
+
+	name     string
+	observer *metrics.AtomicObserver
+
+	// static is the spec set by UpdateStaticSpec, whose JSON is reused to
+	// serve it, or nil if the spec is lazy.
+	static atomic.Pointer[staticSpec]
+
+	// source is the source that registered the group version, or nil if
+	// it was registered directly. Protected by the lock on OpenAPIService.
+	source groupVersionSource
 }
 
-func newOpenAPIV3Group() *openAPIV3Group {
-	o := &openAPIV3Group{}
-	o.jsonCache = cached.Transform[*spec3.OpenAPI](func(spec *spec3.OpenAPI, etag string, err error) (timedSpec, string, error) {
+func newOpenAPIV3Group(name string, observer *metrics.AtomicObserver) *openAPIV3Group {
+	o := &openAPIV3Group{
+		name:     name,
+		observer: observer,
+		filtered: map[string]*openAPIV3Documents{},
+	}
+	o.documents = o.newDocuments(name, &o.specCache)
+	return o
+}
+
+// newDocuments returns the serializations of the spec, named after the
+// given name in the cache graph.
+func (o *openAPIV3Group) newDocuments(name string, openapi cached.ValueCtx[*spec3.OpenAPI]) *openAPIV3Documents {
+	d := &openAPIV3Documents{name: name, custom: map[string]encodedSpecs{}}
+	d.serializedCache = cached.NamedCtx(name+" serialized", cached.TransformCtx[*spec3.OpenAPI](func(ctx context.Context, spec *spec3.OpenAPI, etag string, err error) (serializedSpec, string, error) {
+		if err != nil {
+			return serializedSpec{}, "", err
+		}
+		data, ok := o.static.Load().marshaled(spec)
+		if !ok {
+			data, err = o.serialize(ctx, contentTypeJSON, func() ([]byte, error) { return json.Marshal(spec) })
+			if err != nil {
+				return serializedSpec{}, "", err
+			}
+		}
+		return serializedSpec{openapi: spec, json: timedSpec{spec: data, lastModified: time.Now()}}, computeETag(data), nil
+	}, openapi))
+	d.jsonCache = cached.NamedCtx(name+" "+contentTypeJSON, cached.TransformCtx(func(_ context.Context, s serializedSpec, etag string, err error) (timedSpec, string, error) {
+		return s.json, etag, err
+	}, d.serializedCache))
+	d.pbCache = cached.NamedCtx(name+" "+contentTypeProtobuf, cached.TransformCtx(func(ctx context.Context, ts timedSpec, etag string, err error) (timedSpec, string, error) {
 		if err != nil {
 			return timedSpec{}, "", err
 		}
-		json, err := json.Marshal(spec)
+		proto, err := o.serialize(ctx, contentTypeProtobuf, func() ([]byte, error) { return ToV3ProtoBinary(ts.spec) })
 		if err != nil {
 			return timedSpec{}, "", err
 		}
-		return timedSpec{spec: json, lastModified: time.Now()}, computeETag(json), nil
-	}, &o.specCache)
-	o.pbCache = cached.Transform(func(ts timedSpec, etag string, err error) (timedSpec, string, error) {
+		return timedSpec{spec: proto, lastModified: ts.lastModified}, etag, nil
+	}, d.jsonCache))
+	d.yamlCache = cached.NamedCtx(name+" "+contentTypeYAML, cached.TransformCtx(func(ctx context.Context, ts timedSpec, etag string, err error) (timedSpec, string, error) {
 		if err != nil {
 			return timedSpec{}, "", err
 		}
-		proto, err := ToV3ProtoBinary(ts.spec)
+		yaml, err := o.serialize(ctx, contentTypeYAML, func() ([]byte, error) { return yaml.JSONToYAML(ts.spec) })
 		if err != nil {
 			return timedSpec{}, "", err
 		}
-		return timedSpec{spec: proto, lastModified: ts.lastModified}, etag, nil
-	}, o.jsonCache)
-	return o
+		// We can re-use the same etag as json because of the Vary header.
+		return timedSpec{spec: yaml, lastModified: ts.lastModified}, etag, nil
+	}, d.jsonCache))
+	d.jsonEncoded = newEncodedSpecs(name+" "+contentTypeJSON, d.jsonCache)
+	d.pbEncoded = newEncodedSpecs(name+" "+contentTypeProtobuf, d.pbCache)
+	d.yamlEncoded = newEncodedSpecs(name+" "+contentTypeYAML, d.yamlCache)
+	return d
+}
+
+// customLocked returns the representation of the spec built by the
+// serializer, with its compressed variants.
+func (o *openAPIV3Group) customLocked(d *openAPIV3Documents, s *serializer) encodedSpecs {
+	if c, ok := d.custom[s.mediaType]; ok {
+		return c
+	}
+	name := d.name + " " + s.mediaType
+	c := newEncodedSpecs(name, cached.NamedCtx(name, cached.TransformCtx(func(ctx context.Context, spec serializedSpec, etag string, err error) (timedSpec, string, error) {
+		if err != nil {
+			return timedSpec{}, "", err
+		}
+		data, err := o.serialize(ctx, s.mediaType, func() ([]byte, error) { return s.fn(spec.openapi) })
+		if err != nil {
+			return timedSpec{}, "", err
+		}
+		// We can re-use the same etag as json because of the Vary header.
+		return timedSpec{spec: data, lastModified: spec.json.lastModified}, etag, nil
+	}, d.serializedCache)))
+	d.custom[s.mediaType] = c
+	return c
+}
+
+// documentsLocked returns the serializations of the subset of the spec
+// selected by the filter, creating them if needed.
+func (o *openAPIV3Group) documentsLocked(filter documentFilter) *openAPIV3Documents {
+	if filter.empty() {
+		return o.documents
+	}
+	key := filter.key()
+	if d, ok := o.filtered[key]; ok {
+		return d
+	}
+	if len(o.filtered) >= maxFilteredDocuments {
+		// Evict an arbitrary filter to bound the memory used by filters.
+		for k := range o.filtered {
+			delete(o.filtered, k)
+			break
+		}
+	}
+	name := o.name + "?" + key
+	d := o.newDocuments(name, cached.NamedCtx(name, cached.TransformCtx(func(_ context.Context, spec *spec3.OpenAPI, etag string, err error) (*spec3.OpenAPI, string, error) {
+		if err != nil {
+			return nil, "", err
+		}
+		return aggregator.SelectSpecV3PathsAndSchemas(spec, filter.paths, filter.schemas), etag, nil
+	}, &o.specCache)))
+	o.filtered[key] = d
+	return d
+}
+
+func (o *openAPIV3Group) UpdateSpec(openapi cached.ValueCtx[*spec3.OpenAPI]) {
+	o.static.Store(nil)
+	o.updateSpec(openapi)
+}
+
+// UpdateStaticSpec sets a static spec, whose etag is a hash of its JSON
+// serialization.
+func (o *openAPIV3Group) UpdateStaticSpec(openapi *spec3.OpenAPI) {
+	static := &staticSpec{group: o, openapi: openapi}
+	o.static.Store(static)
+	o.updateSpec(static)
+}
+
+func (o *openAPIV3Group) updateSpec(openapi cached.ValueCtx[*spec3.OpenAPI]) {
+	// The errors are observed before specCache hides them behind the last
+	// success.
+	o.specCache.Store(cached.TransformCtx(func(_ context.Context, spec *spec3.OpenAPI, etag string, err error) (*spec3.OpenAPI, string, error) {
+		if err != nil {
+			o.observer.Load().BuildError(o.name, err)
+		}
+		return spec, etag, err
+	}, cached.NamedCtx(o.name, openapi)))
+}
+
+// staticSpec is a static spec whose etag is a hash of its JSON
+// serialization. The JSON is computed the first time it is needed and
+// kept once successful, to be served without marshaling the spec again.
+type staticSpec struct {
+	group   *openAPIV3Group
+	openapi *spec3.OpenAPI
+
+	lock sync.Mutex
+	json []byte
+	etag string
+}
+
+func (s *staticSpec) Get(ctx context.Context) (*spec3.OpenAPI, string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.json == nil {
+		data, err := s.group.serialize(ctx, contentTypeJSON, func() ([]byte, error) { return json.Marshal(s.openapi) })
+		if err != nil {
+			return nil, "", err
+		}
+		s.json, s.etag = data, computeETag(data)
+	}
+	return s.openapi, s.etag, nil
 }
 
-func (o *openAPIV3Group) UpdateSpec(openapi cached.Value[*spec3.OpenAPI]) {
-	o.specCache.Store(openapi)
+// marshaled returns the JSON of openapi if it is the spec and has been
+// serialized.
+func (s *staticSpec) marshaled(openapi *spec3.OpenAPI) ([]byte, bool) {
+	if s == nil || s.openapi != openapi {
+		return nil, false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.json, s.json != nil
+}
+
+// serialize calls fn and reports it to the observer as a serialization
+// of the document with the given content type.
+func (o *openAPIV3Group) serialize(ctx context.Context, contentType string, fn func() ([]byte, error)) ([]byte, error) {
+	return serving.Serialize(ctx, o.observer.Load(), o.name, contentType, fn)
+}
+
+// get returns the document from the given cache, reporting whether it had
+// to be rebuilt to the observer.
+func (o *openAPIV3Group) get(ctx context.Context, contentType string, cache cached.ValueCtx[timedSpec]) (timedSpec, string, error) {
+	return serving.Get(ctx, o.observer.Load(), o.name, contentType, cache)
 }
 
 // OpenAPIService is the service responsible for serving OpenAPI spec. It has
@@ -115,7 +331,34 @@ type OpenAPIService struct {
 	mutex    sync.Mutex
 	v3Schema map[string]*openAPIV3Group
 
-	discoveryCache cached.LastSuccess[timedSpec]
+	discoveryCache   cached.LastSuccessCtx[timedSpec]
+	discoveryEncoded encodedSpecs
+
+	// watchers are signaled when the discovery document may have changed,
+	// protected by the mutex.
+	watchers map[chan struct{}]struct{}
+
+	// serializers are the additional representations of the spec, keyed
+	// by media type and protected by the mutex.
+	serializers map[string]*serializer
+
+	observer metrics.AtomicObserver
+
+	// sources are refreshed before serving a request, so that they can
+	// lazily add and remove group versions, protected by the mutex.
+	sources []groupVersionSource
+}
+
+// groupVersionSource adds and removes group versions of an OpenAPIService
+// lazily, e.g. V2Source.
+type groupVersionSource interface {
+	// refresh updates the group versions of the source. It returns an
+	// error if they are unknown because the source never succeeded.
+	refresh() error
+	// subscribe returns a channel signaled when the group versions of the
+	// source may have changed, and a function to cancel the subscription.
+	// The channel is nil if the source can't tell.
+	subscribe() (<-chan struct{}, func())
 }
 
 func computeETag(data []byte) string {
@@ -126,8 +369,13 @@ func computeETag(data []byte) string {
 }
 
 func constructServerRelativeURL(gvString, etag string) string {
+	return constructFilteredServerRelativeURL(gvString, etag, documentFilter{})
+}
+
+func constructFilteredServerRelativeURL(gvString, etag string, filter documentFilter) string {
 	u := url.URL{Path: path.Join("/openapi/v3", gvString)}
 	query := url.Values{}
+	filter.encode(query)
 	query.Set("hash", etag)
 	u.RawQuery = query.Encode()
 	return u.String()
@@ -137,17 +385,20 @@ func constructServerRelativeURL(gvString, etag string) string {
 func NewOpenAPIService() *OpenAPIService {
 	o := &OpenAPIService{}
 	o.v3Schema = make(map[string]*openAPIV3Group)
+	o.watchers = make(map[chan struct{}]struct{})
+	o.serializers = make(map[string]*serializer)
 	// We're not locked because we haven't shared the structure yet.
 	o.discoveryCache.Store(o.buildDiscoveryCacheLocked())
+	o.discoveryEncoded = newEncodedSpecs(discoveryName, &o.discoveryCache)
 	return o
 }
 
-func (o *OpenAPIService) buildDiscoveryCacheLocked() cached.Value[timedSpec] {
-	caches := make(map[string]cached.Value[timedSpec], len(o.v3Schema))
+func (o *OpenAPIService) buildDiscoveryCacheLocked() cached.ValueCtx[timedSpec] {
+	caches := make(map[string]cached.ValueCtx[timedSpec], len(o.v3Schema))
 	for gvName, group := range o.v3Schema {
-		caches[gvName] = group.jsonCache
+		caches[gvName] = group.documents.jsonCache
 	}
-	return cached.Merge(func(results map[string]cached.Result[timedSpec]) (timedSpec, string, error) {
+	return cached.NamedCtx(discoveryName, cached.MergeCtx(func(_ context.Context, results map[string]cached.Result[timedSpec]) (timedSpec, string, error) {
 		discovery := &OpenAPIV3Discovery{Paths: make(map[string]OpenAPIV3DiscoveryGroupVersion)}
 		for gvName, result := range results {
 			if result.Err != nil {
@@ -162,42 +413,118 @@ func (o *OpenAPIService) buildDiscoveryCacheLocked() cached.Value[timedSpec] {
 			return timedSpec{}, "", err
 		}
 		return timedSpec{spec: j, lastModified: time.Now()}, computeETag(j), nil
-	}, caches)
+	}, caches))
+}
+
+func (o *OpenAPIService) hasGroupVersion(group string) bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	_, ok := o.v3Schema[group]
+	return ok
+}
+
+// RegisterSerializer adds a representation of the group versions, served
+// when requested with the given media type (e.g. "application/cbor"). The
+// representation is built lazily from the spec, cached, and shares its etag
+// with the JSON representation. Built-in media types can't be replaced.
+func (o *OpenAPIService) RegisterSerializer(mediaType string, fn func(*spec3.OpenAPI) ([]byte, error)) error {
+	mediaType, err := serving.ParseSerializerMediaType(mediaType, contentTypeJSON, contentTypeProtobuf, "application/"+subTypeProtobufDeprecated, contentTypeYAML)
+	if err != nil {
+		return err
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if _, ok := o.serializers[mediaType]; ok {
+		return fmt.Errorf("media type %q is already registered", mediaType)
+	}
+	o.serializers[mediaType] = &serializer{mediaType: mediaType, fn: fn}
+	return nil
+}
+
+// registeredMediaTypes returns the media types of the registered
+// serializers, sorted.
+func (o *OpenAPIService) registeredMediaTypes() []string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	mediaTypes := make([]string, 0, len(o.serializers))
+	for mediaType := range o.serializers {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+	return mediaTypes
 }
 
-func (o *OpenAPIService) getSingleGroupBytes(getType string, group string) ([]byte, string, time.Time, error) {
+func (o *OpenAPIService) getSingleGroupBytes(ctx context.Context, contentType string, group string, encoding string, filter documentFilter) ([]byte, string, time.Time, error) {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 	v, ok := o.v3Schema[group]
 	if !ok {
-		return nil, "", time.Now(), fmt.Errorf("Cannot find CRD group %s", group)
+		return nil, "", time.Now(), fmt.Errorf("%w: %s", errGroupVersionNotFound, group)
 	}
-	switch getType {
-	case subTypeJSON:
-		ts, etag, err := v.jsonCache.Get()
+	d := v.documentsLocked(filter)
+	switch contentType {
+	case contentTypeJSON:
+		ts, etag, err := v.get(ctx, contentTypeJSON, d.jsonEncoded.get(encoding))
 		return ts.spec, etag, ts.lastModified, err
-	case subTypeProtobuf, subTypeProtobufDeprecated:
-		ts, etag, err := v.pbCache.Get()
+	case contentTypeProtobuf:
+		ts, etag, err := v.get(ctx, contentTypeProtobuf, d.pbEncoded.get(encoding))
+		return ts.spec, etag, ts.lastModified, err
+	case contentTypeYAML:
+		ts, etag, err := v.get(ctx, contentTypeYAML, d.yamlEncoded.get(encoding))
 		return ts.spec, etag, ts.lastModified, err
 	default:
-		return nil, "", time.Now(), fmt.Errorf("Invalid accept clause %s", getType)
+		s, ok := o.serializers[contentType]
+		if !ok {
+			return nil, "", time.Now(), fmt.Errorf("Invalid accept clause %s", contentType)
+		}
+		ts, etag, err := v.get(ctx, contentType, v.customLocked(d, s).get(encoding))
+		return ts.spec, etag, ts.lastModified, err
 	}
 }
 
+// SetObserver sets the observer notified when group versions are built and
+// served. It can be called at any time.
+func (o *OpenAPIService) SetObserver(observer metrics.Observer) {
+	o.observer.Store(observer)
+}
+
 // UpdateGroupVersionLazy adds or updates an existing group with the new cached.
 func (o *OpenAPIService) UpdateGroupVersionLazy(group string, openapi cached.Value[*spec3.OpenAPI]) {
+	o.UpdateGroupVersionLazyCtx(group, cached.WithContext(openapi))
+}
+
+// UpdateGroupVersionLazyCtx is the same as UpdateGroupVersionLazy, with the
+// context of the request building the spec passed to the cache.
+func (o *OpenAPIService) UpdateGroupVersionLazyCtx(group string, openapi cached.ValueCtx[*spec3.OpenAPI]) {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
+	g := o.groupLocked(group)
+	g.source = nil
+	g.UpdateSpec(openapi)
+	o.notifyWatchersLocked()
+}
+
+// UpdateGroupVersion adds or updates an existing group with a static spec.
+// The etag of the spec is a hash of its JSON serialization, computed
+// lazily, so that identical specs have identical etags across updates and
+// processes. The serialization is also the served JSON document.
+func (o *OpenAPIService) UpdateGroupVersion(group string, openapi *spec3.OpenAPI) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	g := o.groupLocked(group)
+	g.source = nil
+	g.UpdateStaticSpec(openapi)
+	o.notifyWatchersLocked()
+}
+
+// groupLocked returns the group, creating it if needed.
+func (o *OpenAPIService) groupLocked(group string) *openAPIV3Group {
 	if _, ok := o.v3Schema[group]; !ok {
-		o.v3Schema[group] = newOpenAPIV3Group()
+		o.v3Schema[group] = newOpenAPIV3Group(group, &o.observer)
 		// Since there is a new item, we need to re-build the cache map.
 		o.discoveryCache.Store(o.buildDiscoveryCacheLocked())
 	}
-	o.v3Schema[group].UpdateSpec(openapi)
-}
-
-func (o *OpenAPIService) UpdateGroupVersion(group string, openapi *spec3.OpenAPI) {
-	o.UpdateGroupVersionLazy(group, cached.Static(openapi, uuid.New().String()))
+	return o.v3Schema[group]
 }
 
 func (o *OpenAPIService) DeleteGroupVersion(group string) {
@@ -206,17 +533,78 @@ func (o *OpenAPIService) DeleteGroupVersion(group string) {
 	delete(o.v3Schema, group)
 	// Rebuild the merge cache map since the items have changed.
 	o.discoveryCache.Store(o.buildDiscoveryCacheLocked())
+	o.notifyWatchersLocked()
+}
+
+// addSource registers a source refreshed before serving a request.
+func (o *OpenAPIService) addSource(source groupVersionSource) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.sources = append(o.sources, source)
+}
+
+// refreshSources refreshes the registered sources, and returns an error if
+// the group versions of any of them are unknown. It must be called without
+// the mutex held since the sources update the group versions.
+func (o *OpenAPIService) refreshSources() error {
+	o.mutex.Lock()
+	sources := o.sources
+	o.mutex.Unlock()
+	var errs []error
+	for _, source := range sources {
+		if err := source.refresh(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncSourceLocked registers the group versions of the source that are not
+// registered yet with the spec returned by newSpec, and deletes the group
+// versions registered by the source that are not in groups anymore. Group
+// versions registered by other means are left alone.
+func (o *OpenAPIService) syncSourceLocked(source groupVersionSource, groups map[string]bool, newSpec func(group string) cached.ValueCtx[*spec3.OpenAPI]) {
+	changed := false
+	for group := range groups {
+		if _, ok := o.v3Schema[group]; !ok {
+			g := o.groupLocked(group)
+			g.source = source
+			g.UpdateSpec(newSpec(group))
+			changed = true
+		}
+	}
+	for name, g := range o.v3Schema {
+		if g.source == source && !groups[name] {
+			delete(o.v3Schema, name)
+			o.discoveryCache.Store(o.buildDiscoveryCacheLocked())
+			changed = true
+		}
+	}
+	if changed {
+		o.notifyWatchersLocked()
+	}
 }
 
 func (o *OpenAPIService) HandleDiscovery(w http.ResponseWriter, r *http.Request) {
-	ts, etag, err := o.discoveryCache.Get()
+	if isWatch(r) {
+		o.watchDiscovery(w, r)
+		return
+	}
+	if err := o.refreshSources(); err != nil {
+		klog.Errorf("Error serving discovery: %s", err)
+		writeUnavailable(w)
+		return
+	}
+	encoding := negotiateEncoding(r)
+	ts, etag, err := o.discoveryEncoded.get(encoding).Get(r.Context())
 	if err != nil {
 		klog.Errorf("Error serving discovery: %s", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeUnavailable(w)
 		return
 	}
-	w.Header().Set("Etag", strconv.Quote(etag))
+	w.Header().Set("Etag", strconv.Quote(encodedETag(etag, encoding)))
 	w.Header().Set("Content-Type", "application/json")
+	setEncodingHeaders(w, encoding)
 	http.ServeContent(w, r, "/openapi/v3", ts.lastModified, bytes.NewReader(ts.spec))
 }
 
@@ -229,22 +617,45 @@ func (o *OpenAPIService) HandleGroupVersion(w http.ResponseWriter, r *http.Reque
 		decipherableFormats = "*/*"
 	}
 	clauses := goautoneg.ParseAccept(decipherableFormats)
+	// The Vary header is required because the Accept header can
+	// change the contents returned. This prevents clients from caching
+	// protobuf as JSON and vice versa.
 	w.Header().Add("Vary", "Accept")
+	encoding := negotiateEncoding(r)
 
 	if len(clauses) == 0 {
 		return
 	}
 
-	accepted := []struct {
+	if err := o.refreshSources(); err != nil && !o.hasGroupVersion(group) {
+		// The group version may be served by a source that isn't ready.
+		klog.Errorf("Error serving OpenAPI for %s: %s", group, err)
+		writeUnavailable(w)
+		return
+	}
+	if !o.hasGroupVersion(group) {
+		http.Error(w, fmt.Sprintf("group version %q not found", group), http.StatusNotFound)
+		return
+	}
+
+	type acceptedType struct {
 		Type                string
 		SubType             string
 		ReturnedContentType string
-	}{
-		{"application", subTypeJSON, "application/" + subTypeJSON},
-		{"application", subTypeProtobuf, "application/" + subTypeProtobuf},
-		{"application", subTypeProtobufDeprecated, "application/" + subTypeProtobuf},
+	}
+	accepted := []acceptedType{
+		{"application", subTypeJSON, contentTypeJSON},
+		{"application", subTypeProtobuf, contentTypeProtobuf},
+		{"application", subTypeProtobufDeprecated, contentTypeProtobuf},
+		{"application", subTypeYAML, contentTypeYAML},
+	}
+	for _, mediaType := range o.registeredMediaTypes() {
+		t, subType, _ := strings.Cut(mediaType, "/")
+		accepted = append(accepted, acceptedType{t, subType, mediaType})
 	}
 
+	hash := r.URL.Query().Get("hash")
+	filter := parseDocumentFilter(r.URL.Query())
 	for _, clause := range clauses {
 		for _, accepts := range accepted {
 			if clause.Type != accepts.Type && clause.Type != "*" {
@@ -253,34 +664,47 @@ func (o *OpenAPIService) HandleGroupVersion(w http.ResponseWriter, r *http.Reque
 			if clause.SubType != accepts.SubType && clause.SubType != "*" {
 				continue
 			}
-			data, etag, lastModified, err := o.getSingleGroupBytes(accepts.SubType, group)
+			setEncodingHeaders(w, encoding)
+
+			// The content of a URL with a hash never changes, so a client
+			// that already has it doesn't need the spec to be built at all.
+			if hash != "" && etagMatches(r.Header.Get("If-None-Match"), encodedETag(hash, encoding)) {
+				w.Header().Set("Etag", strconv.Quote(encodedETag(hash, encoding)))
+				setImmutableCacheHeaders(w)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			data, etag, lastModified, err := o.getSingleGroupBytes(r.Context(), accepts.ReturnedContentType, group, encoding, filter)
+			if errors.Is(err, errGroupVersionNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
 			if err != nil {
+				klog.Errorf("Error serving OpenAPI for %s: %s", group, err)
+				writeUnavailable(w)
 				return
 			}
 			// Set Content-Type header in the reponse
 			w.Header().Set("Content-Type", accepts.ReturnedContentType)
 
 			// ETag must be enclosed in double quotes: https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/ETag
-			w.Header().Set("Etag", strconv.Quote(etag))
+			w.Header().Set("Etag", strconv.Quote(encodedETag(etag, encoding)))
 
-			if hash := r.URL.Query().Get("hash"); hash != "" {
+			if hash != "" {
 				if hash != etag {
-					u := constructServerRelativeURL(group, etag)
+					u := constructFilteredServerRelativeURL(group, etag, filter)
 					http.Redirect(w, r, u, 301)
 					return
 				}
-				// The Vary header is required because the Accept header can
-				// change the contents returned. This prevents clients from caching
-				// protobuf as JSON and vice versa.
-				w.Header().Set("Vary", "Accept")
-
 				// Only set these headers when a hash is given.
-				w.Header().Set("Cache-Control", "public, immutable")
-				// Set the Expires directive to the maximum value of one year from the request,
-				// effectively indicating that the cache never expires.
-				w.Header().Set("Expires", time.Now().AddDate(1, 0, 0).Format(time.RFC1123))
+				setImmutableCacheHeaders(w)
 			}
-			http.ServeContent(w, r, "", lastModified, bytes.NewReader(data))
+			// The data is already compressed, so the bytes served are
+			// counted after compression, like in OpenAPI v2.
+			cw := &serving.CountingResponseWriter{ResponseWriter: w}
+			http.ServeContent(cw, r, "", lastModified, bytes.NewReader(data))
+			o.observer.Load().Served(group, accepts.ReturnedContentType, cw.Written)
 			return
 		}
 	}
@@ -288,6 +712,38 @@ func (o *OpenAPIService) HandleGroupVersion(w http.ResponseWriter, r *http.Reque
 	return
 }
 
+// setImmutableCacheHeaders marks the response as cacheable forever, which is
+// only correct for URLs that include the hash of their content.
+func setImmutableCacheHeaders(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "public, immutable")
+	// Set the Expires directive to the maximum value of one year from the request,
+	// effectively indicating that the cache never expires.
+	w.Header().Set("Expires", time.Now().AddDate(1, 0, 0).Format(time.RFC1123))
+}
+
+// writeUnavailable responds with a 503, asking the client to retry once the
+// spec had a chance to build.
+func writeUnavailable(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+	w.WriteHeader(http.StatusServiceUnavailable)
+}
+
+// etagMatches returns true if the If-None-Match header value matches the
+// given etag, using the weak comparison function from RFC 9110.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		candidate = strings.TrimPrefix(candidate, "W/")
+		if unquoted, err := strconv.Unquote(candidate); err == nil && unquoted == etag {
+			return true
+		}
+	}
+	return false
+}
+
 func (o *OpenAPIService) RegisterOpenAPIV3VersionedService(servePath string, handler common.PathHandlerByGroupVersion) error {
 	handler.Handle(servePath, http.HandlerFunc(o.HandleDiscovery))
 	handler.HandlePrefix(servePath+"/", http.HandlerFunc(o.HandleGroupVersion))
@@ -18,19 +18,31 @@ package handler3
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"encoding/json"
 
+	"github.com/klauspost/compress/zstd"
+
+	"k8s.io/kube-openapi/pkg/cached"
+	"k8s.io/kube-openapi/pkg/metrics"
 	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
 )
 
 var returnedOpenAPI = []byte(`{
@@ -65,6 +77,11 @@ func TestRegisterOpenAPIVersionedService(t *testing.T) {
 		t.Fatalf("Unexpected error in preparing returnedPb: %v", err)
 	}
 
+	returnedYAML, err := yaml.JSONToYAML(returnedJSON)
+	if err != nil {
+		t.Fatalf("Unexpected error in preparing returnedYAML: %v", err)
+	}
+
 	mux := http.NewServeMux()
 	o := NewOpenAPIService()
 	if err != nil {
@@ -78,7 +95,7 @@ func TestRegisterOpenAPIVersionedService(t *testing.T) {
 
 	server := httptest.NewServer(mux)
 	defer server.Close()
-	client := server.Client()
+	client := identityClient(server)
 
 	tcs := []struct {
 		acceptHeader              string
@@ -225,6 +242,27 @@ func TestRegisterOpenAPIVersionedService(t *testing.T) {
 			respBody:                  returnedJSON,
 			expectedETag:              computeETag(returnedJSON),
 			responseContentTypeHeader: "application/json",
+		}, {
+			acceptHeader:              "application/yaml",
+			respStatus:                200,
+			urlPath:                   "openapi/v3/apis/apps/v1",
+			respBody:                  returnedYAML,
+			expectedETag:              computeETag(returnedJSON),
+			responseContentTypeHeader: "application/yaml",
+		}, {
+			acceptHeader: "application/yaml",
+			respStatus:   304,
+			urlPath:      "openapi/v3/apis/apps/v1",
+			respBody:     returnedYAML,
+			expectedETag: computeETag(returnedJSON),
+			sendETag:     true,
+		}, {
+			acceptHeader:              "application/yaml; q=0.5, application/json",
+			respStatus:                200,
+			urlPath:                   "openapi/v3/apis/apps/v1",
+			respBody:                  returnedJSON,
+			expectedETag:              computeETag(returnedJSON),
+			responseContentTypeHeader: "application/json",
 		},
 	}
 
@@ -449,8 +487,16 @@ func openAPIOrDie(name string) *spec3.OpenAPI {
 	return &spec
 }
 
-func getDiscovery(server *httptest.Server, path string) (*OpenAPIV3Discovery, string, error) {
+// identityClient returns a client for the server that doesn't ask for
+// compressed responses, so that etags are the ones of the identity encoding.
+func identityClient(server *httptest.Server) *http.Client {
 	client := server.Client()
+	client.Transport.(*http.Transport).DisableCompression = true
+	return client
+}
+
+func getDiscovery(server *httptest.Server, path string) (*OpenAPIV3Discovery, string, error) {
+	client := identityClient(server)
 	req, err := http.NewRequest("GET", server.URL+"/"+path, nil)
 	if err != nil {
 		return nil, "", fmt.Errorf("error in creating new request: %v", err)
@@ -538,3 +584,1102 @@ func TestUpdateGroupVersion(t *testing.T) {
 		t.Fatalf("Invalid number of Paths, expected 2: %v", discovery.Paths)
 	}
 }
+
+func TestContentEncoding(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIService()
+
+	mux.Handle("/openapi/v3", http.HandlerFunc(o.HandleDiscovery))
+	mux.Handle("/openapi/v3/apis/apps/v1", http.HandlerFunc(o.HandleGroupVersion))
+
+	spec := openAPIOrDie("apps-v1")
+	o.UpdateGroupVersion("apis/apps/v1", spec)
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	// Disable the transparent decompression of the default transport so
+	// that the encoded bodies can be inspected.
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+
+	decoders := map[string]func([]byte) ([]byte, error){
+		"": func(b []byte) ([]byte, error) { return b, nil },
+		"gzip": func(b []byte) ([]byte, error) {
+			r, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(r)
+		},
+		"zstd": func(b []byte) ([]byte, error) {
+			d, err := zstd.NewReader(nil)
+			if err != nil {
+				return nil, err
+			}
+			defer d.Close()
+			return d.DecodeAll(b, nil)
+		},
+	}
+
+	tcs := []struct {
+		acceptEncoding   string
+		expectedEncoding string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"zstd", "zstd"},
+		{"gzip, zstd", "zstd"},
+		{"gzip, zstd;q=0.5", "gzip"},
+		{"zstd;q=0, gzip", "gzip"},
+		{"*", "zstd"},
+		{"br", ""},
+		{"gzip;q=0", ""},
+	}
+
+	for _, urlPath := range []string{"openapi/v3", "openapi/v3/apis/apps/v1", "openapi/v3/apis/apps/v1?hash=" + computeETag(specJSON)} {
+		var expectedETag string
+		for _, tc := range tcs {
+			req, err := http.NewRequest("GET", server.URL+"/"+urlPath, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error in creating new request: %v", err)
+			}
+			req.Header.Set("Accept", "application/json")
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Accept-Encoding: %v: Unexpected error in serving HTTP request: %v", tc.acceptEncoding, err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("Accept-Encoding: %v: Unexpected error in reading response body: %v", tc.acceptEncoding, err)
+			}
+			if resp.StatusCode != 200 {
+				t.Fatalf("Accept-Encoding: %v: Unexpected response status code, want: 200, got: %v", tc.acceptEncoding, resp.StatusCode)
+			}
+			if got := resp.Header.Get("Content-Encoding"); got != tc.expectedEncoding {
+				t.Errorf("Accept-Encoding: %v: Expected Content-Encoding %q, got %q", tc.acceptEncoding, tc.expectedEncoding, got)
+				continue
+			}
+			if !strings.Contains(strings.Join(resp.Header.Values("Vary"), ","), "Accept-Encoding") {
+				t.Errorf("Accept-Encoding: %v: Expected Vary to contain Accept-Encoding, got %v", tc.acceptEncoding, resp.Header.Values("Vary"))
+			}
+			// Each encoding of the same document has its own etag.
+			if expectedETag == "" {
+				expectedETag, _ = strconv.Unquote(resp.Header.Get("Etag"))
+			}
+			wantETag := expectedETag
+			if tc.expectedEncoding != "" {
+				wantETag += "-" + tc.expectedEncoding
+			}
+			if got := resp.Header.Get("Etag"); got != strconv.Quote(wantETag) {
+				t.Errorf("Accept-Encoding: %v: Expected Etag %q, got %v", tc.acceptEncoding, wantETag, got)
+			}
+			if tc.expectedEncoding != "" {
+				// The etag of another encoding doesn't validate this one.
+				for ifNoneMatch, status := range map[string]int{wantETag: http.StatusNotModified, expectedETag: http.StatusOK} {
+					req.Header.Set("If-None-Match", strconv.Quote(ifNoneMatch))
+					resp, err := client.Do(req)
+					if err != nil {
+						t.Fatalf("Accept-Encoding: %v: Unexpected error in serving HTTP request: %v", tc.acceptEncoding, err)
+					}
+					resp.Body.Close()
+					if resp.StatusCode != status {
+						t.Errorf("Accept-Encoding: %v: If-None-Match: %v: Expected status %v, got %v", tc.acceptEncoding, ifNoneMatch, status, resp.StatusCode)
+					}
+				}
+			}
+			decoded, err := decoders[tc.expectedEncoding](body)
+			if err != nil {
+				t.Fatalf("Accept-Encoding: %v: Failed to decode body: %v", tc.acceptEncoding, err)
+			}
+			if urlPath == "openapi/v3" {
+				discovery := &OpenAPIV3Discovery{}
+				if err := json.Unmarshal(decoded, discovery); err != nil {
+					t.Errorf("Accept-Encoding: %v: Failed to unmarshal discovery: %v", tc.acceptEncoding, err)
+				}
+			} else if !bytes.Equal(decoded, specJSON) {
+				t.Errorf("Accept-Encoding: %v: Response body mismatches, \nwant: %s, \ngot:  %s", tc.acceptEncoding, specJSON, decoded)
+			}
+		}
+	}
+}
+
+func TestGroupVersionErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIService()
+	mux.Handle("/openapi/v3", http.HandlerFunc(o.HandleDiscovery))
+	mux.Handle("/openapi/v3/", http.HandlerFunc(o.HandleGroupVersion))
+
+	o.UpdateGroupVersionLazy("apis/broken/v1", cached.Func(func() (*spec3.OpenAPI, string, error) {
+		return nil, "", fmt.Errorf("spec is not ready")
+	}))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := server.Client()
+
+	tcs := []struct {
+		urlPath    string
+		respStatus int
+		retryAfter string
+	}{
+		{"openapi/v3/apis/unknown/v1", 404, ""},
+		{"openapi/v3/apis/unknown/v1?hash=SOMEHASH", 404, ""},
+		{"openapi/v3/apis/broken/v1", 503, "1"},
+		{"openapi/v3", 503, "1"},
+	}
+	for _, tc := range tcs {
+		resp, err := client.Get(server.URL + "/" + tc.urlPath)
+		if err != nil {
+			t.Fatalf("%v: Unexpected error in serving HTTP request: %v", tc.urlPath, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.respStatus {
+			t.Errorf("%v: Unexpected response status code, want: %v, got: %v", tc.urlPath, tc.respStatus, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Retry-After"); got != tc.retryAfter {
+			t.Errorf("%v: Expected Retry-After %q, got %q", tc.urlPath, tc.retryAfter, got)
+		}
+	}
+}
+
+func TestNotModifiedWithHash(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIService()
+	mux.Handle("/openapi/v3/", http.HandlerFunc(o.HandleGroupVersion))
+
+	calls := 0
+	o.UpdateGroupVersionLazy("apis/apps/v1", cached.Func(func() (*spec3.OpenAPI, string, error) {
+		calls++
+		return openAPIOrDie("apps-v1"), "apps-v1", nil
+	}))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := identityClient(server)
+
+	tcs := []struct {
+		ifNoneMatch string
+		respStatus  int
+	}{
+		{`"SOMEHASH"`, 304},
+		{`W/"SOMEHASH"`, 304},
+		{`"OTHERHASH", "SOMEHASH"`, 304},
+		{`*`, 304},
+	}
+	for _, tc := range tcs {
+		req, err := http.NewRequest("GET", server.URL+"/openapi/v3/apis/apps/v1?hash=SOMEHASH", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error in creating new request: %v", err)
+		}
+		req.Header.Set("If-None-Match", tc.ifNoneMatch)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("If-None-Match: %v: Unexpected error in serving HTTP request: %v", tc.ifNoneMatch, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.respStatus {
+			t.Errorf("If-None-Match: %v: Unexpected response status code, want: %v, got: %v", tc.ifNoneMatch, tc.respStatus, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Etag"); got != `"SOMEHASH"` {
+			t.Errorf("If-None-Match: %v: Expected Etag %q, got %q", tc.ifNoneMatch, `"SOMEHASH"`, got)
+		}
+		if got := resp.Header.Get("Cache-Control"); got != "public, immutable" {
+			t.Errorf("If-None-Match: %v: Expected Cache-Control %q, got %q", tc.ifNoneMatch, "public, immutable", got)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("Expected the spec not to be built, but it was built %d times", calls)
+	}
+
+	// A non-matching etag still requires the spec to be built.
+	req, err := http.NewRequest("GET", server.URL+"/openapi/v3/apis/apps/v1?hash=SOMEHASH", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error in creating new request: %v", err)
+	}
+	req.Header.Set("If-None-Match", `"OTHERHASH"`)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("Unexpected response status code, want: 200, got: %v", resp.StatusCode)
+	}
+	if calls == 0 {
+		t.Errorf("Expected the spec to be built")
+	}
+}
+
+func TestWatchDiscovery(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIService()
+	mux.Handle("/openapi/v3", http.HandlerFunc(o.HandleDiscovery))
+
+	o.UpdateGroupVersion("apis/apps/v1", openAPIOrDie("apps-v1"))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/openapi/v3?watch=true")
+	if err != nil {
+		t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("Unexpected response status code, want: 200, got: %v", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/json;stream=watch" {
+		t.Errorf("Unexpected content type in response, want: application/json;stream=watch, got: %v", got)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	nextEvent := func() OpenAPIV3DiscoveryEvent {
+		t.Helper()
+		event := OpenAPIV3DiscoveryEvent{}
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatalf("Failed to decode event: %v", err)
+		}
+		return event
+	}
+
+	event := nextEvent()
+	if !reflect.DeepEqual(event.Changed, []string{"apis/apps/v1"}) || len(event.Removed) != 0 {
+		t.Errorf("Unexpected initial event: %+v", event)
+	}
+	_, discoveryEtag, err := getDiscovery(server, "/openapi/v3")
+	if err != nil {
+		t.Fatalf("failed to get /openapi/v3: %v", err)
+	}
+	if strconv.Quote(event.Etag) != discoveryEtag {
+		t.Errorf("Expected event etag %v, got %v", discoveryEtag, strconv.Quote(event.Etag))
+	}
+
+	o.UpdateGroupVersion("apis/something/v1", openAPIOrDie("something-v1"))
+	event = nextEvent()
+	if !reflect.DeepEqual(event.Changed, []string{"apis/something/v1"}) || len(event.Removed) != 0 {
+		t.Errorf("Unexpected event after adding a group version: %+v", event)
+	}
+	if len(event.Discovery.Paths) != 2 {
+		t.Errorf("Invalid number of Paths, expected 2: %v", event.Discovery.Paths)
+	}
+
+	// A no-op update doesn't change the discovery, so no event is sent for it.
+	o.UpdateGroupVersion("apis/apps/v1", openAPIOrDie("apps-v1"))
+	o.UpdateGroupVersion("apis/apps/v1", openAPIOrDie("apps-v1-updated"))
+	event = nextEvent()
+	if !reflect.DeepEqual(event.Changed, []string{"apis/apps/v1"}) || len(event.Removed) != 0 {
+		t.Errorf("Unexpected event after updating a group version: %+v", event)
+	}
+
+	o.DeleteGroupVersion("apis/something/v1")
+	event = nextEvent()
+	if len(event.Changed) != 0 || !reflect.DeepEqual(event.Removed, []string{"apis/something/v1"}) {
+		t.Errorf("Unexpected event after deleting a group version: %+v", event)
+	}
+	if len(event.Discovery.Paths) != 1 {
+		t.Errorf("Invalid number of Paths, expected 1: %v", event.Discovery.Paths)
+	}
+}
+
+func TestWatchDiscoveryError(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIService()
+	mux.Handle("/openapi/v3", http.HandlerFunc(o.HandleDiscovery))
+
+	o.UpdateGroupVersionLazy("apis/apps/v1", cached.Func(func() (*spec3.OpenAPI, string, error) {
+		return nil, "", errors.New("spec is not ready")
+	}))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/openapi/v3?watch=true")
+	if err != nil {
+		t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The error is sent and the stream is closed, for the client to watch
+	// again.
+	decoder := json.NewDecoder(resp.Body)
+	event := OpenAPIV3DiscoveryEvent{}
+	if err := decoder.Decode(&event); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if event.Error != "spec is not ready" || event.Etag != "" || len(event.Changed) != 0 {
+		t.Errorf("Unexpected error event: %+v", event)
+	}
+	if err := decoder.Decode(&event); err != io.EOF {
+		t.Errorf("Expected the stream to be closed, got: %v", err)
+	}
+}
+
+func TestFilteredGroupVersion(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIService()
+	mux.Handle("/openapi/v3/", http.HandlerFunc(o.HandleGroupVersion))
+
+	openapi := &spec3.OpenAPI{}
+	if err := json.Unmarshal([]byte(`{
+  "openapi": "3.0",
+  "info": {"title": "apps", "version": "v1"},
+  "paths": {
+    "/apis/apps/v1/deployments": {"get": {"responses": {"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/DeploymentList"}}}}}}},
+    "/apis/apps/v1/statefulsets": {"get": {"responses": {"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/StatefulSet"}}}}}}}
+  },
+  "components": {"schemas": {
+    "DeploymentList": {"type": "object", "properties": {"items": {"type": "array", "items": {"$ref": "#/components/schemas/Deployment"}}}},
+    "Deployment": {"type": "object", "properties": {"metadata": {"$ref": "#/components/schemas/ObjectMeta"}}},
+    "StatefulSet": {"type": "object", "properties": {"metadata": {"$ref": "#/components/schemas/ObjectMeta"}}},
+    "ObjectMeta": {"type": "object"}
+  }}}`), openapi); err != nil {
+		t.Fatal(err)
+	}
+	o.UpdateGroupVersion("apis/apps/v1", openapi)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := identityClient(server)
+
+	tcs := []struct {
+		query           string
+		expectedPaths   []string
+		expectedSchemas []string
+	}{
+		{"", []string{"/apis/apps/v1/deployments", "/apis/apps/v1/statefulsets"}, []string{"Deployment", "DeploymentList", "ObjectMeta", "StatefulSet"}},
+		{"?schemas=Deployment", nil, []string{"Deployment", "ObjectMeta"}},
+		{"?schemas=StatefulSet,Deployment", nil, []string{"Deployment", "ObjectMeta", "StatefulSet"}},
+		{"?paths=/apis/apps/v1/deployments", []string{"/apis/apps/v1/deployments"}, []string{"Deployment", "DeploymentList", "ObjectMeta"}},
+		{"?paths=/apis/apps/v1/statefulsets&schemas=Deployment", []string{"/apis/apps/v1/statefulsets"}, []string{"Deployment", "ObjectMeta", "StatefulSet"}},
+		{"?schemas=Unknown", nil, nil},
+		{"?schemas=%25zz,Deployment", nil, []string{"Deployment", "ObjectMeta"}},
+	}
+	for _, tc := range tcs {
+		resp, err := client.Get(server.URL + "/openapi/v3/apis/apps/v1" + tc.query)
+		if err != nil {
+			t.Fatalf("%v: Unexpected error in serving HTTP request: %v", tc.query, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%v: Unexpected error in reading response body: %v", tc.query, err)
+		}
+		if resp.StatusCode != 200 {
+			t.Fatalf("%v: Unexpected response status code, want: 200, got: %v", tc.query, resp.StatusCode)
+		}
+
+		got := &spec3.OpenAPI{}
+		if err := json.Unmarshal(body, got); err != nil {
+			t.Fatalf("%v: Failed to unmarshal response: %v", tc.query, err)
+		}
+		var gotPaths, gotSchemas []string
+		for p := range got.Paths.Paths {
+			gotPaths = append(gotPaths, p)
+		}
+		if got.Components != nil {
+			for s := range got.Components.Schemas {
+				gotSchemas = append(gotSchemas, s)
+			}
+		}
+		sort.Strings(gotPaths)
+		sort.Strings(gotSchemas)
+		if !reflect.DeepEqual(gotPaths, tc.expectedPaths) {
+			t.Errorf("%v: Expected paths %v, got %v", tc.query, tc.expectedPaths, gotPaths)
+		}
+		if !reflect.DeepEqual(gotSchemas, tc.expectedSchemas) {
+			t.Errorf("%v: Expected schemas %v, got %v", tc.query, tc.expectedSchemas, gotSchemas)
+		}
+		if etag := resp.Header.Get("Etag"); etag != strconv.Quote(computeETag(body)) {
+			t.Errorf("%v: Expected Etag %v, got %v", tc.query, strconv.Quote(computeETag(body)), etag)
+		}
+	}
+
+	// A stale hash redirects to the filtered document with the right hash.
+	resp, err := client.Get(server.URL + "/openapi/v3/apis/apps/v1?schemas=Deployment&hash=OUTDATEDHASH")
+	if err != nil {
+		t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error in reading response body: %v", err)
+	}
+	if got, expected := resp.Request.URL.Query().Get("schemas"), "Deployment"; got != expected {
+		t.Errorf("Expected redirect to keep schemas=%v, got %v", expected, got)
+	}
+	if got, expected := resp.Request.URL.Query().Get("hash"), computeETag(body); got != expected {
+		t.Errorf("Expected redirect to hash %v, got %v", expected, got)
+	}
+	if cacheControl := resp.Header.Get("Cache-Control"); cacheControl != "public, immutable" {
+		t.Errorf("Expected Cache Control %v, got %v", "public, immutable", cacheControl)
+	}
+}
+
+func TestRegisterSerializer(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIService()
+	mux.Handle("/openapi/v3/", http.HandlerFunc(o.HandleGroupVersion))
+	o.UpdateGroupVersion("apis/apps/v1", openAPIOrDie("apps"))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := server.Client()
+
+	calls := 0
+	if err := o.RegisterSerializer("application/x-title", func(openapi *spec3.OpenAPI) ([]byte, error) {
+		calls++
+		return []byte(openapi.Info.Title), nil
+	}); err != nil {
+		t.Fatalf("Unexpected error registering serializer: %v", err)
+	}
+	for _, mediaType := range []string{"application/x-title", "application/json", "application/yaml", "application/com.github.proto-openapi.spec.v3@v1.0+protobuf", "application/*", "invalid"} {
+		if err := o.RegisterSerializer(mediaType, func(*spec3.OpenAPI) ([]byte, error) { return nil, nil }); err == nil {
+			t.Errorf("Expected an error registering %v", mediaType)
+		}
+	}
+
+	get := func(url, accept string) (*http.Response, []byte) {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error in creating new request: %v", err)
+		}
+		req.Header.Set("Accept", accept)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Unexpected error in reading response body: %v", err)
+		}
+		return resp, body
+	}
+
+	jsonResp, _ := get(server.URL+"/openapi/v3/apis/apps/v1", "application/json")
+	etag := strings.Trim(jsonResp.Header.Get("Etag"), `"`)
+	for _, url := range []string{"/openapi/v3/apis/apps/v1", "/openapi/v3/apis/apps/v1?hash=" + etag} {
+		resp, body := get(server.URL+url, "application/x-title")
+		if resp.StatusCode != 200 {
+			t.Fatalf("%v: Unexpected response status code, want: 200, got: %v", url, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Content-Type"); got != "application/x-title" {
+			t.Errorf("%v: Unexpected content type in response, want: application/x-title, got: %v", url, got)
+		}
+		if string(body) != "apps" {
+			t.Errorf("%v: Unexpected response body, want: apps, got: %s", url, body)
+		}
+		if got, want := resp.Header.Get("Etag"), jsonResp.Header.Get("Etag"); got != want {
+			t.Errorf("%v: Expected Etag to match the JSON Etag %v, got %v", url, want, got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected serializer to be called once, got %d", calls)
+	}
+
+	// Custom serializers also apply to filtered documents.
+	_, body := get(server.URL+"/openapi/v3/apis/apps/v1?schemas=Unknown", "application/x-title")
+	if string(body) != "apps" {
+		t.Errorf("Unexpected response body for filtered document, want: apps, got: %s", body)
+	}
+
+	// JSON is still the default.
+	resp, _ := get(server.URL+"/openapi/v3/apis/apps/v1", "*/*")
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Unexpected content type in response, want: application/json, got: %v", got)
+	}
+}
+
+// v2SpecOrDie returns an OpenAPI v2 spec with the given paths.
+func v2SpecOrDie(t *testing.T, paths ...string) *spec.Swagger {
+	t.Helper()
+	// Every path uses a definition named after its last segment.
+	pathItems, definitions := []string{}, []string{}
+	for _, p := range paths {
+		segments := strings.Split(strings.Trim(p, "/"), "/")
+		name := segments[len(segments)-1]
+		pathItems = append(pathItems, fmt.Sprintf(`%q: {"get": {"responses": {"200": {"description": "OK", "schema": {"$ref": "#/definitions/%s"}}}}}`, p, name))
+		definitions = append(definitions, fmt.Sprintf(`%q: {"type": "object"}`, name))
+	}
+	s := &spec.Swagger{}
+	if err := json.Unmarshal([]byte(fmt.Sprintf(`{
+  "swagger": "2.0",
+  "info": {"title": "Kubernetes", "version": "v1.30.0"},
+  "paths": {%s},
+  "definitions": {%s}
+  }`, strings.Join(pathItems, ","), strings.Join(definitions, ","))), s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestV2Source(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIService()
+	mux.Handle("/openapi/v3", http.HandlerFunc(o.HandleDiscovery))
+	mux.Handle("/openapi/v3/", http.HandlerFunc(o.HandleGroupVersion))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := server.Client()
+
+	var source cached.Atomic[*spec.Swagger]
+	source.Store(cached.Static(v2SpecOrDie(t, "/api/v1/pods", "/apis/apps/v1/deployments", "/apis/apps/v1/", "/apis/apps/", "/version"), "1"))
+	v2 := NewV2Source(o, &source)
+
+	discoveredGroups := func() []string {
+		discovery, _, err := getDiscovery(server, "/openapi/v3")
+		if err != nil {
+			t.Fatalf("Unexpected error getting discovery: %v", err)
+		}
+		groups := []string{}
+		for gv := range discovery.Paths {
+			groups = append(groups, gv)
+		}
+		sort.Strings(groups)
+		return groups
+	}
+	getGroupVersion := func(gv string) *spec3.OpenAPI {
+		resp, err := client.Get(server.URL + "/openapi/v3/" + gv)
+		if err != nil {
+			t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Fatalf("%v: Unexpected response status code, want: 200, got: %v", gv, resp.StatusCode)
+		}
+		openapi := &spec3.OpenAPI{}
+		if err := json.NewDecoder(resp.Body).Decode(openapi); err != nil {
+			t.Fatalf("%v: Failed to decode response: %v", gv, err)
+		}
+		return openapi
+	}
+	keys := func(openapi *spec3.OpenAPI) (paths []string, schemas []string) {
+		for p := range openapi.Paths.Paths {
+			paths = append(paths, p)
+		}
+		for s := range openapi.Components.Schemas {
+			schemas = append(schemas, s)
+		}
+		sort.Strings(paths)
+		sort.Strings(schemas)
+		return paths, schemas
+	}
+
+	if groups, want := discoveredGroups(), []string{"api/v1", "apis/apps/v1"}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("Unexpected group versions, want: %v, got: %v", want, groups)
+	}
+	apps := getGroupVersion("apis/apps/v1")
+	paths, schemas := keys(apps)
+	if want := []string{"/apis/apps/v1/", "/apis/apps/v1/deployments"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Unexpected paths, want: %v, got: %v", want, paths)
+	}
+	if want := []string{"deployments", "v1"}; !reflect.DeepEqual(schemas, want) {
+		t.Errorf("Unexpected schemas, want: %v, got: %v", want, schemas)
+	}
+	if apps.Version != "3.0.0" || apps.Info == nil || apps.Info.Title != "Kubernetes" {
+		t.Errorf("Unexpected converted spec: %v %v", apps.Version, apps.Info)
+	}
+
+	// A new etag updates the content and the list of group versions
+	// lazily.
+	source.Store(cached.Static(v2SpecOrDie(t, "/api/v1/pods", "/api/v1/version", "/apis/batch/v1/jobs"), "2"))
+	paths, _ = keys(getGroupVersion("api/v1"))
+	if want := []string{"/api/v1/pods", "/api/v1/version"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Unexpected paths, want: %v, got: %v", want, paths)
+	}
+	if groups, want := discoveredGroups(), []string{"api/v1", "apis/batch/v1"}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("Unexpected group versions, want: %v, got: %v", want, groups)
+	}
+	paths, schemas = keys(getGroupVersion("apis/batch/v1"))
+	if want := []string{"/apis/batch/v1/jobs"}; !reflect.DeepEqual(paths, want) || !reflect.DeepEqual(schemas, []string{"jobs"}) {
+		t.Errorf("Unexpected batch spec, paths: %v, schemas: %v", paths, schemas)
+	}
+
+	// The etag of a group version only changes with its content.
+	discovery, _, err := getDiscovery(server, "/openapi/v3")
+	if err != nil {
+		t.Fatalf("Unexpected error getting discovery: %v", err)
+	}
+	source.Store(cached.Static(v2SpecOrDie(t, "/api/v1/pods", "/api/v1/version", "/apis/batch/v1/jobs", "/apis/batch/v1/cronjobs"), "3"))
+	updated, _, err := getDiscovery(server, "/openapi/v3")
+	if err != nil {
+		t.Fatalf("Unexpected error getting discovery: %v", err)
+	}
+	if before, after := discovery.Paths["api/v1"], updated.Paths["api/v1"]; before != after {
+		t.Errorf("Expected the unchanged group version to keep its URL, before: %v, after: %v", before, after)
+	}
+	if before, after := discovery.Paths["apis/batch/v1"], updated.Paths["apis/batch/v1"]; before == after {
+		t.Errorf("Expected the changed group version to get a new URL, got: %v", after)
+	}
+
+	// The group versions of the last successful sync are kept when the
+	// source fails.
+	source.Store(cached.Func(func() (*spec.Swagger, string, error) {
+		return nil, "", fmt.Errorf("spec is not ready")
+	}))
+	if err := v2.Sync(); err == nil {
+		t.Errorf("Expected an error syncing a failing source")
+	}
+	if groups, want := discoveredGroups(), []string{"api/v1", "apis/batch/v1"}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("Unexpected group versions, want: %v, got: %v", want, groups)
+	}
+
+	// The group versions are those of the service: a group version deleted
+	// directly is registered again when the v2 spec changes, and a group
+	// version registered directly isn't deleted.
+	o.DeleteGroupVersion("api/v1")
+	o.UpdateGroupVersion("apis/apps/v1", openAPIOrDie("apps"))
+	source.Store(cached.Static(v2SpecOrDie(t, "/api/v1/pods"), "4"))
+	if groups, want := discoveredGroups(), []string{"api/v1", "apis/apps/v1"}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("Unexpected group versions, want: %v, got: %v", want, groups)
+	}
+}
+
+func TestV2SourceNotReady(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIService()
+	mux.Handle("/openapi/v3", http.HandlerFunc(o.HandleDiscovery))
+	mux.Handle("/openapi/v3/", http.HandlerFunc(o.HandleGroupVersion))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var source cached.Atomic[*spec.Swagger]
+	source.Store(cached.Func(func() (*spec.Swagger, string, error) {
+		return nil, "", fmt.Errorf("spec is not ready")
+	}))
+	NewV2Source(o, &source)
+
+	// Until the first successful sync, the group versions are unknown.
+	for _, path := range []string{"/openapi/v3", "/openapi/v3/apis/apps/v1"} {
+		resp, err := server.Client().Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" {
+			t.Errorf("%v: Expected 503 with Retry-After, got: %v %v", path, resp.StatusCode, resp.Header)
+		}
+	}
+
+	source.Store(cached.Static(v2SpecOrDie(t, "/apis/apps/v1/deployments"), "1"))
+	for path, want := range map[string]int{"/openapi/v3/apis/apps/v1": 200, "/openapi/v3/apis/batch/v1": 404} {
+		resp, err := server.Client().Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%v: Unexpected response status code, want: %v, got: %v", path, want, resp.StatusCode)
+		}
+	}
+}
+
+func TestV2SourceWatch(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIService()
+	mux.Handle("/openapi/v3", http.HandlerFunc(o.HandleDiscovery))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var source cached.Atomic[*spec.Swagger]
+	source.Store(cached.Static(v2SpecOrDie(t, "/apis/apps/v1/deployments"), "1"))
+	NewV2Source(o, &source)
+
+	resp, err := server.Client().Get(server.URL + "/openapi/v3?watch=true")
+	if err != nil {
+		t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+	nextEvent := func() OpenAPIV3DiscoveryEvent {
+		t.Helper()
+		event := OpenAPIV3DiscoveryEvent{}
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatalf("Failed to decode event: %v", err)
+		}
+		return event
+	}
+
+	if event := nextEvent(); !reflect.DeepEqual(event.Changed, []string{"apis/apps/v1"}) {
+		t.Errorf("Unexpected initial event: %+v", event)
+	}
+	// The watch is notified of the changes of the source without any
+	// other request.
+	source.Store(cached.Static(v2SpecOrDie(t, "/apis/batch/v1/jobs"), "2"))
+	if event := nextEvent(); !reflect.DeepEqual(event.Changed, []string{"apis/batch/v1"}) || !reflect.DeepEqual(event.Removed, []string{"apis/apps/v1"}) {
+		t.Errorf("Unexpected event after changing the source: %+v", event)
+	}
+}
+
+func TestUpdateGroupVersionStableEtag(t *testing.T) {
+	newServer := func() (*OpenAPIService, *httptest.Server) {
+		mux := http.NewServeMux()
+		o := NewOpenAPIService()
+		mux.Handle("/openapi/v3", http.HandlerFunc(o.HandleDiscovery))
+		mux.Handle("/openapi/v3/", http.HandlerFunc(o.HandleGroupVersion))
+		return o, httptest.NewServer(mux)
+	}
+
+	o1, server1 := newServer()
+	defer server1.Close()
+	o2, server2 := newServer()
+	defer server2.Close()
+	o1.UpdateGroupVersion("apis/apps/v1", openAPIOrDie("apps"))
+	o2.UpdateGroupVersion("apis/apps/v1", openAPIOrDie("apps"))
+
+	discovery1, etag1, err := getDiscovery(server1, "/openapi/v3")
+	if err != nil {
+		t.Fatalf("Unexpected error getting discovery: %v", err)
+	}
+	discovery2, etag2, err := getDiscovery(server2, "/openapi/v3")
+	if err != nil {
+		t.Fatalf("Unexpected error getting discovery: %v", err)
+	}
+	if etag1 != etag2 || !reflect.DeepEqual(discovery1, discovery2) {
+		t.Errorf("Expected identical discovery for identical specs, got %v (%v) and %v (%v)", discovery1, etag1, discovery2, etag2)
+	}
+
+	// Updating with an identical spec only serializes it to compute its
+	// etag, the documents aren't rebuilt.
+	observer := &recordingObserver{}
+	o1.SetObserver(observer)
+	o1.UpdateGroupVersion("apis/apps/v1", openAPIOrDie("apps"))
+	discovery, etag, err := getDiscovery(server1, "/openapi/v3")
+	if err != nil {
+		t.Fatalf("Unexpected error getting discovery: %v", err)
+	}
+	if etag != etag1 || !reflect.DeepEqual(discovery, discovery1) {
+		t.Errorf("Expected discovery to be unchanged, got %v (%v)", discovery, etag)
+	}
+	if got := observer.count("serialized"); got != 1 {
+		t.Errorf("Expected 1 serialization for an identical spec, got %d", got)
+	}
+
+	// The serialization computing the etag of a new spec is the served
+	// JSON document.
+	o1.UpdateGroupVersion("apis/apps/v1", openAPIOrDie("apps-updated"))
+	if discovery, _, err := getDiscovery(server1, "/openapi/v3"); err != nil {
+		t.Fatalf("Unexpected error getting discovery: %v", err)
+	} else if reflect.DeepEqual(discovery, discovery1) {
+		t.Errorf("Expected discovery to change with the spec")
+	}
+	resp, err := identityClient(server1).Get(server1.URL + "/openapi/v3/apis/apps/v1")
+	if err != nil {
+		t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	if want, _ := json.Marshal(openAPIOrDie("apps-updated")); !bytes.Equal(body, want) {
+		t.Errorf("Unexpected JSON document, want: %s, got: %s", want, body)
+	}
+	if got := observer.count("serialized"); got != 2 {
+		t.Errorf("Expected 2 serializations after updating the spec, got %d", got)
+	}
+}
+
+func TestUpdateGroupVersionRetriesErrors(t *testing.T) {
+	o := NewOpenAPIService()
+	openapi := openAPIOrDie("apps")
+	openapi.Info.Extensions = spec.Extensions{"x-invalid": make(chan int)}
+	o.UpdateGroupVersion("apis/apps/v1", openapi)
+	if _, _, err := o.discoveryCache.Get(context.Background()); err == nil {
+		t.Fatalf("Expected an error serializing an invalid spec")
+	}
+
+	// The error isn't kept, the spec is serialized again.
+	openapi.Info.Extensions = nil
+	if _, _, err := o.discoveryCache.Get(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+type testContextKey struct{}
+
+func TestUpdateGroupVersionLazyCtx(t *testing.T) {
+	o := NewOpenAPIService()
+	o.UpdateGroupVersionLazyCtx("apis/apps/v1", cached.FuncCtx(func(ctx context.Context) (*spec3.OpenAPI, string, error) {
+		title, ok := ctx.Value(testContextKey{}).(string)
+		if !ok {
+			return nil, "", fmt.Errorf("missing request context")
+		}
+		return openAPIOrDie(title), title, nil
+	}))
+
+	req := httptest.NewRequest("GET", "/openapi/v3/apis/apps/v1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), testContextKey{}, "from-request"))
+	w := httptest.NewRecorder()
+	o.HandleGroupVersion(w, req)
+	if w.Code != 200 {
+		t.Fatalf("Unexpected response status code, want: 200, got: %v", w.Code)
+	}
+	got := &spec3.OpenAPI{}
+	if err := json.Unmarshal(w.Body.Bytes(), got); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if got.Info.Title != "from-request" {
+		t.Errorf("Expected the spec to be built with the request context, got title %q", got.Info.Title)
+	}
+
+	// A request whose context is done doesn't build the spec.
+	o.UpdateGroupVersionLazyCtx("apis/batch/v1", cached.FuncCtx(func(ctx context.Context) (*spec3.OpenAPI, string, error) {
+		t.Errorf("Unexpected build of the spec")
+		return openAPIOrDie("batch"), "batch", nil
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req = httptest.NewRequest("GET", "/openapi/v3/apis/batch/v1", nil).WithContext(ctx)
+	w = httptest.NewRecorder()
+	o.HandleGroupVersion(w, req)
+	if w.Code != 503 {
+		t.Errorf("Unexpected response status code, want: 503, got: %v", w.Code)
+	}
+}
+
+func TestRegisterDebugHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIService()
+	o.UpdateGroupVersion("apis/apps/v1", openAPIOrDie("apps-v1"))
+	mux.Handle("/openapi/v3", http.HandlerFunc(o.HandleDiscovery))
+	o.RegisterDebugHandler("/debug/openapi/v3", mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	if _, _, err := getDiscovery(server, "/openapi/v3"); err != nil {
+		t.Fatalf("failed to get /openapi/v3: %v", err)
+	}
+	resp, err := server.Client().Get(server.URL + "/debug/openapi/v3")
+	if err != nil {
+		t.Fatalf("failed to get the debug handler: %v", err)
+	}
+	defer resp.Body.Close()
+	var nodes []cached.NodeStatus
+	if err := json.NewDecoder(resp.Body).Decode(&nodes); err != nil {
+		t.Fatalf("failed to decode the cache status: %v", err)
+	}
+
+	got := map[string]cached.NodeStatus{}
+	for _, node := range nodes {
+		got[node.Name] = node
+	}
+	if len(nodes) == 0 || nodes[0].Name != discoveryName {
+		t.Fatalf("expected the discovery document first, got %+v", nodes)
+	}
+	wantDependencies := map[string][]string{
+		discoveryName:                        {"apis/apps/v1 application/json"},
+		discoveryName + " gzip":              {discoveryName},
+		"apis/apps/v1 application/json":      {"apis/apps/v1 serialized"},
+		"apis/apps/v1 application/json zstd": {"apis/apps/v1 application/json"},
+		"apis/apps/v1 serialized":            {"apis/apps/v1"},
+		"apis/apps/v1":                       nil,
+	}
+	for name, deps := range wantDependencies {
+		node, ok := got[name]
+		if !ok {
+			t.Errorf("missing node %q", name)
+			continue
+		}
+		if !reflect.DeepEqual(node.Dependencies, deps) {
+			t.Errorf("expected the dependencies of %q to be %v, got %v", name, deps, node.Dependencies)
+		}
+	}
+	if node := got["apis/apps/v1"]; node.Evaluations == 0 || node.Etag == "" || node.LastEvaluated.IsZero() {
+		t.Errorf("expected the group version to be evaluated, got %+v", node)
+	}
+	if node := got["apis/apps/v1 application/yaml"]; node.Evaluations != 0 {
+		t.Errorf("expected the YAML document not to be evaluated, got %+v", node)
+	}
+}
+
+func TestObserverServedCompressedBytes(t *testing.T) {
+	// The spec is large enough to be compressed.
+	openapi := openAPIOrDie("apps-v1")
+	openapi.Paths = &spec3.Paths{Paths: map[string]*spec3.Path{}}
+	for i := 0; i < 100; i++ {
+		openapi.Paths.Paths[fmt.Sprintf("/apis/apps/v1/namespaces/{namespace}/kind%d", i)] = &spec3.Path{}
+	}
+	o := NewOpenAPIService()
+	o.UpdateGroupVersion("apis/apps/v1", openapi)
+	observer := &recordingObserver{}
+	o.SetObserver(observer)
+	mux := http.NewServeMux()
+	mux.Handle("/openapi/v3/", http.HandlerFunc(o.HandleGroupVersion))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := identityClient(server)
+
+	var sizes []int
+	for _, encoding := range []string{"identity", "gzip"} {
+		req, err := http.NewRequest("GET", server.URL+"/openapi/v3/apis/apps/v1", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error in creating new request: %v", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Accept-Encoding", encoding)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Unexpected error in reading response body: %v", err)
+		}
+		if encoding == "gzip" && resp.Header.Get("Content-Encoding") != "gzip" {
+			t.Fatalf("Expected a gzip response, got Content-Encoding %q", resp.Header.Get("Content-Encoding"))
+		}
+		sizes = append(sizes, len(body))
+	}
+	if sizes[1] >= sizes[0] {
+		t.Fatalf("Expected the gzip response to be smaller, got %v", sizes)
+	}
+	if !reflect.DeepEqual(observer.served, sizes) {
+		t.Errorf("Expected served sizes %v, got %v", sizes, observer.served)
+	}
+}
+
+// recordingObserver records the events reported to a metrics.Observer, as
+// "<event> <group version> <content type>".
+type recordingObserver struct {
+	lock   sync.Mutex
+	events []string
+	// served are the sizes of the served documents.
+	served []int
+}
+
+var _ metrics.Observer = &recordingObserver{}
+
+func (r *recordingObserver) record(event, groupVersion, contentType string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, event+" "+groupVersion+" "+contentType)
+}
+
+func (r *recordingObserver) CacheHit(groupVersion, contentType string) {
+	r.record("hit", groupVersion, contentType)
+}
+
+func (r *recordingObserver) CacheMiss(groupVersion, contentType string) {
+	r.record("miss", groupVersion, contentType)
+}
+
+func (r *recordingObserver) Serialized(groupVersion, contentType string, _ time.Duration, _ int) {
+	r.record("serialized", groupVersion, contentType)
+}
+
+func (r *recordingObserver) Served(groupVersion, contentType string, size int) {
+	r.record("served", groupVersion, contentType)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.served = append(r.served, size)
+}
+
+func (r *recordingObserver) BuildError(groupVersion string, _ error) {
+	r.record("error", groupVersion, "")
+}
+
+// count returns the number of recorded events of the given kind.
+func (r *recordingObserver) count(event string) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	n := 0
+	for _, e := range r.events {
+		if strings.HasPrefix(e, event+" ") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestObserverCacheHitsAndMisses(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIService()
+	mux.Handle("/openapi/v3/", http.HandlerFunc(o.HandleGroupVersion))
+	observer := &recordingObserver{}
+	o.SetObserver(observer)
+
+	groups := []string{"apis/apps/v1", "apis/batch/v1", "apis/policy/v1", "apis/storage/v1"}
+	for _, group := range groups {
+		o.UpdateGroupVersion(group, openAPIOrDie(group))
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := identityClient(server)
+
+	// Requests for other groups running concurrently don't change whether
+	// a request is a hit or a miss.
+	for _, expected := range []string{"miss", "hit"} {
+		observer.events = nil
+		var wg sync.WaitGroup
+		for _, group := range groups {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := client.Get(server.URL + "/openapi/v3/" + group)
+				if err != nil {
+					t.Errorf("Unexpected error in serving HTTP request: %v", err)
+					return
+				}
+				resp.Body.Close()
+			}()
+		}
+		wg.Wait()
+		for _, group := range groups {
+			found := false
+			for _, e := range observer.events {
+				found = found || e == expected+" "+group+" "+contentTypeJSON
+			}
+			if !found {
+				t.Errorf("Expected a %v for %v, got %v", expected, group, observer.events)
+			}
+		}
+		if got := observer.count(expected); got != len(groups) {
+			t.Errorf("Expected %d %vs, got %v", len(groups), expected, observer.events)
+		}
+	}
+}
+
+func TestObserverBuildErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	o := NewOpenAPIService()
+	mux.Handle("/openapi/v3/", http.HandlerFunc(o.HandleGroupVersion))
+	observer := &recordingObserver{}
+	o.SetObserver(observer)
+
+	broken := false
+	o.UpdateGroupVersionLazy("apis/apps/v1", cached.Func(func() (*spec3.OpenAPI, string, error) {
+		if broken {
+			return nil, "", errors.New("broken")
+		}
+		return openAPIOrDie("apps-v1"), "apps-v1", nil
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := identityClient(server)
+
+	for _, b := range []bool{false, true, true} {
+		broken = b
+		resp, err := client.Get(server.URL + "/openapi/v3/apis/apps/v1")
+		if err != nil {
+			t.Fatalf("Unexpected error in serving HTTP request: %v", err)
+		}
+		resp.Body.Close()
+		// The last success is still served.
+		if resp.StatusCode != 200 {
+			t.Errorf("Unexpected response status code, want: 200, got: %v", resp.StatusCode)
+		}
+	}
+	if got := observer.count("error"); got != 2 {
+		t.Errorf("Expected 2 build errors after the first success, got %v", observer.events)
+	}
+}
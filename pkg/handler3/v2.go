@@ -0,0 +1,161 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler3
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"k8s.io/kube-openapi/pkg/aggregator"
+	"k8s.io/kube-openapi/pkg/cached"
+	"k8s.io/kube-openapi/pkg/internal"
+	"k8s.io/kube-openapi/pkg/openapiconv"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// V2Source serves an OpenAPI v2 spec as OpenAPI v3 group versions of an
+// OpenAPIService. Every group version found in the paths of the spec, i.e.
+// "apis/<group>/<version>" or "api/<version>", is registered with the
+// paths under it and the definitions they use, converted to OpenAPI v3.
+// Paths outside of a group version are not served.
+//
+// Everything is lazy: before serving a request, the service checks the
+// etag of the v2 spec and, if it changed, adds and removes group versions
+// to match its paths. Group versions are converted when requested, and
+// their etag is a hash of their content, so that a change to the v2 spec
+// only changes the etag of the group versions it affects. Discovery
+// watches are notified of the changes of the v2 spec if it is
+// cached.Subscribable, e.g. a cached.Atomic, and otherwise on the next
+// request.
+//
+// Until the v2 spec is successfully built once, requests for unknown group
+// versions are answered with 503 rather than 404, since they may be in the
+// v2 spec.
+type V2Source struct {
+	service *OpenAPIService
+	source  cached.Value[*spec.Swagger]
+
+	// synced and etag are the state of the last successful Sync,
+	// protected by the mutex of the service.
+	synced bool
+	etag   string
+}
+
+// NewV2Source creates a V2Source registering the group versions of source
+// into service. The source is called every time the service serves a
+// request and should be cached.
+func NewV2Source(service *OpenAPIService, source cached.Value[*spec.Swagger]) *V2Source {
+	s := &V2Source{
+		service: service,
+		source:  source,
+	}
+	service.addSource(s)
+	return s
+}
+
+// refresh syncs the group versions. Errors are logged and only returned
+// until the first successful sync, since afterwards the group versions of
+// the last successful sync are still served.
+func (s *V2Source) refresh() error {
+	err := s.Sync()
+	if err == nil {
+		return nil
+	}
+	klog.Errorf("Error syncing OpenAPI v2 source: %v", err)
+	s.service.mutex.Lock()
+	defer s.service.mutex.Unlock()
+	if s.synced {
+		return nil
+	}
+	return err
+}
+
+func (s *V2Source) subscribe() (<-chan struct{}, func()) {
+	if source, ok := s.source.(cached.Subscribable); ok {
+		return source.Subscribe()
+	}
+	return nil, func() {}
+}
+
+// Sync registers the group versions of the v2 spec that are not registered
+// yet, and deletes the ones registered by the V2Source that have
+// disappeared from it. It does nothing if the etag of the v2 spec didn't
+// change since the last call. The service calls it before serving
+// requests, calling it directly is only needed to update the group
+// versions eagerly or to get the error.
+func (s *V2Source) Sync() error {
+	swagger, etag, err := s.source.Get()
+	if err != nil {
+		return err
+	}
+	groups := groupVersionsV2(swagger)
+
+	s.service.mutex.Lock()
+	defer s.service.mutex.Unlock()
+	if s.synced && etag == s.etag {
+		return nil
+	}
+	s.service.syncSourceLocked(s, groups, func(group string) cached.ValueCtx[*spec3.OpenAPI] {
+		return cached.WithContext(s.groupVersion(group))
+	})
+	s.synced, s.etag = true, etag
+	return nil
+}
+
+// groupVersion returns the OpenAPI v3 spec of a group version, rebuilt
+// when the etag of the v2 spec changes. Its etag is a hash of the
+// converted spec.
+func (s *V2Source) groupVersion(group string) cached.Value[*spec3.OpenAPI] {
+	return cached.Transform(func(swagger *spec.Swagger, _ string, err error) (*spec3.OpenAPI, string, error) {
+		if err != nil {
+			return nil, "", err
+		}
+		if !groupVersionsV2(swagger)[group] {
+			return nil, "", fmt.Errorf("group version %s not found in the OpenAPI v2 spec", group)
+		}
+		filtered := aggregator.FilterSpecByPathsWithoutSideEffects(swagger, []string{"/" + group + "/"})
+		openapi := openapiconv.ConvertV2ToV3(filtered)
+		data, err := internal.DeterministicMarshal(openapi)
+		if err != nil {
+			return nil, "", err
+		}
+		return openapi, computeETag(data), nil
+	}, s.source)
+}
+
+// groupVersionsV2 returns the group versions of the paths of a v2 spec.
+// Only paths ending with a slash or longer are considered, e.g.
+// "/apis/apps/v1/" but not "/apis/apps/v1", so that the group version is
+// a prefix of all of its paths.
+func groupVersionsV2(swagger *spec.Swagger) map[string]bool {
+	groups := map[string]bool{}
+	if swagger == nil || swagger.Paths == nil {
+		return groups
+	}
+	for path := range swagger.Paths.Paths {
+		segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+		switch {
+		case len(segments) >= 4 && segments[0] == "apis" && segments[1] != "" && segments[2] != "":
+			groups[strings.Join(segments[:3], "/")] = true
+		case len(segments) >= 3 && segments[0] == "api" && segments[1] != "":
+			groups[strings.Join(segments[:2], "/")] = true
+		}
+	}
+	return groups
+}
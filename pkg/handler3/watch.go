@@ -0,0 +1,203 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler3
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"k8s.io/klog/v2"
+)
+
+// OpenAPIV3DiscoveryEvent is streamed, one JSON object per line, by the
+// discovery endpoint when requested with ?watch=true. An event is sent
+// when the watch starts, and then every time the discovery document
+// changes, either because a group version was added or removed, or
+// because the etag of a group version changed. If the discovery document
+// can't be built, an event with only Error set is sent and the stream is
+// closed, and clients should watch again after a delay.
+type OpenAPIV3DiscoveryEvent struct {
+	// Etag is the etag of the discovery document, as returned in the
+	// Etag header of the uncompressed discovery endpoint.
+	Etag string `json:"etag"`
+	// Discovery is the complete discovery document.
+	Discovery OpenAPIV3Discovery `json:"discovery"`
+	// Changed lists the group versions that were added or whose URL
+	// changed since the previous event, sorted.
+	Changed []string `json:"changed,omitempty"`
+	// Removed lists the group versions that were removed since the
+	// previous event, sorted.
+	Removed []string `json:"removed,omitempty"`
+	// Error is the reason the stream is closed, on its last event.
+	Error string `json:"error,omitempty"`
+}
+
+// isWatch returns true if the request asks for a stream of discovery
+// changes rather than the discovery document.
+func isWatch(r *http.Request) bool {
+	watch, err := strconv.ParseBool(r.URL.Query().Get("watch"))
+	return err == nil && watch
+}
+
+// addWatcher registers a channel that is signaled every time the
+// discovery document may have changed. The channel is buffered so that
+// signals are coalesced while the watcher is busy.
+func (o *OpenAPIService) addWatcher() chan struct{} {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	ch := make(chan struct{}, 1)
+	o.watchers[ch] = struct{}{}
+	return ch
+}
+
+func (o *OpenAPIService) removeWatcher(ch chan struct{}) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	delete(o.watchers, ch)
+}
+
+func (o *OpenAPIService) notifyWatchersLocked() {
+	for ch := range o.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// diffDiscovery returns the sorted group versions that were added or
+// modified, and removed, between old and new.
+func diffDiscovery(old, new *OpenAPIV3Discovery) (changed []string, removed []string) {
+	for gv, newGV := range new.Paths {
+		if oldGV, ok := old.Paths[gv]; !ok || oldGV != newGV {
+			changed = append(changed, gv)
+		}
+	}
+	for gv := range old.Paths {
+		if _, ok := new.Paths[gv]; !ok {
+			removed = append(removed, gv)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return changed, removed
+}
+
+// watchDiscovery streams an OpenAPIV3DiscoveryEvent every time the
+// discovery document changes, until the client goes away or the discovery
+// document fails to build.
+func (o *OpenAPIService) watchDiscovery(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	ch := o.addWatcher()
+	defer o.removeWatcher(ch)
+	o.forwardSourceChanges(r.Context(), ch)
+
+	w.Header().Set("Content-Type", "application/json;stream=watch")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(w)
+	last := &OpenAPIV3Discovery{}
+	lastEtag := ""
+	// Send the current state right away.
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+		}
+
+		if err := o.refreshSources(); err != nil {
+			writeWatchError(encoder, flusher, err)
+			return
+		}
+		ts, etag, err := o.discoveryCache.Get(r.Context())
+		if err != nil {
+			writeWatchError(encoder, flusher, err)
+			return
+		}
+		if etag == lastEtag {
+			continue
+		}
+		discovery := &OpenAPIV3Discovery{}
+		if err := json.Unmarshal(ts.spec, discovery); err != nil {
+			writeWatchError(encoder, flusher, err)
+			return
+		}
+		event := OpenAPIV3DiscoveryEvent{Etag: etag, Discovery: *discovery}
+		event.Changed, event.Removed = diffDiscovery(last, discovery)
+		if err := encoder.Encode(&event); err != nil {
+			return
+		}
+		flusher.Flush()
+		last, lastEtag = discovery, etag
+	}
+}
+
+// forwardSourceChanges signals ch every time the group versions of a
+// source may have changed, until ctx is done, so that the watch refreshes
+// the sources without waiting for another request to do it.
+func (o *OpenAPIService) forwardSourceChanges(ctx context.Context, ch chan struct{}) {
+	o.mutex.Lock()
+	sources := o.sources
+	o.mutex.Unlock()
+	for _, source := range sources {
+		changes, cancel := source.subscribe()
+		if changes == nil {
+			cancel()
+			continue
+		}
+		go func() {
+			defer cancel()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-changes:
+				}
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+}
+
+// writeWatchError sends the event ending a watch that failed. The stream
+// can't stay open without events since the client would miss the changes
+// until the next one, so it is closed for the client to watch again.
+func writeWatchError(encoder *json.Encoder, flusher http.Flusher, err error) {
+	klog.Errorf("Error watching discovery: %s", err)
+	if err := encoder.Encode(&OpenAPIV3DiscoveryEvent{Error: err.Error()}); err != nil {
+		return
+	}
+	flusher.Flush()
+}
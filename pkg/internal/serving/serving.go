@@ -0,0 +1,109 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package serving holds what the OpenAPI v2 and v3 handlers share to
+// serve documents and report them to a metrics.Observer.
+package serving
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/kube-openapi/pkg/cached"
+	"k8s.io/kube-openapi/pkg/metrics"
+)
+
+// rebuildsKey is the context key of the counter of the documents
+// serialized while getting a document, see withRebuilds.
+type rebuildsKey struct{}
+
+// withRebuilds returns a context counting the documents serialized by the
+// transforms run with it. Concurrent requests waiting for the same
+// evaluation don't run the transforms, so only the request that ran them
+// counts the serializations.
+func withRebuilds(ctx context.Context) (context.Context, *atomic.Int64) {
+	rebuilds := &atomic.Int64{}
+	return context.WithValue(ctx, rebuildsKey{}, rebuilds), rebuilds
+}
+
+// Get returns the document from the given cache, reporting to the observer
+// whether it had to be serialized, i.e. whether Serialize was called with
+// the context while getting it.
+func Get[T any](ctx context.Context, observer metrics.Observer, group, contentType string, cache cached.ValueCtx[T]) (T, string, error) {
+	ctx, rebuilds := withRebuilds(ctx)
+	value, etag, err := cache.Get(ctx)
+	if err != nil {
+		return value, etag, err
+	}
+	if rebuilds.Load() == 0 {
+		observer.CacheHit(group, contentType)
+	} else {
+		observer.CacheMiss(group, contentType)
+	}
+	return value, etag, nil
+}
+
+// Serialize calls fn and reports it to the observer as a serialization of
+// the document with the given content type.
+func Serialize(ctx context.Context, observer metrics.Observer, group, contentType string, fn func() ([]byte, error)) ([]byte, error) {
+	if rebuilds, ok := ctx.Value(rebuildsKey{}).(*atomic.Int64); ok {
+		rebuilds.Add(1)
+	}
+	start := time.Now()
+	data, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	observer.Serialized(group, contentType, time.Since(start), len(data))
+	return data, nil
+}
+
+// CountingResponseWriter counts the bytes written to the response body. It
+// must wrap the writer the compressed body is written to, so that the
+// bytes served are counted after compression.
+type CountingResponseWriter struct {
+	http.ResponseWriter
+	Written int
+}
+
+func (c *CountingResponseWriter) Write(b []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(b)
+	c.Written += n
+	return n, err
+}
+
+// ParseSerializerMediaType returns the media type of a serializer without
+// its parameters, or an error if it is invalid or one of the built-in
+// media types.
+func ParseSerializerMediaType(mediaType string, builtins ...string) (string, error) {
+	parsed, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return "", fmt.Errorf("invalid media type %q: %w", mediaType, err)
+	}
+	if !strings.Contains(parsed, "/") || strings.Contains(parsed, "*") {
+		return "", fmt.Errorf("invalid media type %q", parsed)
+	}
+	if slices.Contains(builtins, parsed) {
+		return "", fmt.Errorf("media type %q is built-in", parsed)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics defines the hooks used by the OpenAPI services in
+// [k8s.io/kube-openapi/pkg/handler] and [k8s.io/kube-openapi/pkg/handler3]
+// to report how documents are built and served.
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Observer receives events from the OpenAPI services. The groupVersion is
+// the name of the served OpenAPI v3 group version (e.g. "apis/apps/v1"),
+// and is empty for OpenAPI v2 which serves a single document. The
+// contentType is the media type of the document, as returned in the
+// Content-Type header.
+//
+// Implementations must be safe for concurrent use and should not block.
+type Observer interface {
+	// CacheHit is called when a document is served without rebuilding
+	// any part of it.
+	CacheHit(groupVersion, contentType string)
+	// CacheMiss is called when a document had to be rebuilt, at least
+	// in part, before being served.
+	CacheMiss(groupVersion, contentType string)
+	// Serialized is called every time a document is serialized, with the
+	// time it took and the size of the result.
+	Serialized(groupVersion, contentType string, duration time.Duration, size int)
+	// Served is called after a response body has been written, with the
+	// number of bytes written as sent to the client, i.e. after the
+	// response was compressed, if it was.
+	Served(groupVersion, contentType string, size int)
+	// BuildError is called every time the lazy spec of a document fails
+	// to build, including when the last successfully built document is
+	// served instead.
+	BuildError(groupVersion string, err error)
+}
+
+// NoopObserver is an Observer that ignores all events.
+type NoopObserver struct{}
+
+var _ Observer = NoopObserver{}
+
+func (NoopObserver) CacheHit(string, string)                       {}
+func (NoopObserver) CacheMiss(string, string)                      {}
+func (NoopObserver) Serialized(string, string, time.Duration, int) {}
+func (NoopObserver) Served(string, string, int)                    {}
+func (NoopObserver) BuildError(string, error)                      {}
+
+// AtomicObserver holds an Observer that can be replaced while in use. The
+// zero value holds a NoopObserver.
+type AtomicObserver struct {
+	observer atomic.Pointer[Observer]
+}
+
+// Store replaces the held Observer. A nil observer is replaced with a
+// NoopObserver.
+func (a *AtomicObserver) Store(observer Observer) {
+	if observer == nil {
+		observer = NoopObserver{}
+	}
+	a.observer.Store(&observer)
+}
+
+// Load returns the held Observer.
+func (a *AtomicObserver) Load() Observer {
+	if observer := a.observer.Load(); observer != nil {
+		return *observer
+	}
+	return NoopObserver{}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package prometheus implements a [metrics.Observer] that exports the
+// events of the OpenAPI services as Prometheus metrics.
+//
+// It is a separate module, so that importing k8s.io/kube-openapi doesn't
+// depend on the Prometheus client library.
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s.io/kube-openapi/pkg/metrics"
+)
+
+const (
+	namespace = "openapi"
+
+	labelGroupVersion = "group_version"
+	labelContentType  = "content_type"
+	labelResult       = "result"
+)
+
+// Observer is a metrics.Observer recording events as Prometheus metrics.
+type Observer struct {
+	cacheRequests         *prometheus.CounterVec
+	serializationDuration *prometheus.HistogramVec
+	documentSize          *prometheus.GaugeVec
+	servedBytes           *prometheus.CounterVec
+	buildErrors           *prometheus.CounterVec
+}
+
+var _ metrics.Observer = &Observer{}
+
+// NewObserver creates an Observer and registers its metrics with the
+// given registerer.
+func NewObserver(registerer prometheus.Registerer) (*Observer, error) {
+	o := &Observer{
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_requests_total",
+			Help:      "Number of OpenAPI documents served, partitioned by whether they were served from the cache (hit) or had to be rebuilt (miss).",
+		}, []string{labelGroupVersion, labelContentType, labelResult}),
+		serializationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "serialization_duration_seconds",
+			Help:      "Time it took to serialize an OpenAPI document.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+		}, []string{labelGroupVersion, labelContentType}),
+		documentSize: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "document_size_bytes",
+			Help:      "Size of the last serialization of an OpenAPI document.",
+		}, []string{labelGroupVersion, labelContentType}),
+		servedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "served_bytes_total",
+			Help:      "Number of bytes written in OpenAPI response bodies, after compression.",
+		}, []string{labelGroupVersion, labelContentType}),
+		buildErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "build_errors_total",
+			Help:      "Number of times building a lazy OpenAPI spec failed.",
+		}, []string{labelGroupVersion}),
+	}
+	for _, c := range []prometheus.Collector{o.cacheRequests, o.serializationDuration, o.documentSize, o.servedBytes, o.buildErrors} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+func (o *Observer) CacheHit(groupVersion, contentType string) {
+	o.cacheRequests.WithLabelValues(groupVersion, contentType, "hit").Inc()
+}
+
+func (o *Observer) CacheMiss(groupVersion, contentType string) {
+	o.cacheRequests.WithLabelValues(groupVersion, contentType, "miss").Inc()
+}
+
+func (o *Observer) Serialized(groupVersion, contentType string, duration time.Duration, size int) {
+	o.serializationDuration.WithLabelValues(groupVersion, contentType).Observe(duration.Seconds())
+	o.documentSize.WithLabelValues(groupVersion, contentType).Set(float64(size))
+}
+
+func (o *Observer) Served(groupVersion, contentType string, size int) {
+	o.servedBytes.WithLabelValues(groupVersion, contentType).Add(float64(size))
+}
+
+func (o *Observer) BuildError(groupVersion string, err error) {
+	o.buildErrors.WithLabelValues(groupVersion).Inc()
+}
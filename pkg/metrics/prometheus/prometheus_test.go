@@ -0,0 +1,142 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"k8s.io/kube-openapi/pkg/cached"
+	"k8s.io/kube-openapi/pkg/handler"
+	"k8s.io/kube-openapi/pkg/handler3"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+func serve(t *testing.T, h http.Handler, path, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Accept", accept)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestObserverOpenAPIV3(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	observer, err := NewObserver(registry)
+	if err != nil {
+		t.Fatalf("Failed to create observer: %v", err)
+	}
+
+	o := handler3.NewOpenAPIService()
+	o.SetObserver(observer)
+	o.UpdateGroupVersion("apis/apps/v1", &spec3.OpenAPI{
+		Version: "3.0.0",
+		Info:    &spec.Info{InfoProps: spec.InfoProps{Title: "apps", Version: "v1"}},
+		Paths:   &spec3.Paths{},
+	})
+	o.UpdateGroupVersionLazy("apis/broken/v1", cached.Func(func() (*spec3.OpenAPI, string, error) {
+		return nil, "", errors.New("not ready")
+	}))
+	h := http.HandlerFunc(o.HandleGroupVersion)
+
+	var body []byte
+	for i := 0; i < 3; i++ {
+		w := serve(t, h, "/openapi/v3/apis/apps/v1", "application/json")
+		if w.Code != 200 {
+			t.Fatalf("Unexpected response status code, want: 200, got: %v", w.Code)
+		}
+		body = w.Body.Bytes()
+	}
+	if w := serve(t, h, "/openapi/v3/apis/broken/v1", "application/json"); w.Code != 503 {
+		t.Fatalf("Unexpected response status code, want: 503, got: %v", w.Code)
+	}
+
+	if got := testutil.ToFloat64(observer.cacheRequests.WithLabelValues("apis/apps/v1", "application/json", "miss")); got != 1 {
+		t.Errorf("Expected 1 cache miss, got %v", got)
+	}
+	if got := testutil.ToFloat64(observer.cacheRequests.WithLabelValues("apis/apps/v1", "application/json", "hit")); got != 2 {
+		t.Errorf("Expected 2 cache hits, got %v", got)
+	}
+	if got := testutil.ToFloat64(observer.servedBytes.WithLabelValues("apis/apps/v1", "application/json")); got != float64(3*len(body)) {
+		t.Errorf("Expected %v bytes served, got %v", 3*len(body), got)
+	}
+	if got := testutil.ToFloat64(observer.documentSize.WithLabelValues("apis/apps/v1", "application/json")); got != float64(len(body)) {
+		t.Errorf("Expected document size %v, got %v", len(body), got)
+	}
+	if got := testutil.ToFloat64(observer.buildErrors.WithLabelValues("apis/broken/v1")); got != 1 {
+		t.Errorf("Expected 1 build error, got %v", got)
+	}
+	if got := testutil.CollectAndCount(observer.serializationDuration); got != 1 {
+		t.Errorf("Expected 1 serialization duration series, got %v", got)
+	}
+
+	// Protobuf is built from the cached JSON, only the protobuf
+	// serialization is a miss.
+	serve(t, h, "/openapi/v3/apis/apps/v1", "application/com.github.proto-openapi.spec.v3.v1.0+protobuf")
+	if got := testutil.ToFloat64(observer.cacheRequests.WithLabelValues("apis/apps/v1", "application/com.github.proto-openapi.spec.v3.v1.0+protobuf", "miss")); got != 1 {
+		t.Errorf("Expected 1 protobuf cache miss, got %v", got)
+	}
+	if got := testutil.CollectAndCount(observer.serializationDuration); got != 2 {
+		t.Errorf("Expected 2 serialization duration series, got %v", got)
+	}
+}
+
+func TestObserverOpenAPIV2(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	observer, err := NewObserver(registry)
+	if err != nil {
+		t.Fatalf("Failed to create observer: %v", err)
+	}
+
+	o := handler.NewOpenAPIService(&spec.Swagger{SwaggerProps: spec.SwaggerProps{Swagger: "2.0"}})
+	o.SetObserver(observer)
+	mux := http.NewServeMux()
+	o.RegisterOpenAPIVersionedService("/openapi/v2", mux)
+
+	for i := 0; i < 2; i++ {
+		if w := serve(t, mux, "/openapi/v2", "application/json"); w.Code != 200 {
+			t.Fatalf("Unexpected response status code, want: 200, got: %v", w.Code)
+		}
+	}
+
+	if got := testutil.ToFloat64(observer.cacheRequests.WithLabelValues("", "application/json", "miss")); got != 1 {
+		t.Errorf("Expected 1 cache miss, got %v", got)
+	}
+	if got := testutil.ToFloat64(observer.cacheRequests.WithLabelValues("", "application/json", "hit")); got != 1 {
+		t.Errorf("Expected 1 cache hit, got %v", got)
+	}
+	if got := testutil.ToFloat64(observer.servedBytes.WithLabelValues("", "application/json")); got == 0 {
+		t.Errorf("Expected bytes to be served")
+	}
+}
+
+func TestNewObserverAlreadyRegistered(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if _, err := NewObserver(registry); err != nil {
+		t.Fatalf("Failed to create observer: %v", err)
+	}
+	if _, err := NewObserver(registry); err == nil {
+		t.Fatalf("Expected an error when registering the metrics twice")
+	}
+}
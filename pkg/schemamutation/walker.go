@@ -17,6 +17,7 @@ limitations under the License.
 package schemamutation
 
 import (
+	"k8s.io/kube-openapi/pkg/spec3"
 	"k8s.io/kube-openapi/pkg/validation/spec"
 )
 
@@ -33,6 +34,24 @@ type Walker struct {
 	// If the ref needs to be mutated, DO NOT mutate it in-place,
 	// always create a copy, mutate, and return it.
 	RefCallback func(ref *spec.Ref) *spec.Ref
+
+	// OperationCallback, ParameterCallback and ResponseCallback, if not
+	// nil, will be called on each operation, parameter and response of
+	// an OpenAPI v2 spec, like SchemaCallback.
+	OperationCallback func(op *spec.Operation) *spec.Operation
+	ParameterCallback func(param *spec.Parameter) *spec.Parameter
+	ResponseCallback  func(resp *spec.Response) *spec.Response
+
+	// OperationV3Callback, ParameterV3Callback, RequestBodyCallback,
+	// ResponseV3Callback, MediaTypeCallback and HeaderCallback, if not nil,
+	// will be called on each object of that type of an OpenAPI v3 spec,
+	// like SchemaCallback.
+	OperationV3Callback func(op *spec3.Operation) *spec3.Operation
+	ParameterV3Callback func(param *spec3.Parameter) *spec3.Parameter
+	RequestBodyCallback func(body *spec3.RequestBody) *spec3.RequestBody
+	ResponseV3Callback  func(resp *spec3.Response) *spec3.Response
+	MediaTypeCallback   func(mediaType *spec3.MediaType) *spec3.MediaType
+	HeaderCallback      func(header *spec3.Header) *spec3.Header
 }
 
 type SchemaCallbackFunc func(schema *spec.Schema) *spec.Schema
@@ -210,6 +229,9 @@ func (w *Walker) walkParameter(param *spec.Parameter) *spec.Parameter {
 	if param == nil {
 		return nil
 	}
+	if w.ParameterCallback != nil {
+		param = w.ParameterCallback(param)
+	}
 
 	orig := param
 	cloned := false
@@ -267,6 +289,9 @@ func (w *Walker) walkResponse(resp *spec.Response) *spec.Response {
 	if resp == nil {
 		return nil
 	}
+	if w.ResponseCallback != nil {
+		resp = w.ResponseCallback(resp)
+	}
 
 	orig := resp
 	cloned := false
@@ -332,6 +357,9 @@ func (w *Walker) walkOperation(op *spec.Operation) *spec.Operation {
 	if op == nil {
 		return nil
 	}
+	if w.OperationCallback != nil {
+		op = w.OperationCallback(op)
+	}
 
 	orig := op
 	cloned := false
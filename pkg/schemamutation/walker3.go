@@ -0,0 +1,403 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schemamutation
+
+import (
+	"maps"
+	"slices"
+
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ReplaceReferencesV3 rewrites the references of an OpenAPI v3 spec without
+// mutating the input. The output might share data with the input.
+func ReplaceReferencesV3(walkRef func(ref *spec.Ref) *spec.Ref, sp *spec3.OpenAPI) *spec3.OpenAPI {
+	walker := &Walker{RefCallback: walkRef, SchemaCallback: SchemaCallBackNoop}
+	return walker.WalkRootV3(sp)
+}
+
+// walkMap walks the values of m, and returns m, or a copy of it if any
+// value changed.
+func walkMap[K comparable, V any](m map[K]*V, walk func(*V) *V) (map[K]*V, bool) {
+	ret, changed := m, false
+	for k, v := range m {
+		if w := walk(v); w != v {
+			if !changed {
+				changed = true
+				ret = maps.Clone(m)
+			}
+			ret[k] = w
+		}
+	}
+	return ret, changed
+}
+
+// walkSlice walks the values of s, and returns s, or a copy of it if any
+// value changed.
+func walkSlice[V any](s []*V, walk func(*V) *V) ([]*V, bool) {
+	ret, changed := s, false
+	for i, v := range s {
+		if w := walk(v); w != v {
+			if !changed {
+				changed = true
+				ret = slices.Clone(s)
+			}
+			ret[i] = w
+		}
+	}
+	return ret, changed
+}
+
+// cloner returns a function that makes *p a copy of orig the first time
+// it is called, so that it can be mutated.
+func cloner[V any](p **V) func() {
+	orig := *p
+	return func() {
+		if *p == orig {
+			*p = new(V)
+			**p = *orig
+		}
+	}
+}
+
+func (w *Walker) walkExample(example *spec3.Example) *spec3.Example {
+	if example == nil {
+		return nil
+	}
+	clone := cloner(&example)
+	if r := w.RefCallback(&example.Ref); r != &example.Ref {
+		clone()
+		example.Ref = *r
+	}
+	return example
+}
+
+func (w *Walker) walkLink(link *spec3.Link) *spec3.Link {
+	if link == nil {
+		return nil
+	}
+	clone := cloner(&link)
+	if r := w.RefCallback(&link.Ref); r != &link.Ref {
+		clone()
+		link.Ref = *r
+	}
+	return link
+}
+
+func (w *Walker) walkSecurityScheme(scheme *spec3.SecurityScheme) *spec3.SecurityScheme {
+	if scheme == nil {
+		return nil
+	}
+	clone := cloner(&scheme)
+	if r := w.RefCallback(&scheme.Ref); r != &scheme.Ref {
+		clone()
+		scheme.Ref = *r
+	}
+	return scheme
+}
+
+func (w *Walker) walkEncoding(encoding *spec3.Encoding) *spec3.Encoding {
+	if encoding == nil {
+		return nil
+	}
+	clone := cloner(&encoding)
+	if headers, changed := walkMap(encoding.Headers, w.walkHeader); changed {
+		clone()
+		encoding.Headers = headers
+	}
+	return encoding
+}
+
+func (w *Walker) walkMediaType(mediaType *spec3.MediaType) *spec3.MediaType {
+	if mediaType == nil {
+		return nil
+	}
+	if w.MediaTypeCallback != nil {
+		mediaType = w.MediaTypeCallback(mediaType)
+	}
+	clone := cloner(&mediaType)
+	if s := w.WalkSchema(mediaType.Schema); s != mediaType.Schema {
+		clone()
+		mediaType.Schema = s
+	}
+	if examples, changed := walkMap(mediaType.Examples, w.walkExample); changed {
+		clone()
+		mediaType.Examples = examples
+	}
+	if encoding, changed := walkMap(mediaType.Encoding, w.walkEncoding); changed {
+		clone()
+		mediaType.Encoding = encoding
+	}
+	return mediaType
+}
+
+func (w *Walker) walkHeader(header *spec3.Header) *spec3.Header {
+	if header == nil {
+		return nil
+	}
+	if w.HeaderCallback != nil {
+		header = w.HeaderCallback(header)
+	}
+	clone := cloner(&header)
+	if r := w.RefCallback(&header.Ref); r != &header.Ref {
+		clone()
+		header.Ref = *r
+	}
+	if s := w.WalkSchema(header.Schema); s != header.Schema {
+		clone()
+		header.Schema = s
+	}
+	if content, changed := walkMap(header.Content, w.walkMediaType); changed {
+		clone()
+		header.Content = content
+	}
+	if examples, changed := walkMap(header.Examples, w.walkExample); changed {
+		clone()
+		header.Examples = examples
+	}
+	return header
+}
+
+func (w *Walker) walkParameterV3(param *spec3.Parameter) *spec3.Parameter {
+	if param == nil {
+		return nil
+	}
+	if w.ParameterV3Callback != nil {
+		param = w.ParameterV3Callback(param)
+	}
+	clone := cloner(&param)
+	if r := w.RefCallback(&param.Ref); r != &param.Ref {
+		clone()
+		param.Ref = *r
+	}
+	if s := w.WalkSchema(param.Schema); s != param.Schema {
+		clone()
+		param.Schema = s
+	}
+	if content, changed := walkMap(param.Content, w.walkMediaType); changed {
+		clone()
+		param.Content = content
+	}
+	if examples, changed := walkMap(param.Examples, w.walkExample); changed {
+		clone()
+		param.Examples = examples
+	}
+	return param
+}
+
+func (w *Walker) walkRequestBody(body *spec3.RequestBody) *spec3.RequestBody {
+	if body == nil {
+		return nil
+	}
+	if w.RequestBodyCallback != nil {
+		body = w.RequestBodyCallback(body)
+	}
+	clone := cloner(&body)
+	if r := w.RefCallback(&body.Ref); r != &body.Ref {
+		clone()
+		body.Ref = *r
+	}
+	if content, changed := walkMap(body.Content, w.walkMediaType); changed {
+		clone()
+		body.Content = content
+	}
+	return body
+}
+
+func (w *Walker) walkResponseV3(resp *spec3.Response) *spec3.Response {
+	if resp == nil {
+		return nil
+	}
+	if w.ResponseV3Callback != nil {
+		resp = w.ResponseV3Callback(resp)
+	}
+	clone := cloner(&resp)
+	if r := w.RefCallback(&resp.Ref); r != &resp.Ref {
+		clone()
+		resp.Ref = *r
+	}
+	if headers, changed := walkMap(resp.Headers, w.walkHeader); changed {
+		clone()
+		resp.Headers = headers
+	}
+	if content, changed := walkMap(resp.Content, w.walkMediaType); changed {
+		clone()
+		resp.Content = content
+	}
+	if links, changed := walkMap(resp.Links, w.walkLink); changed {
+		clone()
+		resp.Links = links
+	}
+	return resp
+}
+
+func (w *Walker) walkResponsesV3(resps *spec3.Responses) *spec3.Responses {
+	if resps == nil {
+		return nil
+	}
+	clone := cloner(&resps)
+	if r := w.walkResponseV3(resps.Default); r != resps.Default {
+		clone()
+		resps.Default = r
+	}
+	if codes, changed := walkMap(resps.StatusCodeResponses, w.walkResponseV3); changed {
+		clone()
+		resps.StatusCodeResponses = codes
+	}
+	return resps
+}
+
+func (w *Walker) walkOperationV3(op *spec3.Operation) *spec3.Operation {
+	if op == nil {
+		return nil
+	}
+	if w.OperationV3Callback != nil {
+		op = w.OperationV3Callback(op)
+	}
+	clone := cloner(&op)
+	if params, changed := walkSlice(op.Parameters, w.walkParameterV3); changed {
+		clone()
+		op.Parameters = params
+	}
+	if body := w.walkRequestBody(op.RequestBody); body != op.RequestBody {
+		clone()
+		op.RequestBody = body
+	}
+	if resps := w.walkResponsesV3(op.Responses); resps != op.Responses {
+		clone()
+		op.Responses = resps
+	}
+	return op
+}
+
+func (w *Walker) walkPath(path *spec3.Path) *spec3.Path {
+	if path == nil {
+		return nil
+	}
+	clone := cloner(&path)
+	if r := w.RefCallback(&path.Ref); r != &path.Ref {
+		clone()
+		path.Ref = *r
+	}
+	if params, changed := walkSlice(path.Parameters, w.walkParameterV3); changed {
+		clone()
+		path.Parameters = params
+	}
+	if op := w.walkOperationV3(path.Get); op != path.Get {
+		clone()
+		path.Get = op
+	}
+	if op := w.walkOperationV3(path.Put); op != path.Put {
+		clone()
+		path.Put = op
+	}
+	if op := w.walkOperationV3(path.Post); op != path.Post {
+		clone()
+		path.Post = op
+	}
+	if op := w.walkOperationV3(path.Delete); op != path.Delete {
+		clone()
+		path.Delete = op
+	}
+	if op := w.walkOperationV3(path.Options); op != path.Options {
+		clone()
+		path.Options = op
+	}
+	if op := w.walkOperationV3(path.Head); op != path.Head {
+		clone()
+		path.Head = op
+	}
+	if op := w.walkOperationV3(path.Patch); op != path.Patch {
+		clone()
+		path.Patch = op
+	}
+	if op := w.walkOperationV3(path.Trace); op != path.Trace {
+		clone()
+		path.Trace = op
+	}
+	return path
+}
+
+func (w *Walker) walkPathsV3(paths *spec3.Paths) *spec3.Paths {
+	if paths == nil {
+		return nil
+	}
+	clone := cloner(&paths)
+	if p, changed := walkMap(paths.Paths, w.walkPath); changed {
+		clone()
+		paths.Paths = p
+	}
+	return paths
+}
+
+func (w *Walker) walkComponents(components *spec3.Components) *spec3.Components {
+	if components == nil {
+		return nil
+	}
+	clone := cloner(&components)
+	if schemas, changed := walkMap(components.Schemas, w.WalkSchema); changed {
+		clone()
+		components.Schemas = schemas
+	}
+	if schemes, changed := walkMap(components.SecuritySchemes, w.walkSecurityScheme); changed {
+		clone()
+		components.SecuritySchemes = schemes
+	}
+	if responses, changed := walkMap(components.Responses, w.walkResponseV3); changed {
+		clone()
+		components.Responses = responses
+	}
+	if params, changed := walkMap(components.Parameters, w.walkParameterV3); changed {
+		clone()
+		components.Parameters = params
+	}
+	if examples, changed := walkMap(components.Examples, w.walkExample); changed {
+		clone()
+		components.Examples = examples
+	}
+	if bodies, changed := walkMap(components.RequestBodies, w.walkRequestBody); changed {
+		clone()
+		components.RequestBodies = bodies
+	}
+	if links, changed := walkMap(components.Links, w.walkLink); changed {
+		clone()
+		components.Links = links
+	}
+	if headers, changed := walkMap(components.Headers, w.walkHeader); changed {
+		clone()
+		components.Headers = headers
+	}
+	return components
+}
+
+// WalkRootV3 walks the paths and the components of an OpenAPI v3 spec.
+func (w *Walker) WalkRootV3(openapi *spec3.OpenAPI) *spec3.OpenAPI {
+	if openapi == nil {
+		return nil
+	}
+	clone := cloner(&openapi)
+	if paths := w.walkPathsV3(openapi.Paths); paths != openapi.Paths {
+		clone()
+		openapi.Paths = paths
+	}
+	if components := w.walkComponents(openapi.Components); components != openapi.Components {
+		clone()
+		openapi.Components = components
+	}
+	return openapi
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schemamutation
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
+)
+
+const replaceReferencesV3Input = `
+openapi: "3.0.0"
+info:
+  title: test
+  version: v1
+paths:
+  /ref:
+    $ref: "#/components/pathItems/Ref"
+  /test:
+    parameters:
+    - $ref: "#/components/parameters/path"
+    get:
+      parameters:
+      - name: q
+        in: query
+        schema:
+          $ref: "#/components/schemas/Query"
+        examples:
+          q:
+            $ref: "#/components/examples/Query"
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: array
+              items:
+                $ref: "#/components/schemas/Item"
+            encoding:
+              item:
+                headers:
+                  X-Item:
+                    $ref: "#/components/headers/Item"
+      responses:
+        default:
+          $ref: "#/components/responses/Error"
+        "200":
+          description: ok
+          headers:
+            X-Rate:
+              schema:
+                $ref: "#/components/schemas/Rate"
+          content:
+            application/json:
+              schema:
+                properties:
+                  items:
+                    $ref: "#/components/schemas/Item"
+          links:
+            next:
+              $ref: "#/components/links/Next"
+    trace:
+      requestBody:
+        $ref: "#/components/requestBodies/Trace"
+components:
+  schemas:
+    Item:
+      additionalProperties:
+        $ref: "#/components/schemas/Value"
+    Unchanged:
+      type: string
+  parameters:
+    path:
+      name: path
+      in: path
+      content:
+        text/plain:
+          schema:
+            $ref: "#/components/schemas/Path"
+  responses:
+    Error:
+      description: error
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Status"
+  headers:
+    Item:
+      schema:
+        $ref: "#/components/schemas/Header"
+  requestBodies:
+    Trace:
+      $ref: "#/components/requestBodies/Other"
+  securitySchemes:
+    Token:
+      $ref: "#/components/securitySchemes/Other"
+`
+
+func TestReplaceReferencesV3(t *testing.T) {
+	var input, orig, expected *spec3.OpenAPI
+	if err := yaml.Unmarshal([]byte(replaceReferencesV3Input), &input); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal([]byte(replaceReferencesV3Input), &orig); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal([]byte(strings.ReplaceAll(replaceReferencesV3Input, `$ref: "#/components/`, `$ref: "#/components/renamed-`)), &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := 0
+	mutated := ReplaceReferencesV3(func(ref *spec.Ref) *spec.Ref {
+		if ref.String() == "" {
+			return ref
+		}
+		seen++
+		r := spec.MustCreateRef(strings.Replace(ref.String(), "#/components/", "#/components/renamed-", 1))
+		return &r
+	}, input)
+
+	if want := strings.Count(replaceReferencesV3Input, "$ref"); seen != want {
+		t.Errorf("expected %d references to be walked, got %d", want, seen)
+	}
+	if got, want := mustMarshal(t, mutated), mustMarshal(t, expected); got != want {
+		t.Errorf("unexpected result\ngot:  %s\nwant: %s", got, want)
+	}
+	if got, want := mustMarshal(t, input), mustMarshal(t, orig); got != want {
+		t.Errorf("unexpected mutation of the input\ngot:  %s\nwant: %s", got, want)
+	}
+	if mutated.Components.Schemas["Unchanged"] != input.Components.Schemas["Unchanged"] {
+		t.Errorf("expected unchanged schemas to be shared with the input")
+	}
+
+	if same := ReplaceReferencesV3(RefCallbackNoop, input); same != input {
+		t.Errorf("expected the input to be returned when no reference changes")
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
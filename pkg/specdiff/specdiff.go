@@ -0,0 +1,707 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package specdiff compares two OpenAPI documents, e.g. the ones of two
+// releases of an API, and classifies the changes as breaking or compatible.
+//
+// Definitions, or schema components, are compared by name, and references
+// are compared without being followed. Schemas of parameters and request
+// bodies are only used in requests, and schemas of responses only in
+// responses, so some changes are only breaking for one of them: e.g. an
+// enum value added is breaking for clients reading it, but not for
+// clients sending it. Definitions can be used in both, so a change to a
+// definition is breaking if it breaks either.
+package specdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// ChangeKind is a kind of change between two specs.
+type ChangeKind int
+
+const (
+	PathAdded ChangeKind = iota
+	PathRemoved
+	OperationAdded
+	OperationRemoved
+	// ParameterAdded is breaking if the parameter is required.
+	ParameterAdded
+	// ParameterRemoved is breaking if the parameter was required, since
+	// the clients sending it expect it to be taken into account, while
+	// optional ones may be ignored anyway.
+	ParameterRemoved
+	DefinitionAdded
+	DefinitionRemoved
+	PropertyAdded
+	// PropertyRemoved is breaking for requests, since the value sent by
+	// clients would be dropped or rejected, and for responses if the
+	// property was required. Read-only properties are only used in
+	// responses.
+	PropertyRemoved
+	// RequiredAdded is a parameter or a property becoming required. The
+	// name of the property is New. It is breaking for requests.
+	RequiredAdded
+	// RequiredRemoved is a parameter or a property becoming optional. The
+	// name of the property is Old. It is breaking for responses.
+	RequiredRemoved
+	TypeChanged
+	// FormatChanged is breaking if the format is replaced by another one.
+	// A format added narrows the values, which is breaking for requests,
+	// and a format removed widens them, which is breaking for responses.
+	FormatChanged
+	ReferenceChanged
+	// EnumNarrowed is values removed from an enum, in Old, or an enum
+	// added, in New. It is breaking for requests.
+	EnumNarrowed
+	// EnumWidened is values added to an enum, in New, or an enum removed,
+	// in Old. It is breaking for responses.
+	EnumWidened
+	// ListTypeChanged is a change of the x-kubernetes-list-type extension.
+	// A missing list type is the same as atomic.
+	ListTypeChanged
+	// PatchStrategyChanged is a change of the x-kubernetes-patch-strategy
+	// extension.
+	PatchStrategyChanged
+	// ResponseAdded and ResponseRemoved are a status code, or the default
+	// response, added to or removed from an operation.
+	ResponseAdded
+	ResponseRemoved
+	// MediaTypeAdded and MediaTypeRemoved are a media type added to or
+	// removed from the content of an OpenAPI v3 request body or response.
+	MediaTypeAdded
+	MediaTypeRemoved
+)
+
+var changeKinds = map[ChangeKind]struct {
+	name, description string
+}{
+	PathAdded:            {"PathAdded", "path added"},
+	PathRemoved:          {"PathRemoved", "path removed"},
+	OperationAdded:       {"OperationAdded", "operation added"},
+	OperationRemoved:     {"OperationRemoved", "operation removed"},
+	ParameterAdded:       {"ParameterAdded", "parameter added"},
+	ParameterRemoved:     {"ParameterRemoved", "parameter removed"},
+	DefinitionAdded:      {"DefinitionAdded", "definition added"},
+	DefinitionRemoved:    {"DefinitionRemoved", "definition removed"},
+	PropertyAdded:        {"PropertyAdded", "property added"},
+	PropertyRemoved:      {"PropertyRemoved", "property removed"},
+	RequiredAdded:        {"RequiredAdded", "became required"},
+	RequiredRemoved:      {"RequiredRemoved", "became optional"},
+	TypeChanged:          {"TypeChanged", "type changed"},
+	FormatChanged:        {"FormatChanged", "format changed"},
+	ReferenceChanged:     {"ReferenceChanged", "reference changed"},
+	EnumNarrowed:         {"EnumNarrowed", "enum narrowed"},
+	EnumWidened:          {"EnumWidened", "enum widened"},
+	ListTypeChanged:      {"ListTypeChanged", "list type changed"},
+	PatchStrategyChanged: {"PatchStrategyChanged", "patch strategy changed"},
+	ResponseAdded:        {"ResponseAdded", "response added"},
+	ResponseRemoved:      {"ResponseRemoved", "response removed"},
+	MediaTypeAdded:       {"MediaTypeAdded", "media type added"},
+	MediaTypeRemoved:     {"MediaTypeRemoved", "media type removed"},
+}
+
+func (k ChangeKind) String() string {
+	if kind, found := changeKinds[k]; found {
+		return kind.name
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// MarshalText marshals the kind as its name.
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	if _, found := changeKinds[k]; !found {
+		return nil, fmt.Errorf("invalid change kind %d", int(k))
+	}
+	return []byte(k.String()), nil
+}
+
+// Change is a change between two specs.
+type Change struct {
+	Kind     ChangeKind `json:"kind"`
+	Breaking bool       `json:"breaking"`
+	// Pointer is the JSON pointer to the changed value in the new spec,
+	// or to the removed value in the old spec.
+	Pointer string `json:"pointer"`
+	// Old and New are the old and new values, if the kind of change has
+	// any, e.g. the old and new type.
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	severity := "compatible"
+	if c.Breaking {
+		severity = "breaking"
+	}
+	description := c.Kind.String()
+	if kind, found := changeKinds[c.Kind]; found {
+		description = kind.description
+	}
+	if c.Old == nil && c.New == nil {
+		return fmt.Sprintf("%s: %s: %s", severity, c.Pointer, description)
+	}
+	return fmt.Sprintf("%s: %s: %s from %s to %s", severity, c.Pointer, description, jsonString(c.Old), jsonString(c.New))
+}
+
+func jsonString(v interface{}) string {
+	if v == nil {
+		return "<none>"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// HasBreaking returns true if any of the changes is breaking.
+func HasBreaking(changes []Change) bool {
+	return slices.ContainsFunc(changes, func(c Change) bool { return c.Breaking })
+}
+
+// WriteText writes the changes to w, one per line.
+func WriteText(w io.Writer, changes []Change) error {
+	for _, c := range changes {
+		if _, err := fmt.Fprintln(w, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the changes to w as a JSON array.
+func WriteJSON(w io.Writer, changes []Change) error {
+	if changes == nil {
+		changes = []Change{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(changes)
+}
+
+// Diff returns the changes from an OpenAPI v2 spec to another, sorted by
+// pointer.
+func Diff(oldSpec, newSpec *spec.Swagger) []Change {
+	d := &differ{}
+	oldPaths, newPaths := map[string]spec.PathItem{}, map[string]spec.PathItem{}
+	if oldSpec.Paths != nil {
+		oldPaths = oldSpec.Paths.Paths
+	}
+	if newSpec.Paths != nil {
+		newPaths = newSpec.Paths.Paths
+	}
+	for _, k := range unionKeys(oldPaths, newPaths) {
+		pointer := "/paths/" + escapePointerToken(k)
+		oldItem, inOld := oldPaths[k]
+		newItem, inNew := newPaths[k]
+		if !d.addedOrRemoved(PathAdded, PathRemoved, pointer, inOld, inNew) {
+			continue
+		}
+		for _, method := range methods {
+			oldOp, newOp := operation(&oldItem, method), operation(&newItem, method)
+			p := pointer + "/" + method
+			if !d.addedOrRemoved(OperationAdded, OperationRemoved, p, oldOp != nil, newOp != nil) {
+				continue
+			}
+			d.parameters(parameters(oldSpec, pointer, oldItem.Parameters, p, oldOp.Parameters), parameters(newSpec, pointer, newItem.Parameters, p, newOp.Parameters))
+			d.responses(p, oldSpec, oldOp.Responses, newSpec, newOp.Responses)
+		}
+	}
+	definitions(d, "/definitions/", oldSpec.Definitions, newSpec.Definitions, func(s spec.Schema) *spec.Schema { return &s })
+	return d.sorted()
+}
+
+// DiffV3 returns the changes from an OpenAPI v3 spec to another, sorted by
+// pointer.
+func DiffV3(oldSpec, newSpec *spec3.OpenAPI) []Change {
+	d := &differ{}
+	oldPaths, newPaths := map[string]*spec3.Path{}, map[string]*spec3.Path{}
+	if oldSpec.Paths != nil {
+		oldPaths = oldSpec.Paths.Paths
+	}
+	if newSpec.Paths != nil {
+		newPaths = newSpec.Paths.Paths
+	}
+	for _, k := range unionKeys(oldPaths, newPaths) {
+		pointer := "/paths/" + escapePointerToken(k)
+		oldPath, newPath := oldPaths[k], newPaths[k]
+		if !d.addedOrRemoved(PathAdded, PathRemoved, pointer, oldPath != nil, newPath != nil) {
+			continue
+		}
+		for _, method := range methodsV3 {
+			oldOp, newOp := operationV3(oldPath, method), operationV3(newPath, method)
+			p := pointer + "/" + method
+			if !d.addedOrRemoved(OperationAdded, OperationRemoved, p, oldOp != nil, newOp != nil) {
+				continue
+			}
+			d.parameters(parametersV3(oldSpec, pointer, oldPath.Parameters, p, oldOp.Parameters), parametersV3(newSpec, pointer, newPath.Parameters, p, newOp.Parameters))
+			oldBody, newBody := resolveV3(oldSpec, oldOp.RequestBody, requestBodies), resolveV3(newSpec, newOp.RequestBody, requestBodies)
+			if oldBody != nil && newBody != nil {
+				d.content(p+"/requestBody", request, oldBody.Content, newBody.Content)
+			}
+			d.responsesV3(p, oldSpec, oldOp.Responses, newSpec, newOp.Responses)
+		}
+	}
+	var oldSchemas, newSchemas map[string]*spec.Schema
+	if oldSpec.Components != nil {
+		oldSchemas = oldSpec.Components.Schemas
+	}
+	if newSpec.Components != nil {
+		newSchemas = newSpec.Components.Schemas
+	}
+	definitions(d, "/components/schemas/", oldSchemas, newSchemas, func(s *spec.Schema) *spec.Schema { return s })
+	return d.sorted()
+}
+
+var (
+	methods   = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+	methodsV3 = append(slices.Clone(methods), "trace")
+)
+
+func operation(pathItem *spec.PathItem, method string) *spec.Operation {
+	switch method {
+	case "get":
+		return pathItem.Get
+	case "put":
+		return pathItem.Put
+	case "post":
+		return pathItem.Post
+	case "delete":
+		return pathItem.Delete
+	case "options":
+		return pathItem.Options
+	case "head":
+		return pathItem.Head
+	case "patch":
+		return pathItem.Patch
+	}
+	return nil
+}
+
+func operationV3(path *spec3.Path, method string) *spec3.Operation {
+	if path == nil {
+		return nil
+	}
+	switch method {
+	case "get":
+		return path.Get
+	case "put":
+		return path.Put
+	case "post":
+		return path.Post
+	case "delete":
+		return path.Delete
+	case "options":
+		return path.Options
+	case "head":
+		return path.Head
+	case "patch":
+		return path.Patch
+	case "trace":
+		return path.Trace
+	}
+	return nil
+}
+
+// parameter is an OpenAPI v2 or v3 parameter of an operation.
+type parameter struct {
+	pointer  string
+	required bool
+	// schema is the schema of the parameter, made of the type, format,
+	// enum and items of an OpenAPI v2 parameter other than the body.
+	schema        *spec.Schema
+	schemaPointer string
+}
+
+// parameters returns the parameters of an OpenAPI v2 operation, including
+// the ones of its path, by location and name.
+func parameters(root *spec.Swagger, pathPointer string, pathParams []spec.Parameter, opPointer string, opParams []spec.Parameter) map[string]parameter {
+	ret := map[string]parameter{}
+	add := func(pointer string, params []spec.Parameter) {
+		for i, param := range params {
+			p := parameter{pointer: pointer + "/parameters/" + strconv.Itoa(i)}
+			if refStr := param.Ref.String(); strings.HasPrefix(refStr, "#/parameters/") {
+				param = root.Parameters[refStr[len("#/parameters/"):]]
+			}
+			p.required = param.Required
+			if param.In == "body" {
+				p.schema, p.schemaPointer = param.Schema, p.pointer+"/schema"
+			} else {
+				p.schema, p.schemaPointer = simpleSchema(&param.SimpleSchema, param.Enum), p.pointer
+			}
+			ret[param.In+"/"+param.Name] = p
+		}
+	}
+	add(pathPointer, pathParams)
+	add(opPointer, opParams)
+	return ret
+}
+
+func simpleSchema(s *spec.SimpleSchema, enum []interface{}) *spec.Schema {
+	ret := &spec.Schema{}
+	if s.Type != "" {
+		ret.Type = spec.StringOrArray{s.Type}
+	}
+	ret.Format = s.Format
+	ret.Enum = enum
+	if s.Items != nil {
+		ret.Items = &spec.SchemaOrArray{Schema: simpleSchema(&s.Items.SimpleSchema, s.Items.Enum)}
+	}
+	return ret
+}
+
+// parametersV3 returns the parameters of an OpenAPI v3 operation,
+// including the ones of its path, by location and name.
+func parametersV3(root *spec3.OpenAPI, pathPointer string, pathParams []*spec3.Parameter, opPointer string, opParams []*spec3.Parameter) map[string]parameter {
+	ret := map[string]parameter{}
+	add := func(pointer string, params []*spec3.Parameter) {
+		for i, param := range params {
+			param = resolveV3(root, param, parametersOf)
+			if param == nil {
+				continue
+			}
+			p := parameter{pointer: pointer + "/parameters/" + strconv.Itoa(i), required: param.Required, schema: param.Schema}
+			p.schemaPointer = p.pointer + "/schema"
+			ret[param.In+"/"+param.Name] = p
+		}
+	}
+	add(pathPointer, pathParams)
+	add(opPointer, opParams)
+	return ret
+}
+
+func parametersOf(c *spec3.Components) map[string]*spec3.Parameter    { return c.Parameters }
+func requestBodies(c *spec3.Components) map[string]*spec3.RequestBody { return c.RequestBodies }
+func responsesOf(c *spec3.Components) map[string]*spec3.Response      { return c.Responses }
+
+// resolveV3 returns the component referenced by v, if any, or else v.
+func resolveV3[V interface {
+	*spec3.Parameter | *spec3.RequestBody | *spec3.Response
+}](root *spec3.OpenAPI, v V, components func(*spec3.Components) map[string]V) V {
+	if v == nil || root.Components == nil {
+		return v
+	}
+	var ref spec.Ref
+	switch v := any(v).(type) {
+	case *spec3.Parameter:
+		ref = v.Ref
+	case *spec3.RequestBody:
+		ref = v.Ref
+	case *spec3.Response:
+		ref = v.Ref
+	}
+	refStr := ref.String()
+	if i := strings.LastIndex(refStr, "/"); strings.HasPrefix(refStr, "#/components/") && i >= 0 {
+		return components(root.Components)[refStr[i+1:]]
+	}
+	return v
+}
+
+// usage is where a schema is used, which decides whether some changes are
+// breaking.
+type usage int
+
+const (
+	request usage = 1 << iota
+	response
+	requestAndResponse = request | response
+)
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(kind ChangeKind, breaking bool, pointer string, old, new interface{}) {
+	d.changes = append(d.changes, Change{Kind: kind, Breaking: breaking, Pointer: pointer, Old: old, New: new})
+}
+
+// addedOrRemoved adds the change of a value that is only in one of the
+// specs, and returns true if it is in both.
+func (d *differ) addedOrRemoved(added, removed ChangeKind, pointer string, inOld, inNew bool) bool {
+	switch {
+	case inOld && !inNew:
+		d.add(removed, true, pointer, nil, nil)
+	case !inOld && inNew:
+		d.add(added, false, pointer, nil, nil)
+	}
+	return inOld && inNew
+}
+
+func (d *differ) sorted() []Change {
+	slices.SortStableFunc(d.changes, func(a, b Change) int {
+		return strings.Compare(a.Pointer, b.Pointer)
+	})
+	return d.changes
+}
+
+func definitions[M ~map[string]V, V any](d *differ, prefix string, oldDefs, newDefs M, schema func(V) *spec.Schema) {
+	for _, k := range unionKeys(oldDefs, newDefs) {
+		pointer := prefix + escapePointerToken(k)
+		oldDef, inOld := oldDefs[k]
+		newDef, inNew := newDefs[k]
+		if d.addedOrRemoved(DefinitionAdded, DefinitionRemoved, pointer, inOld, inNew) {
+			d.schema(pointer, pointer, requestAndResponse, schema(oldDef), schema(newDef))
+		}
+	}
+}
+
+func (d *differ) parameters(oldParams, newParams map[string]parameter) {
+	for _, k := range unionKeys(oldParams, newParams) {
+		oldParam, inOld := oldParams[k]
+		newParam, inNew := newParams[k]
+		switch {
+		case !inNew:
+			d.add(ParameterRemoved, oldParam.required, oldParam.pointer, nil, nil)
+		case !inOld:
+			d.add(ParameterAdded, newParam.required, newParam.pointer, nil, nil)
+		default:
+			d.required(newParam.pointer+"/required", request, oldParam.required, newParam.required)
+			d.schema(oldParam.schemaPointer, newParam.schemaPointer, request, oldParam.schema, newParam.schema)
+		}
+	}
+}
+
+func (d *differ) required(pointer string, use usage, old, new bool) {
+	switch {
+	case !old && new:
+		d.add(RequiredAdded, use&request != 0, pointer, false, true)
+	case old && !new:
+		d.add(RequiredRemoved, use&response != 0, pointer, true, false)
+	}
+}
+
+func (d *differ) responses(pointer string, oldRoot *spec.Swagger, oldResps *spec.Responses, newRoot *spec.Swagger, newResps *spec.Responses) {
+	if oldResps == nil || newResps == nil {
+		return
+	}
+	resolve := func(root *spec.Swagger, resp *spec.Response) *spec.Response {
+		if refStr := resp.Ref.String(); strings.HasPrefix(refStr, "#/responses/") {
+			if r, found := root.Responses[refStr[len("#/responses/"):]]; found {
+				return &r
+			}
+		}
+		return resp
+	}
+	p := pointer + "/responses/default"
+	if d.addedOrRemoved(ResponseAdded, ResponseRemoved, p, oldResps.Default != nil, newResps.Default != nil) {
+		d.schema(p+"/schema", p+"/schema", response, resolve(oldRoot, oldResps.Default).Schema, resolve(newRoot, newResps.Default).Schema)
+	}
+	for _, code := range unionKeys(oldResps.StatusCodeResponses, newResps.StatusCodeResponses) {
+		oldResp, inOld := oldResps.StatusCodeResponses[code]
+		newResp, inNew := newResps.StatusCodeResponses[code]
+		p := pointer + "/responses/" + strconv.Itoa(code)
+		if d.addedOrRemoved(ResponseAdded, ResponseRemoved, p, inOld, inNew) {
+			d.schema(p+"/schema", p+"/schema", response, resolve(oldRoot, &oldResp).Schema, resolve(newRoot, &newResp).Schema)
+		}
+	}
+}
+
+func (d *differ) responsesV3(pointer string, oldRoot *spec3.OpenAPI, oldResps *spec3.Responses, newRoot *spec3.OpenAPI, newResps *spec3.Responses) {
+	if oldResps == nil || newResps == nil {
+		return
+	}
+	compare := func(p string, oldResp, newResp *spec3.Response) {
+		if !d.addedOrRemoved(ResponseAdded, ResponseRemoved, p, oldResp != nil, newResp != nil) {
+			return
+		}
+		oldResp, newResp = resolveV3(oldRoot, oldResp, responsesOf), resolveV3(newRoot, newResp, responsesOf)
+		if oldResp != nil && newResp != nil {
+			d.content(p, response, oldResp.Content, newResp.Content)
+		}
+	}
+	compare(pointer+"/responses/default", oldResps.Default, newResps.Default)
+	for _, code := range unionKeys(oldResps.StatusCodeResponses, newResps.StatusCodeResponses) {
+		compare(pointer+"/responses/"+strconv.Itoa(code), oldResps.StatusCodeResponses[code], newResps.StatusCodeResponses[code])
+	}
+}
+
+func (d *differ) content(pointer string, use usage, oldContent, newContent map[string]*spec3.MediaType) {
+	for _, k := range unionKeys(oldContent, newContent) {
+		oldMediaType, inOld := oldContent[k]
+		newMediaType, inNew := newContent[k]
+		p := pointer + "/content/" + escapePointerToken(k)
+		if d.addedOrRemoved(MediaTypeAdded, MediaTypeRemoved, p, inOld, inNew) && oldMediaType != nil && newMediaType != nil {
+			d.schema(p+"/schema", p+"/schema", use, oldMediaType.Schema, newMediaType.Schema)
+		}
+	}
+}
+
+const (
+	listTypeKey      = "x-kubernetes-list-type"
+	patchStrategyKey = "x-kubernetes-patch-strategy"
+)
+
+func (d *differ) schema(oldPointer, newPointer string, use usage, oldSchema, newSchema *spec.Schema) {
+	if oldSchema == nil || newSchema == nil {
+		return
+	}
+	if oldRef, newRef := oldSchema.Ref.String(), newSchema.Ref.String(); oldRef != newRef {
+		d.add(ReferenceChanged, true, newPointer, nonEmpty(oldRef), nonEmpty(newRef))
+	}
+	if !slices.Equal(oldSchema.Type, newSchema.Type) {
+		d.add(TypeChanged, true, newPointer+"/type", nonEmptySlice(oldSchema.Type), nonEmptySlice(newSchema.Type))
+	}
+	switch {
+	case oldSchema.Format == newSchema.Format:
+	case oldSchema.Format == "":
+		d.add(FormatChanged, use&request != 0, newPointer+"/format", nil, newSchema.Format)
+	case newSchema.Format == "":
+		d.add(FormatChanged, use&response != 0, newPointer+"/format", oldSchema.Format, nil)
+	default:
+		d.add(FormatChanged, true, newPointer+"/format", oldSchema.Format, newSchema.Format)
+	}
+	d.enum(newPointer+"/enum", use, oldSchema.Enum, newSchema.Enum)
+
+	oldListType, _ := oldSchema.Extensions.GetString(listTypeKey)
+	newListType, _ := newSchema.Extensions.GetString(listTypeKey)
+	if oldListType == "" {
+		oldListType = "atomic"
+	}
+	if newListType == "" {
+		newListType = "atomic"
+	}
+	if oldListType != newListType {
+		d.add(ListTypeChanged, true, newPointer+"/"+listTypeKey, oldListType, newListType)
+	}
+	oldStrategy, _ := oldSchema.Extensions.GetString(patchStrategyKey)
+	newStrategy, _ := newSchema.Extensions.GetString(patchStrategyKey)
+	if oldStrategy != newStrategy {
+		d.add(PatchStrategyChanged, true, newPointer+"/"+patchStrategyKey, nonEmpty(oldStrategy), nonEmpty(newStrategy))
+	}
+
+	for _, name := range newSchema.Required {
+		if !slices.Contains(oldSchema.Required, name) {
+			d.add(RequiredAdded, use&request != 0, newPointer+"/required", nil, name)
+		}
+	}
+	for _, name := range oldSchema.Required {
+		if !slices.Contains(newSchema.Required, name) {
+			d.add(RequiredRemoved, use&response != 0, newPointer+"/required", name, nil)
+		}
+	}
+
+	for _, k := range unionKeys(oldSchema.Properties, newSchema.Properties) {
+		token := "/properties/" + escapePointerToken(k)
+		oldProp, inOld := oldSchema.Properties[k]
+		newProp, inNew := newSchema.Properties[k]
+		switch {
+		case !inNew:
+			propUse := use
+			if oldProp.ReadOnly {
+				propUse &= response
+			}
+			breaking := propUse&request != 0 || propUse&response != 0 && slices.Contains(oldSchema.Required, k)
+			d.add(PropertyRemoved, breaking, oldPointer+token, nil, nil)
+		case !inOld:
+			d.add(PropertyAdded, false, newPointer+token, nil, nil)
+		default:
+			propUse := use
+			if oldProp.ReadOnly && newProp.ReadOnly {
+				propUse &= response
+			}
+			d.schema(oldPointer+token, newPointer+token, propUse, &oldProp, &newProp)
+		}
+	}
+	if oldSchema.Items != nil && newSchema.Items != nil {
+		d.schema(oldPointer+"/items", newPointer+"/items", use, oldSchema.Items.Schema, newSchema.Items.Schema)
+	}
+	if oldSchema.AdditionalProperties != nil && newSchema.AdditionalProperties != nil {
+		d.schema(oldPointer+"/additionalProperties", newPointer+"/additionalProperties", use, oldSchema.AdditionalProperties.Schema, newSchema.AdditionalProperties.Schema)
+	}
+	for i := range min(len(oldSchema.AllOf), len(newSchema.AllOf)) {
+		token := "/allOf/" + strconv.Itoa(i)
+		d.schema(oldPointer+token, newPointer+token, use, &oldSchema.AllOf[i], &newSchema.AllOf[i])
+	}
+}
+
+// enum adds the changes of an enum. Narrowing an enum breaks the clients
+// sending the removed values, and widening it breaks the clients that
+// don't expect the added values.
+func (d *differ) enum(pointer string, use usage, oldEnum, newEnum []interface{}) {
+	narrowed, widened := use&request != 0, use&response != 0
+	switch {
+	case len(oldEnum) == 0 && len(newEnum) == 0:
+		return
+	case len(oldEnum) == 0:
+		d.add(EnumNarrowed, narrowed, pointer, nil, newEnum)
+		return
+	case len(newEnum) == 0:
+		d.add(EnumWidened, widened, pointer, oldEnum, nil)
+		return
+	}
+	if removed := valuesNotIn(oldEnum, newEnum); len(removed) > 0 {
+		d.add(EnumNarrowed, narrowed, pointer, removed, nil)
+	}
+	if added := valuesNotIn(newEnum, oldEnum); len(added) > 0 {
+		d.add(EnumWidened, widened, pointer, nil, added)
+	}
+}
+
+// valuesNotIn returns the values of s1 that are not in s2, comparing their
+// JSON encoding.
+func valuesNotIn(s1, s2 []interface{}) []interface{} {
+	in2 := make(map[string]bool, len(s2))
+	for _, v := range s2 {
+		in2[jsonString(v)] = true
+	}
+	var ret []interface{}
+	for _, v := range s1 {
+		if !in2[jsonString(v)] {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func nonEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nonEmptySlice(s []string) interface{} {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
+
+// unionKeys returns the keys of both maps, sorted.
+func unionKeys[K interface{ ~string | ~int }, V any](m1, m2 map[K]V) []K {
+	keys := slices.Collect(maps.Keys(m1))
+	for k := range m2 {
+		if _, found := m1[k]; !found {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func escapePointerToken(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
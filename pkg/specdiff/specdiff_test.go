@@ -0,0 +1,442 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package specdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/yaml"
+)
+
+const oldSpec = `
+swagger: "2.0"
+paths:
+  /api/v1/pods:
+    parameters:
+    - name: pretty
+      in: query
+      type: string
+      enum: ["true"]
+    get:
+      parameters:
+      - $ref: "#/parameters/limit"
+      - name: watch
+        in: query
+        type: boolean
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/PodList"
+        404:
+          description: Not Found
+    delete:
+      responses:
+        200:
+          description: OK
+  /api/v1/nodes:
+    get:
+      responses:
+        200:
+          description: OK
+parameters:
+  limit:
+    name: limit
+    in: query
+    type: integer
+definitions:
+  PodList:
+    type: object
+    properties:
+      items:
+        type: array
+        items:
+          $ref: "#/definitions/Pod"
+  Pod:
+    type: object
+    required: [name]
+    properties:
+      name:
+        type: string
+      phase:
+        type: string
+        enum: [Pending, Running, Unknown]
+      containers:
+        type: array
+        x-kubernetes-patch-strategy: merge
+        items:
+          type: string
+      hostIP:
+        type: string
+      age:
+        type: integer
+        format: int32
+  Node:
+    type: object
+`
+
+const newSpec = `
+swagger: "2.0"
+paths:
+  /api/v1/pods:
+    parameters:
+    - name: pretty
+      in: query
+      type: string
+      required: true
+    get:
+      parameters:
+      - name: limit
+        in: query
+        type: string
+      - name: labelSelector
+        in: query
+        type: string
+        required: true
+      - name: fieldSelector
+        in: query
+        type: string
+      responses:
+        200:
+          schema:
+            $ref: "#/definitions/PodList"
+        default:
+          description: Error
+    post:
+      responses:
+        201:
+          description: Created
+  /api/v1/services:
+    get:
+      responses:
+        200:
+          description: OK
+definitions:
+  PodList:
+    type: object
+    properties:
+      items:
+        type: array
+        x-kubernetes-list-type: atomic
+        items:
+          $ref: "#/definitions/Pod"
+  Pod:
+    type: object
+    required: [phase]
+    properties:
+      name:
+        type: string
+      phase:
+        type: string
+        enum: [Pending, Running, Succeeded]
+      containers:
+        type: array
+        x-kubernetes-list-type: map
+        items:
+          type: string
+      age:
+        type: integer
+      nodeName:
+        type: string
+  Service:
+    type: object
+`
+
+func TestDiff(t *testing.T) {
+	var oldSwagger, newSwagger *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(oldSpec), &oldSwagger))
+	require.NoError(t, yaml.Unmarshal([]byte(newSpec), &newSwagger))
+
+	changes := Diff(oldSwagger, newSwagger)
+	assert.Equal(t, []Change{
+		{Kind: DefinitionRemoved, Breaking: true, Pointer: "/definitions/Node"},
+		{Kind: FormatChanged, Breaking: true, Pointer: "/definitions/Pod/properties/age/format", Old: "int32"},
+		{Kind: ListTypeChanged, Breaking: true, Pointer: "/definitions/Pod/properties/containers/x-kubernetes-list-type", Old: "atomic", New: "map"},
+		{Kind: PatchStrategyChanged, Breaking: true, Pointer: "/definitions/Pod/properties/containers/x-kubernetes-patch-strategy", Old: "merge"},
+		{Kind: PropertyRemoved, Breaking: true, Pointer: "/definitions/Pod/properties/hostIP"},
+		{Kind: PropertyAdded, Pointer: "/definitions/Pod/properties/nodeName"},
+		{Kind: EnumNarrowed, Breaking: true, Pointer: "/definitions/Pod/properties/phase/enum", Old: []interface{}{"Unknown"}},
+		{Kind: EnumWidened, Breaking: true, Pointer: "/definitions/Pod/properties/phase/enum", New: []interface{}{"Succeeded"}},
+		{Kind: RequiredAdded, Breaking: true, Pointer: "/definitions/Pod/required", New: "phase"},
+		{Kind: RequiredRemoved, Breaking: true, Pointer: "/definitions/Pod/required", Old: "name"},
+		{Kind: DefinitionAdded, Pointer: "/definitions/Service"},
+		{Kind: PathRemoved, Breaking: true, Pointer: "/paths/~1api~1v1~1nodes"},
+		{Kind: OperationRemoved, Breaking: true, Pointer: "/paths/~1api~1v1~1pods/delete"},
+		{Kind: TypeChanged, Breaking: true, Pointer: "/paths/~1api~1v1~1pods/get/parameters/0/type", Old: []string{"integer"}, New: []string{"string"}},
+		{Kind: ParameterAdded, Breaking: true, Pointer: "/paths/~1api~1v1~1pods/get/parameters/1"},
+		{Kind: ParameterRemoved, Pointer: "/paths/~1api~1v1~1pods/get/parameters/1"},
+		{Kind: ParameterAdded, Pointer: "/paths/~1api~1v1~1pods/get/parameters/2"},
+		{Kind: ResponseRemoved, Breaking: true, Pointer: "/paths/~1api~1v1~1pods/get/responses/404"},
+		{Kind: ResponseAdded, Pointer: "/paths/~1api~1v1~1pods/get/responses/default"},
+		{Kind: EnumWidened, Pointer: "/paths/~1api~1v1~1pods/parameters/0/enum", Old: []interface{}{"true"}},
+		{Kind: RequiredAdded, Breaking: true, Pointer: "/paths/~1api~1v1~1pods/parameters/0/required", Old: false, New: true},
+		{Kind: OperationAdded, Pointer: "/paths/~1api~1v1~1pods/post"},
+		{Kind: PathAdded, Pointer: "/paths/~1api~1v1~1services"},
+	}, changes)
+	assert.True(t, HasBreaking(changes))
+	assert.Empty(t, Diff(oldSwagger, oldSwagger))
+
+	var text bytes.Buffer
+	require.NoError(t, WriteText(&text, []Change{changes[0], changes[8]}))
+	assert.Equal(t, `breaking: /definitions/Node: definition removed
+breaking: /definitions/Pod/required: became required from <none> to "phase"
+`, text.String())
+
+	var data bytes.Buffer
+	require.NoError(t, WriteJSON(&data, changes[1:2]))
+	assert.JSONEq(t, `[{"kind":"FormatChanged","breaking":true,"pointer":"/definitions/Pod/properties/age/format","old":"int32"}]`, data.String())
+	data.Reset()
+	require.NoError(t, WriteJSON(&data, nil))
+	assert.JSONEq(t, `[]`, data.String())
+}
+
+func TestDiffRequestsAndResponses(t *testing.T) {
+	var oldSwagger, newSwagger *spec.Swagger
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /foos:
+    post:
+      parameters:
+      - name: dryRun
+        in: query
+        type: string
+        required: true
+      - name: body
+        in: body
+        schema:
+          type: object
+          properties:
+            optional:
+              type: string
+            formatAdded:
+              type: string
+            formatRemoved:
+              type: string
+              format: byte
+      responses:
+        200:
+          schema:
+            type: object
+            required: [required]
+            properties:
+              optional:
+                type: string
+              required:
+                type: string
+              formatAdded:
+                type: string
+              formatRemoved:
+                type: string
+                format: byte
+definitions:
+  Foo:
+    type: object
+    properties:
+      status:
+        type: string
+        readOnly: true
+`), &oldSwagger))
+	require.NoError(t, yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /foos:
+    post:
+      parameters:
+      - name: body
+        in: body
+        schema:
+          type: object
+          properties:
+            formatAdded:
+              type: string
+              format: byte
+            formatRemoved:
+              type: string
+      responses:
+        200:
+          schema:
+            type: object
+            properties:
+              formatAdded:
+                type: string
+                format: byte
+              formatRemoved:
+                type: string
+definitions:
+  Foo:
+    type: object
+`), &newSwagger))
+
+	assert.Equal(t, []Change{
+		{Kind: PropertyRemoved, Pointer: "/definitions/Foo/properties/status"},
+		{Kind: ParameterRemoved, Breaking: true, Pointer: "/paths/~1foos/post/parameters/0"},
+		{Kind: FormatChanged, Breaking: true, Pointer: "/paths/~1foos/post/parameters/0/schema/properties/formatAdded/format", New: "byte"},
+		{Kind: FormatChanged, Pointer: "/paths/~1foos/post/parameters/0/schema/properties/formatRemoved/format", Old: "byte"},
+		{Kind: PropertyRemoved, Breaking: true, Pointer: "/paths/~1foos/post/parameters/1/schema/properties/optional"},
+		{Kind: FormatChanged, Pointer: "/paths/~1foos/post/responses/200/schema/properties/formatAdded/format", New: "byte"},
+		{Kind: FormatChanged, Breaking: true, Pointer: "/paths/~1foos/post/responses/200/schema/properties/formatRemoved/format", Old: "byte"},
+		{Kind: PropertyRemoved, Pointer: "/paths/~1foos/post/responses/200/schema/properties/optional"},
+		{Kind: PropertyRemoved, Breaking: true, Pointer: "/paths/~1foos/post/responses/200/schema/properties/required"},
+		{Kind: RequiredRemoved, Breaking: true, Pointer: "/paths/~1foos/post/responses/200/schema/required", Old: "required"},
+	}, Diff(oldSwagger, newSwagger))
+}
+
+func TestDiffV3(t *testing.T) {
+	var oldOpenAPI, newOpenAPI *spec3.OpenAPI
+	require.NoError(t, yaml.Unmarshal([]byte(`
+openapi: 3.0.0
+info:
+  title: test
+  version: v1
+paths:
+  /api/v1/pods:
+    post:
+      parameters:
+      - $ref: "#/components/parameters/dryRun"
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Pod"
+      responses:
+        "201":
+          $ref: "#/components/responses/Created"
+        "202":
+          description: Accepted
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [name]
+                properties:
+                  name:
+                    type: string
+                    enum: [A]
+        "404":
+          description: Not Found
+components:
+  parameters:
+    dryRun:
+      name: dryRun
+      in: query
+      schema:
+        type: string
+  responses:
+    Created:
+      description: Created
+      content:
+        application/json:
+          schema:
+            type: object
+        application/yaml:
+          schema:
+            type: object
+  schemas:
+    Pod:
+      type: object
+`), &oldOpenAPI))
+	require.NoError(t, yaml.Unmarshal([]byte(`
+openapi: 3.0.0
+info:
+  title: test
+  version: v1
+paths:
+  /api/v1/pods:
+    post:
+      parameters:
+      - name: dryRun
+        in: query
+        schema:
+          type: string
+          enum: [All]
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/PodV2"
+          application/yaml:
+            schema:
+              $ref: "#/components/schemas/PodV2"
+      responses:
+        "201":
+          $ref: "#/components/responses/Created"
+        "202":
+          description: Accepted
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  name:
+                    type: string
+                    enum: [B]
+    trace:
+      responses:
+        "200":
+          description: OK
+components:
+  responses:
+    Created:
+      description: Created
+      content:
+        application/json:
+          schema:
+            type: string
+  schemas:
+    PodV2:
+      type: object
+`), &newOpenAPI))
+
+	assert.Equal(t, []Change{
+		{Kind: DefinitionRemoved, Breaking: true, Pointer: "/components/schemas/Pod"},
+		{Kind: DefinitionAdded, Pointer: "/components/schemas/PodV2"},
+		{Kind: EnumNarrowed, Breaking: true, Pointer: "/paths/~1api~1v1~1pods/post/parameters/0/schema/enum", New: []interface{}{"All"}},
+		{Kind: ReferenceChanged, Breaking: true, Pointer: "/paths/~1api~1v1~1pods/post/requestBody/content/application~1json/schema", Old: "#/components/schemas/Pod", New: "#/components/schemas/PodV2"},
+		{Kind: MediaTypeAdded, Pointer: "/paths/~1api~1v1~1pods/post/requestBody/content/application~1yaml"},
+		{Kind: TypeChanged, Breaking: true, Pointer: "/paths/~1api~1v1~1pods/post/responses/201/content/application~1json/schema/type", Old: []string{"object"}, New: []string{"string"}},
+		{Kind: MediaTypeRemoved, Breaking: true, Pointer: "/paths/~1api~1v1~1pods/post/responses/201/content/application~1yaml"},
+		{Kind: EnumNarrowed, Pointer: "/paths/~1api~1v1~1pods/post/responses/202/content/application~1json/schema/properties/name/enum", Old: []interface{}{"A"}},
+		{Kind: EnumWidened, Breaking: true, Pointer: "/paths/~1api~1v1~1pods/post/responses/202/content/application~1json/schema/properties/name/enum", New: []interface{}{"B"}},
+		{Kind: RequiredRemoved, Breaking: true, Pointer: "/paths/~1api~1v1~1pods/post/responses/202/content/application~1json/schema/required", Old: "name"},
+		{Kind: ResponseRemoved, Breaking: true, Pointer: "/paths/~1api~1v1~1pods/post/responses/404"},
+		{Kind: OperationAdded, Pointer: "/paths/~1api~1v1~1pods/trace"},
+	}, DiffV3(oldOpenAPI, newOpenAPI))
+}
+
+func TestDiffGolden(t *testing.T) {
+	data, err := os.ReadFile("../../test/integration/testdata/golden.v2.json")
+	require.NoError(t, err)
+	var oldSwagger, newSwagger *spec.Swagger
+	require.NoError(t, json.Unmarshal(data, &oldSwagger))
+	require.NoError(t, json.Unmarshal(data, &newSwagger))
+	assert.Empty(t, Diff(oldSwagger, newSwagger))
+
+	data, err = os.ReadFile("../../test/integration/testdata/golden.v3.json")
+	require.NoError(t, err)
+	var oldOpenAPI, newOpenAPI *spec3.OpenAPI
+	require.NoError(t, json.Unmarshal(data, &oldOpenAPI))
+	require.NoError(t, json.Unmarshal(data, &newOpenAPI))
+	assert.Empty(t, DiffV3(oldOpenAPI, newOpenAPI))
+}